@@ -20,18 +20,25 @@
 package app
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/andrejacobs/go-analyse/internal/compiledinfo"
 	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/langid"
+	"github.com/andrejacobs/go-analyse/text/markov"
 	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/dustin/go-humanize"
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/exp/maps"
 )
@@ -100,9 +107,69 @@ func (a *application) run(stdOut io.Writer, stdErr io.Writer) error {
 		return a.discoverLetters(ctx)
 	}
 
+	if a.opt.transform {
+		return a.transformTables()
+	}
+
+	if a.opt.tokens {
+		return a.streamTokens(ctx)
+	}
+
+	if a.opt.generate {
+		return a.generateText()
+	}
+
+	if a.opt.detect {
+		return a.detectLanguages(ctx)
+	}
+
 	return a.generateNgrams(ctx)
 }
 
+// parseOptions returns the options passed along to the tokenizers.
+func (a *application) parseOptions() []ngrams.ParseOption {
+	parseOpts := make([]ngrams.ParseOption, 0, 6)
+	parseOpts = append(parseOpts, ngrams.WithCase(a.opt.caseMode))
+	if a.opt.skip > 0 {
+		parseOpts = append(parseOpts, ngrams.WithSkip(a.opt.skip))
+	}
+	if a.opt.graphemes {
+		parseOpts = append(parseOpts, ngrams.WithGraphemes())
+	}
+	if a.opt.wordBoundaries {
+		parseOpts = append(parseOpts, ngrams.WithWordBoundaries())
+	}
+	if a.opt.stopWords != nil {
+		a.verbose("Filtering %d stop words (%s)\n", a.opt.stopWords.Len(), a.opt.stopWordFilter)
+		parseOpts = append(parseOpts, ngrams.WithStopWords(a.opt.stopWords, a.opt.stopWordFilter))
+	}
+	if a.opt.allowList != nil {
+		parseOpts = append(parseOpts, ngrams.WithAllowList(a.opt.allowList))
+	}
+	if a.opt.denyList != nil {
+		parseOpts = append(parseOpts, ngrams.WithDenyList(a.opt.denyList))
+	}
+	if a.opt.filter != nil {
+		parseOpts = append(parseOpts, ngrams.WithFilter(a.opt.filter))
+	}
+	if a.opt.stemmer != nil {
+		parseOpts = append(parseOpts, ngrams.WithStemmer(a.opt.stemmer))
+	}
+	return parseOpts
+}
+
+// configs returns the tokenizers and ngram sizes being used.
+func (a *application) configs() []ngrams.ProcessorConfig {
+	sizes := a.opt.sizes()
+	names := a.opt.tokenizerNames()
+
+	configs := make([]ngrams.ProcessorConfig, 0, len(names))
+	for _, name := range names {
+		configs = append(configs, ngrams.ProcessorConfig{Tokenizer: name, Sizes: sizes})
+	}
+	return configs
+}
+
 func (a *application) generateNgrams(ctx context.Context) error {
 
 	lang, err := a.opt.languages.Get(a.opt.langCode)
@@ -111,36 +178,197 @@ func (a *application) generateNgrams(ctx context.Context) error {
 	}
 	a.verbose("Language: %s - %s\n", lang.Code, lang.Name)
 
-	p := ngrams.NewFrequencyProcessor(ngrams.ProcessorMode(a.opt.words), lang, a.opt.tokenSize)
+	parseOpts := a.parseOptions()
+
+	var stemForms *ngrams.StemForms
+	if a.opt.stemmer != nil {
+		a.verbose("Stemming words (%s)\n", a.opt.stemOutput)
+		if a.opt.stemOutput == stemOutputSurface {
+			stemForms = ngrams.NewStemForms()
+			parseOpts = append(parseOpts, ngrams.WithStemForms(stemForms))
+		}
+	}
+
+	lineFilter, err := a.lineFilter(lang)
+	if err != nil {
+		return err
+	}
+	if lineFilter != nil {
+		parseOpts = append(parseOpts, ngrams.WithInputFilter(lineFilter.Reader))
+	}
+	coverage := a.coverage()
+	if coverage != nil {
+		parseOpts = append(parseOpts, ngrams.WithCoverage(coverage))
+	}
+
+	sizes := a.opt.sizes()
+	names := a.opt.tokenizerNames()
+
+	p := ngrams.NewFrequencyProcessorWithConfigs(lang, a.configs(), parseOpts...)
+	if a.opt.top > 0 {
+		a.verbose("Keeping the top %d tokens (approximate counts)\n", a.opt.top)
+		p.SetTopK(a.opt.top)
+	}
+	if a.opt.memoryBudget > 0 {
+		a.verbose("Memory budget: %s (spilling to %q)\n", humanize.IBytes(a.opt.memoryBudget), a.opt.spillDirDescription())
+		p.SetMemoryBudget(a.opt.memoryBudget, a.opt.spillDir)
+		defer func() {
+			if err := p.Close(); err != nil {
+				fmt.Fprintf(a.stdErr, "ERROR: %v\n", err)
+			}
+		}()
+	}
 
 	if a.opt.update {
-		exists, err := pathExists(a.opt.outPath)
-		if err != nil {
-			return err
+		for _, name := range names {
+			for _, size := range sizes {
+				outPath := a.opt.outputPath(name, size)
+				exists, err := pathExists(outPath)
+				if err != nil {
+					return err
+				}
+				if exists {
+					a.verbose("Loading existing frequency table: %q\n", outPath)
+					err = p.LoadFrequenciesForTokenizerFromFile(name, size, outPath)
+					if err != nil {
+						return err
+					}
+				}
+			}
 		}
-		if exists {
-			a.verbose("Loading existing frequency table: %q\n", a.opt.outPath)
-			err = p.LoadFrequenciesFromFile(a.opt.outPath)
+	}
+
+	a.verbose("Generating %s %s ngrams...\n", a.opt.sizeDescription(), a.opt.tokenizerDescription())
+
+	if a.progress != nil {
+		a.progress.progressBar = progressbar.DefaultBytes(1)
+		p.SetProgressReporter(a.progress)
+	} else if a.opt.verbose {
+		p.SetProgressReporter(&verboseReporter{a: a})
+	}
+
+	if err = p.ProcessFiles(ctx, a.opt.inputs); err != nil {
+		return err
+	}
+
+	if a.progress != nil {
+		_ = a.progress.progressBar.Finish()
+	}
+	a.reportRejectedLines(lineFilter, lang)
+	if err := a.reportCoverage(coverage, lang); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		for _, size := range sizes {
+			outPath := a.opt.outputPath(name, size)
+			a.verbose("Saving frequency table...\n")
+
+			ft := p.FrequencyTableForTokenizer(name, size)
+			if a.opt.stemmer != nil && name == ngrams.TokenizerWords {
+				ft.SetMetadata(ngrams.MetadataStemmer, string(a.opt.langCode))
+				if stemForms != nil {
+					if ft, err = stemForms.ApplyToTable(ft); err != nil {
+						return err
+					}
+					// Remove the temporary files of the rewritten table when it has spilled to disk
+					defer func() {
+						if err := ft.Close(); err != nil {
+							fmt.Fprintf(a.stdErr, "ERROR: %v\n", err)
+						}
+					}()
+				}
+			}
+
+			if a.opt.pruner != nil {
+				err = a.opt.pruner.SaveToFile(ft, outPath)
+			} else {
+				err = ft.SaveToFile(outPath)
+			}
 			if err != nil {
 				return err
 			}
+
+			a.verbose("Created frequency table at: %q\n", outPath)
 		}
 	}
+	return nil
+}
 
-	if a.opt.words {
-		a.verbose("Generating %d word ngrams...\n", a.opt.tokenSize)
-	} else {
-		a.verbose("Generating %d letter ngrams...\n", a.opt.tokenSize)
+// streamTokens writes every ngram token produced by the tokenizers to the output path or STDOUT,
+// one token per line.
+func (a *application) streamTokens(ctx context.Context) error {
+	w := a.stdOut
+	if a.opt.outPath == "" {
+		// The tokens are written to STDOUT and thus any other information is written to STDERR
+		a.stdOut = a.stdErr
+	}
+
+	lang, err := a.opt.languages.Get(a.opt.langCode)
+	if err != nil {
+		return err
+	}
+	a.verbose("Language: %s - %s\n", lang.Code, lang.Name)
+
+	var f *os.File
+	if a.opt.outPath != "" {
+		f, err = os.Create(a.opt.outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create the tokens file %q. %w", a.opt.outPath, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	parseOpts := a.parseOptions()
+	lineFilter, err := a.lineFilter(lang)
+	if err != nil {
+		return err
+	}
+	if lineFilter != nil {
+		parseOpts = append(parseOpts, ngrams.WithInputFilter(lineFilter.Reader))
 	}
+	coverage := a.coverage()
+	if coverage != nil {
+		parseOpts = append(parseOpts, ngrams.WithCoverage(coverage))
+	}
+
+	// Tokens from different tokenizers or sizes can be identical (e.g. the letter and word "a")
+	configs := a.configs()
+	multiple := len(configs) > 1 || len(configs[0].Sizes) > 1
+
+	s := ngrams.NewTokenStreamer(lang, configs, parseOpts...)
+
+	a.verbose("Streaming %s %s ngrams...\n", a.opt.sizeDescription(), a.opt.tokenizerDescription())
 
 	if a.progress != nil {
 		a.progress.progressBar = progressbar.DefaultBytes(1)
-		p.SetProgressReporter(a.progress)
+		s.SetProgressReporter(a.progress)
 	} else if a.opt.verbose {
-		p.SetProgressReporter(&verboseReporter{a: a})
+		s.SetProgressReporter(&verboseReporter{a: a})
 	}
 
-	if err = p.ProcessFiles(ctx, a.opt.inputs); err != nil {
+	bw := bufio.NewWriter(w)
+	var line []byte
+	err = s.ProcessFiles(ctx, a.opt.inputs, func(token ngrams.StreamedToken) error {
+		line = append(line[:0], token.Token...)
+		if multiple {
+			line = append(line, '\t')
+			line = append(line, token.Tokenizer...)
+			line = append(line, '\t')
+			line = strconv.AppendInt(line, int64(token.Size), 10)
+		}
+		if a.opt.tokenSource {
+			line = append(line, '\t')
+			line = append(line, token.Source...)
+			line = append(line, '\t')
+			line = strconv.AppendInt(line, token.Offset, 10)
+		}
+		line = append(line, '\n')
+		_, err := bw.Write(line)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
@@ -148,19 +376,250 @@ func (a *application) generateNgrams(ctx context.Context) error {
 		_ = a.progress.progressBar.Finish()
 	}
 
-	a.verbose("Saving frequency table...\n")
-	if err := p.Save(a.opt.outPath); err != nil {
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write the tokens. %w", err)
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write the tokens file %q. %w", a.opt.outPath, err)
+		}
+	}
+	a.reportRejectedLines(lineFilter, lang)
+	if err := a.reportCoverage(coverage, lang); err != nil {
 		return err
 	}
 
-	a.verbose("Created frequency table at: %q\n", a.opt.outPath)
+	if a.opt.outPath != "" {
+		a.verbose("Created tokens file at: %q\n", a.opt.outPath)
+	}
+	return nil
+}
+
+// generateText combines the existing frequency tables (input paths) into a Markov model and writes
+// the generated words to the output path or STDOUT.
+func (a *application) generateText() error {
+	w := a.stdOut
+	if a.opt.outPath == "" {
+		// The text is written to STDOUT and thus any other information is written to STDERR
+		a.stdOut = a.stdErr
+	}
+
+	ft := ngrams.NewFrequencyTable()
+	for _, path := range a.opt.inputs {
+		a.verbose("Loading frequency table: %q\n", path)
+		input, err := ngrams.LoadFrequenciesFromFile(path)
+		if err != nil {
+			return err
+		}
+		if err := ft.Merge(input); err != nil {
+			return err
+		}
+	}
+
+	transitionOpts := make([]ngrams.TransitionOption, 0, 2)
+	if a.opt.words {
+		transitionOpts = append(transitionOpts, ngrams.WithWordUnits())
+	}
+	switch a.opt.smoothing {
+	case ngrams.SmoothingLaplace:
+		transitionOpts = append(transitionOpts, ngrams.WithLaplace(ngrams.DefaultLaplaceAlpha))
+	case ngrams.SmoothingKneserNey:
+		transitionOpts = append(transitionOpts, ngrams.WithKneserNey(ngrams.DefaultKneserNeyDiscount))
+	}
+
+	model, err := markov.NewModelFromTable(ft, transitionOpts...)
+	if err != nil {
+		return err
+	}
+
+	g, err := markov.NewGenerator(model, a.opt.seed, markov.WithWordLength(a.opt.minWordLength, a.opt.maxWordLength))
+	if err != nil {
+		return err
+	}
+
+	a.verbose("Generating %d words (seed %d)...\n", a.opt.length, a.opt.seed)
+	text := g.Words(a.opt.length) + "\n"
+
+	if a.opt.outPath == "" {
+		_, err = io.WriteString(w, text)
+		return err
+	}
+
+	if err := os.WriteFile(a.opt.outPath, []byte(text), 0644); err != nil {
+		return fmt.Errorf("failed to write the generated text to %q. %w", a.opt.outPath, err)
+	}
+	a.verbose("Created text file at: %q\n", a.opt.outPath)
+	return nil
+}
+
+// profiles returns the built-in language profiles extended with the profiles found in the --profiles directory.
+func (a *application) profiles() ([]*langid.Profile, error) {
+	profiles, err := langid.BuiltinProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if a.opt.profilesDir != "" {
+		a.verbose("Loading language profiles from: %q\n", a.opt.profilesDir)
+		userProfiles, err := langid.LoadProfilesFromDir(a.opt.profilesDir)
+		if err != nil {
+			return nil, err
+		}
+		profiles = langid.MergeProfiles(profiles, userProfiles...)
+	}
+	return profiles, nil
+}
+
+// lineFilter returns the filter that drops the lines not written in the language or nil if the lines
+// are not being filtered.
+func (a *application) lineFilter(lang alphabet.Language) (*langid.LineFilter, error) {
+	if !a.opt.filterLines {
+		return nil, nil
+	}
+
+	profiles, err := a.profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	a.verbose("Filtering the lines not written in %s (threshold %v)\n", lang.Name, a.opt.lineThreshold)
+	return langid.NewLineFilter(lang, profiles, langid.WithThreshold(a.opt.lineThreshold))
+}
+
+// reportRejectedLines displays the number of lines rejected by the filter for each input source.
+func (a *application) reportRejectedLines(f *langid.LineFilter, lang alphabet.Language) {
+	if f == nil {
+		return
+	}
+
+	fmt.Fprintf(a.stdOut, "Rejected lines not written in %s (%s):\n", lang.Name, lang.Code)
+	for _, stats := range f.Stats() {
+		fmt.Fprintf(a.stdOut, "  %s\t%d of %d\n", stats.Source, stats.Rejected, stats.Lines)
+	}
+}
+
+// coverage returns the collector of the alphabet coverage statistics or nil if the statistics are not needed.
+func (a *application) coverage() *ngrams.Coverage {
+	if !a.opt.verbose && a.opt.alphabetReportPath == "" {
+		return nil
+	}
+	return ngrams.NewCoverage()
+}
+
+// reportCoverage displays the alphabet coverage statistics in verbose mode and writes them to the
+// --alphabet-report path.
+func (a *application) reportCoverage(coverage *ngrams.Coverage, lang alphabet.Language) error {
+	if coverage == nil {
+		return nil
+	}
+
+	if a.opt.verbose {
+		fmt.Fprintf(a.stdOut, "Alphabet coverage (%s - %s):\n", lang.Code, lang.Name)
+		if err := coverage.WriteReport(a.stdOut, coverageTopRejected); err != nil {
+			return err
+		}
+	}
+
+	if a.opt.alphabetReportPath != "" {
+		f, err := os.Create(a.opt.alphabetReportPath)
+		if err != nil {
+			return fmt.Errorf("failed to create the alphabet report %q. %w", a.opt.alphabetReportPath, err)
+		}
+		defer f.Close()
+
+		if _, err := fmt.Fprintf(f, "Alphabet coverage (%s - %s):\n", lang.Code, lang.Name); err != nil {
+			return err
+		}
+		if err := coverage.WriteReport(f, coverageTopRejected); err != nil {
+			return fmt.Errorf("failed to write the alphabet report %q. %w", a.opt.alphabetReportPath, err)
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		a.verbose("Created alphabet report at: %q\n", a.opt.alphabetReportPath)
+	}
+	return nil
+}
+
+// The number of most frequently rejected runes listed in the alphabet report
+const coverageTopRejected = 10
+
+// detectLanguages identifies the top languages of each input file using the language profiles and writes
+// the report to the output path or STDOUT.
+func (a *application) detectLanguages(ctx context.Context) error {
+	w := a.stdOut
+	var f *os.File
+	if a.opt.outPath == "" {
+		// The report is written to STDOUT and thus any other information is written to STDERR
+		a.stdOut = a.stdErr
+	} else {
+		var err error
+		f, err = os.Create(a.opt.outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create the language report %q. %w", a.opt.outPath, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	profiles, err := a.profiles()
+	if err != nil {
+		return err
+	}
+
+	id, err := langid.NewIdentifier(profiles, langid.WithMethod(a.opt.method))
+	if err != nil {
+		return err
+	}
+
+	a.verbose("Detecting the languages (%s)...\n", id.Method())
+	bw := bufio.NewWriter(w)
+	err = id.IdentifyFiles(ctx, a.opt.inputs, func(source string, results []langid.Result, err error) error {
+		if _, werr := fmt.Fprintln(bw, source); werr != nil {
+			return werr
+		}
+		if err != nil {
+			if !errors.Is(err, langid.ErrNoNgrams) {
+				return err
+			}
+			_, err = fmt.Fprintf(bw, "  unknown (%v)\n", err)
+			return err
+		}
+
+		for _, r := range results[:min(a.opt.detectTop, len(results))] {
+			if _, err := fmt.Fprintf(bw, "  %s\t%s\t%.4f\n", r.Code, r.Name, r.Confidence); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if f != nil {
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write the language report %q. %w", a.opt.outPath, err)
+		}
+		a.verbose("Created language report at: %q\n", a.opt.outPath)
+	}
 	return nil
 }
 
 func (a *application) discoverLetters(ctx context.Context) error {
 	a.verbose("Discovering letters being used...\n")
 
-	p := alphabet.NewDiscoverProcessor()
+	discoverOpts := []alphabet.DiscoverOption{
+		alphabet.WithLanguage(a.opt.discoverCode, a.opt.discoverName),
+		alphabet.WithThreshold(a.opt.alphabetThreshold),
+	}
+	if a.opt.union {
+		discoverOpts = append(discoverOpts, alphabet.WithUnion())
+	}
+	p := alphabet.NewDiscoverProcessor(discoverOpts...)
 
 	if a.progress != nil {
 		a.progress.progressBar = progressbar.DefaultBytes(1)
@@ -177,6 +636,10 @@ func (a *application) discoverLetters(ctx context.Context) error {
 		_ = a.progress.progressBar.Finish()
 	}
 
+	if err := p.WriteReport(a.stdOut); err != nil {
+		return err
+	}
+
 	if err := p.Save(a.opt.outPath); err != nil {
 		return err
 	}
@@ -185,6 +648,44 @@ func (a *application) discoverLetters(ctx context.Context) error {
 	return nil
 }
 
+// transformTables combines the existing frequency tables (input paths) and applies the token filter
+// and pruning before writing the result to the output path.
+func (a *application) transformTables() error {
+	a.verbose("Transforming frequency tables...\n")
+
+	ft := ngrams.NewFrequencyTable()
+	for _, path := range a.opt.inputs {
+		a.verbose("Loading frequency table: %q\n", path)
+		input, err := ngrams.LoadFrequenciesFromFile(path)
+		if err != nil {
+			return err
+		}
+		if err := ft.Merge(input); err != nil {
+			return err
+		}
+	}
+
+	if a.opt.filter != nil {
+		a.verbose("Applying %d filter rules...\n", a.opt.filter.Len())
+		ft = a.opt.filter.ApplyToTable(ft)
+	} else {
+		ft.Update()
+	}
+
+	if a.opt.pruner != nil {
+		a.verbose("Pruning the frequency table...\n")
+		ft = a.opt.pruner.ApplyToTable(ft)
+	}
+
+	a.verbose("Saving frequency table...\n")
+	if err := ft.SaveToFile(a.opt.outPath); err != nil {
+		return err
+	}
+
+	a.verbose("Created frequency table at: %q\n", a.opt.outPath)
+	return nil
+}
+
 func (a *application) verbose(format string, args ...any) {
 	if a.opt.verbose {
 		fmt.Fprintf(a.stdOut, format, args...)
@@ -199,10 +700,66 @@ type options struct {
 	inputs    []string
 	langCode  alphabet.LanguageCode
 	languages alphabet.LanguageMap
+	letters   bool
 	words     bool
+	// Names of the other registered tokenizers to be used
+	tokenizers []string
+	graphemes  bool
+	caseMode   ngrams.CaseMode
+	// Split the words on punctuation using the apostrophes and hyphens of the language
+	wordBoundaries bool
+	// Word lists used to filter the words before word ngrams are formed
+	builtinStopWords bool
+	stopWords        ngrams.WordList
+	stopWordFilter   ngrams.StopWordFilter
+	allowList        ngrams.WordList
+	denyList         ngrams.WordList
+	// Stemming of the words before word ngrams are formed
+	stem       bool
+	stemmer    ngrams.Stemmer
+	stemOutput string
+	// Rewrite and drop rules applied to the tokens before they are added to the frequency tables
+	filter *ngrams.TokenFilter
+	// Pruning applied to the frequency tables when they are saved
+	pruner    *ngrams.Pruner
 	tokenSize int
-	discover  bool
-	update    bool
+	// Only keep the approximate top-K tokens when more than 0
+	top int
+	// Spill the frequency tables to disk when the memory budget (in bytes) is exceeded
+	memoryBudget uint64
+	spillDir     string
+	// Only used when a range of ngram sizes are generated
+	maxTokenSize int
+	skip         int
+	discover     bool
+	transform    bool
+	// Stream the ngram tokens instead of creating frequency tables
+	tokens      bool
+	tokenSource bool
+	// Generate text from a Markov model of the existing frequency tables
+	generate      bool
+	length        int
+	seed          int64
+	seedSet       bool
+	minWordLength int
+	maxWordLength int
+	smoothing     ngrams.Smoothing
+	// Detect the top languages of each input file using the language profiles
+	detect      bool
+	detectTop   int
+	profilesDir string
+	method      langid.Method
+	// Drop the lines not written in the language before they are tokenized
+	filterLines   bool
+	lineThreshold float64
+	// The language written to the languages file by --discover
+	discoverCode      alphabet.LanguageCode
+	discoverName      string
+	alphabetThreshold float64
+	union             bool
+	// Write the alphabet coverage statistics to this path
+	alphabetReportPath string
+	update             bool
 
 	verbose  bool
 	progress bool
@@ -216,67 +773,605 @@ func withDefaults() optionFunc {
 	return func(opt *options) error {
 		opt.langCode = "en"
 		opt.languages = alphabet.BuiltinLanguages()
+		opt.letters = false
 		opt.words = false
 		opt.tokenSize = 1
+		opt.lineThreshold = langid.DefaultLineThreshold
+		opt.alphabetThreshold = alphabet.DefaultDiscoverThreshold
+		return nil
+	}
+}
+
+// withLanguageCode configures the language to be used.
+func withLanguageCode(code alphabet.LanguageCode) optionFunc {
+	return func(opt *options) error {
+		opt.langCode = code
+		return nil
+	}
+}
+
+// withLanguagesFile will load the languages from the given file path.
+func withLanguagesFile(path string) optionFunc {
+	return func(opt *options) error {
+		var err error
+		opt.languages, err = alphabet.LoadLanguagesFromFile(path)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// withLetters configures the app to calculate letter combinations. E.g. bigrams st, er, ao, ie.
+// This is the default when neither letters nor words are specified.
+func withLetters() optionFunc {
+	return func(opt *options) error {
+		opt.letters = true
+		return nil
+	}
+}
+
+// withWords configures the app to calculate word combinations. E.g. bigrams she walked, he jumped.
+// Can be combined with [withLetters] to calculate both in a single pass over the input.
+func withWords() optionFunc {
+	return func(opt *options) error {
+		opt.words = true
+		return nil
+	}
+}
+
+// withTokenizer configures the app to use the registered tokenizer. E.g. letters, words or a custom tokenizer.
+func withTokenizer(name string) optionFunc {
+	return func(opt *options) error {
+		switch name {
+		case ngrams.TokenizerLetters:
+			opt.letters = true
+		case ngrams.TokenizerWords:
+			opt.words = true
+		default:
+			if _, err := ngrams.LookupTokenizer(name); err != nil {
+				return err
+			}
+			if !slices.Contains(opt.tokenizers, name) {
+				opt.tokenizers = append(opt.tokenizers, name)
+			}
+		}
+		return nil
+	}
+}
+
+// withGraphemes configures the app to treat extended grapheme clusters and multi-rune letters as single letters.
+func withGraphemes() optionFunc {
+	return func(opt *options) error {
+		opt.graphemes = true
+		return nil
+	}
+}
+
+// withWordBoundaries configures the app to split the words on punctuation instead of only on whitespace.
+func withWordBoundaries() optionFunc {
+	return func(opt *options) error {
+		opt.wordBoundaries = true
+		return nil
+	}
+}
+
+// withCase configures how the case of letters and words are treated (lower, preserve, upper or fold).
+func withCase(name string) optionFunc {
+	return func(opt *options) error {
+		mode, err := ngrams.ParseCaseMode(name)
+		if err != nil {
+			return err
+		}
+		opt.caseMode = mode
+		return nil
+	}
+}
+
+// withBuiltinStopWords configures the app to filter the built-in stop words of the language from the word ngrams.
+func withBuiltinStopWords() optionFunc {
+	return func(opt *options) error {
+		opt.builtinStopWords = true
+		return nil
+	}
+}
+
+// withStopWordsFile configures the app to filter the stop words loaded from the file from the word ngrams.
+func withStopWordsFile(path string) optionFunc {
+	return func(opt *options) error {
+		list, err := ngrams.LoadWordListFromFile(path)
+		if err != nil {
+			return err
+		}
+		opt.stopWords = mergeWordLists(opt.stopWords, list)
+		return nil
+	}
+}
+
+// withStopWordFilter configures how stop words are filtered (remove, any or all).
+func withStopWordFilter(name string) optionFunc {
+	return func(opt *options) error {
+		filter, err := ngrams.ParseStopWordFilter(name)
+		if err != nil {
+			return err
+		}
+		opt.stopWordFilter = filter
+		return nil
+	}
+}
+
+// withAllowListFile configures the app to only keep the words loaded from the file before word ngrams are formed.
+func withAllowListFile(path string) optionFunc {
+	return func(opt *options) error {
+		list, err := ngrams.LoadWordListFromFile(path)
+		if err != nil {
+			return err
+		}
+		opt.allowList = list
+		return nil
+	}
+}
+
+// withDenyListFile configures the app to remove the words loaded from the file before word ngrams are formed.
+func withDenyListFile(path string) optionFunc {
+	return func(opt *options) error {
+		list, err := ngrams.LoadWordListFromFile(path)
+		if err != nil {
+			return err
+		}
+		opt.denyList = list
+		return nil
+	}
+}
+
+// Values of the --stem-output flag
+const (
+	stemOutputStems   = "stems"
+	stemOutputSurface = "surface"
+)
+
+// withStem configures the app to reduce the words to their stem using the built-in stemmer of the language.
+func withStem() optionFunc {
+	return func(opt *options) error {
+		opt.stem = true
+		return nil
+	}
+}
+
+// withStemOutput configures whether the word ngrams are written as stems or as the most frequent surface
+// form of each stem (stems or surface).
+func withStemOutput(name string) optionFunc {
+	return func(opt *options) error {
+		switch name {
+		case stemOutputStems, stemOutputSurface:
+			opt.stemOutput = name
+		default:
+			return fmt.Errorf("invalid stem output %q", name)
+		}
+		return nil
+	}
+}
+
+// withTop configures the app to only keep the k most frequent tokens using a bounded amount of memory.
+func withTop(k int) optionFunc {
+	return func(opt *options) error {
+		if k < 1 {
+			return fmt.Errorf("invalid top %d", k)
+		}
+		opt.top = k
+		return nil
+	}
+}
+
+// withMemoryBudget configures the app to spill the frequency tables to temporary files once the memory budget
+// (e.g. 512MB or 8GiB) is exceeded. The counts remain exact.
+func withMemoryBudget(budget string) optionFunc {
+	return func(opt *options) error {
+		bytes, err := humanize.ParseBytes(budget)
+		if err != nil || bytes == 0 {
+			return fmt.Errorf("invalid memory budget %q", budget)
+		}
+		opt.memoryBudget = bytes
+		return nil
+	}
+}
+
+// withSpillDir configures the directory in which the temporary files are created when the memory budget is exceeded.
+func withSpillDir(dir string) optionFunc {
+	return func(opt *options) error {
+		opt.spillDir = dir
+		return nil
+	}
+}
+
+// spillDirDescription returns the directory used for temporary files as shown to the user.
+func (opt *options) spillDirDescription() string {
+	if opt.spillDir == "" {
+		return os.TempDir()
+	}
+	return opt.spillDir
+}
+
+// withSize defines how many letters or words form a single ngram.
+func withSize(size int) optionFunc {
+	return func(opt *options) error {
+		if size < 1 {
+			return fmt.Errorf("invalid ngram size %d", size)
+		}
+		opt.tokenSize = size
+		opt.maxTokenSize = 0
+		return nil
+	}
+}
+
+// withSizeRange defines the range of ngram sizes to be generated in a single pass over the input.
+func withSizeRange(minSize int, maxSize int) optionFunc {
+	return func(opt *options) error {
+		if minSize < 1 || maxSize < minSize {
+			return fmt.Errorf("invalid ngram size range %d-%d", minSize, maxSize)
+		}
+		opt.tokenSize = minSize
+		opt.maxTokenSize = maxSize
+		return nil
+	}
+}
+
+// withSizes parses either a single ngram size (e.g. 2) or a range of sizes (e.g. 1-5).
+func withSizes(spec string) optionFunc {
+	return func(opt *options) error {
+		minSpec, maxSpec, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+
+		minSize, err := strconv.Atoi(minSpec)
+		if err != nil {
+			return fmt.Errorf("invalid ngram size %q", spec)
+		}
+		if !isRange {
+			return withSize(minSize)(opt)
+		}
+
+		maxSize, err := strconv.Atoi(maxSpec)
+		if err != nil {
+			return fmt.Errorf("invalid ngram size %q", spec)
+		}
+		return withSizeRange(minSize, maxSize)(opt)
+	}
+}
+
+// withSkip configures the maximum number of letters or words that may be skipped to form skip-grams.
+func withSkip(skip int) optionFunc {
+	return func(opt *options) error {
+		if skip < 0 {
+			return fmt.Errorf("invalid skip %d", skip)
+		}
+		opt.skip = skip
+		return nil
+	}
+}
+
+// withDiscoverLanguage configures the app to discover the non-whitespace characters being used.
+func withDiscoverLanguage() optionFunc {
+	return func(opt *options) error {
+		opt.discover = true
+		return nil
+	}
+}
+
+// withDiscoverCode configures the code of the language written to the languages file by --discover.
+func withDiscoverCode(code string) optionFunc {
+	return func(opt *options) error {
+		code = strings.TrimSpace(code)
+		if code == "" || strings.ContainsAny(code, ",\"") {
+			return fmt.Errorf("invalid language code %q", code)
+		}
+		opt.discoverCode = alphabet.LanguageCode(code)
+		return nil
+	}
+}
+
+// withDiscoverName configures the name of the language written to the languages file by --discover.
+func withDiscoverName(name string) optionFunc {
+	return func(opt *options) error {
+		opt.discoverName = strings.TrimSpace(name)
+		return nil
+	}
+}
+
+// withAlphabetThreshold configures the minimum share of the letter occurrences a letter needs to be part
+// of the alphabet suggested by --discover.
+func withAlphabetThreshold(threshold float64) optionFunc {
+	return func(opt *options) error {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("invalid alphabet threshold %v (expected a value between 0 and 1)", threshold)
+		}
+		opt.alphabetThreshold = threshold
+		return nil
+	}
+}
+
+// withUnion configures --discover to combine the suggested alphabet with the letters of the language
+// already in the languages file.
+func withUnion() optionFunc {
+	return func(opt *options) error {
+		opt.union = true
+		return nil
+	}
+}
+
+// withTransform configures the app to combine existing frequency tables (the input paths) and apply
+// the token filter to them.
+func withTransform() optionFunc {
+	return func(opt *options) error {
+		opt.transform = true
+		return nil
+	}
+}
+
+// withTokens configures the app to write every ngram token produced to the output path (or STDOUT)
+// instead of creating frequency tables.
+func withTokens() optionFunc {
+	return func(opt *options) error {
+		opt.tokens = true
+		return nil
+	}
+}
+
+// withTokenSource configures the app to write the input source and byte offset along with every ngram token
+// when the tokens are streamed.
+func withTokenSource() optionFunc {
+	return func(opt *options) error {
+		opt.tokenSource = true
+		return nil
+	}
+}
+
+// withGenerate configures the app to generate the number of words from a Markov model built from
+// existing frequency tables (the input paths).
+func withGenerate(length int) optionFunc {
+	return func(opt *options) error {
+		if length < 1 {
+			return fmt.Errorf("invalid length %d", length)
+		}
+		opt.generate = true
+		opt.length = length
+		return nil
+	}
+}
+
+// withSeed configures the seed of the random number generator used to generate text.
+func withSeed(seed int64) optionFunc {
+	return func(opt *options) error {
+		opt.seed = seed
+		opt.seedSet = true
+		return nil
+	}
+}
+
+// withWordLength parses either the number of letters (e.g. 5) or a range (e.g. 3-8) of the words generated
+// from letter ngrams.
+func withWordLength(spec string) optionFunc {
+	return func(opt *options) error {
+		minSpec, maxSpec, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+		if !isRange {
+			maxSpec = minSpec
+		}
+
+		minLength, err := strconv.Atoi(minSpec)
+		if err != nil {
+			return fmt.Errorf("invalid word length %q", spec)
+		}
+		maxLength, err := strconv.Atoi(maxSpec)
+		if err != nil {
+			return fmt.Errorf("invalid word length %q", spec)
+		}
+		if minLength < 1 || maxLength < minLength {
+			return fmt.Errorf("invalid word length %q", spec)
+		}
+
+		opt.minWordLength = minLength
+		opt.maxWordLength = maxLength
+		return nil
+	}
+}
+
+// withDetect configures the app to report the top number of languages of each input file.
+func withDetect(top int) optionFunc {
+	return func(opt *options) error {
+		if top < 1 {
+			return fmt.Errorf("invalid number of languages %d", top)
+		}
+		opt.detect = true
+		opt.detectTop = top
+		return nil
+	}
+}
+
+// withProfiles configures the directory containing the letter frequency tables used as language profiles.
+func withProfiles(dir string) optionFunc {
+	return func(opt *options) error {
+		opt.profilesDir = dir
+		return nil
+	}
+}
+
+// withMethod configures the method used to identify the languages.
+func withMethod(name string) optionFunc {
+	return func(opt *options) error {
+		method, err := langid.ParseMethod(name)
+		if err != nil {
+			return err
+		}
+		opt.method = method
+		return nil
+	}
+}
+
+// withFilterLines configures the app to drop the lines of the input that are not written in the language.
+func withFilterLines() optionFunc {
+	return func(opt *options) error {
+		opt.filterLines = true
+		return nil
+	}
+}
+
+// withLineThreshold configures the minimum score a line needs to be kept by the line filter.
+func withLineThreshold(threshold float64) optionFunc {
+	return func(opt *options) error {
+		if threshold < 0 || threshold > 1 {
+			return fmt.Errorf("invalid line threshold %v (expected a value between 0 and 1)", threshold)
+		}
+		opt.lineThreshold = threshold
+		return nil
+	}
+}
+
+// withAlphabetReport configures the path to which the alphabet coverage statistics are written.
+func withAlphabetReport(path string) optionFunc {
+	return func(opt *options) error {
+		opt.alphabetReportPath = path
+		return nil
+	}
+}
+
+// withSmoothing configures the smoothing of the transition probabilities used to generate text.
+func withSmoothing(name string) optionFunc {
+	return func(opt *options) error {
+		smoothing, err := ngrams.ParseSmoothing(name)
+		if err != nil {
+			return err
+		}
+		opt.smoothing = smoothing
+		return nil
+	}
+}
+
+// withRewrites configures the app to rewrite numbers, urls and/or email addresses. E.g. "number,url".
+// Email addresses and URLs are always rewritten before numbers.
+func withRewrites(names string) optionFunc {
+	return func(opt *options) error {
+		var number, url, email bool
+		for _, name := range strings.Split(names, ",") {
+			switch strings.TrimSpace(name) {
+			case "number":
+				number = true
+			case "url":
+				url = true
+			case "email":
+				email = true
+			default:
+				return fmt.Errorf("invalid rewrite %q", name)
+			}
+		}
+
+		f := opt.tokenFilter()
+		if email {
+			f.RewriteEmails()
+		}
+		if url {
+			f.RewriteURLs()
+		}
+		if number {
+			f.RewriteNumbers()
+		}
+		return nil
+	}
+}
+
+// withFilterFile configures the app to apply the token filter rules loaded from the file.
+func withFilterFile(path string) optionFunc {
+	return func(opt *options) error {
+		f, err := ngrams.LoadTokenFilterFromFile(path)
+		if err != nil {
+			return err
+		}
+		opt.tokenFilter().Append(f)
 		return nil
 	}
 }
 
-// withLanguageCode configures the language to be used.
-func withLanguageCode(code alphabet.LanguageCode) optionFunc {
+// withMinLength configures the app to drop the tokens with less than length runes.
+func withMinLength(length int) optionFunc {
 	return func(opt *options) error {
-		opt.langCode = code
+		if length < 1 {
+			return fmt.Errorf("invalid minimum length %d", length)
+		}
+		opt.tokenFilter().MinLength(length)
 		return nil
 	}
 }
 
-// withLanguagesFile will load the languages from the given file path.
-func withLanguagesFile(path string) optionFunc {
+// withMaxLength configures the app to drop the tokens with more than length runes.
+func withMaxLength(length int) optionFunc {
 	return func(opt *options) error {
-		var err error
-		opt.languages, err = alphabet.LoadLanguagesFromFile(path)
-		if err != nil {
-			return err
+		if length < 1 {
+			return fmt.Errorf("invalid maximum length %d", length)
 		}
+		opt.tokenFilter().MaxLength(length)
 		return nil
 	}
 }
 
-// withLetters configures the app to calculate letter combinations. E.g. bigrams st, er, ao, ie.
-func withLetters() optionFunc {
+// withMinCount configures the app to only keep the tokens that have a count of at least n when saving.
+func withMinCount(n int) optionFunc {
 	return func(opt *options) error {
-		opt.words = false
+		if n < 1 {
+			return fmt.Errorf("invalid minimum count %d", n)
+		}
+		opt.tablePruner().MinCount(int64(n))
 		return nil
 	}
 }
 
-// withWords configures the app to calculate word combinations. E.g. bigrams she walked, he jumped.
-func withWords() optionFunc {
+// withKeepTop configures the app to only keep the n most frequent tokens when saving.
+func withKeepTop(n int) optionFunc {
 	return func(opt *options) error {
-		opt.words = true
+		if n < 1 {
+			return fmt.Errorf("invalid keep top %d", n)
+		}
+		opt.tablePruner().TopN(n)
 		return nil
 	}
 }
 
-// withSize defines how many letters or words form a single ngram.
-func withSize(size int) optionFunc {
+// withCoverage configures the app to only keep the most frequent tokens that cover the percentage
+// (e.g. 95) of the total count when saving.
+func withCoverage(percentage float64) optionFunc {
 	return func(opt *options) error {
-		if size < 1 {
-			return fmt.Errorf("invalid ngram size %d", size)
+		if percentage <= 0 || percentage > 100 {
+			return fmt.Errorf("invalid coverage %g", percentage)
 		}
-		opt.tokenSize = size
+		opt.tablePruner().Coverage(percentage / 100)
 		return nil
 	}
 }
 
-// withDiscoverLanguage configures the app to discover the non-whitespace characters being used.
-func withDiscoverLanguage() optionFunc {
+// withMinPercentage configures the app to only keep the tokens whose share of the total count is at least
+// the percentage (e.g. 0.01) when saving.
+func withMinPercentage(percentage float64) optionFunc {
 	return func(opt *options) error {
-		opt.discover = true
+		if percentage <= 0 || percentage > 100 {
+			return fmt.Errorf("invalid minimum percentage %g", percentage)
+		}
+		opt.tablePruner().MinPercentage(percentage / 100)
 		return nil
 	}
 }
 
+// tablePruner returns the pruner and creates it if needed.
+func (opt *options) tablePruner() *ngrams.Pruner {
+	if opt.pruner == nil {
+		opt.pruner = ngrams.NewPruner()
+	}
+	return opt.pruner
+}
+
+// tokenFilter returns the token filter and creates it if needed.
+func (opt *options) tokenFilter() *ngrams.TokenFilter {
+	if opt.filter == nil {
+		opt.filter = ngrams.NewTokenFilter()
+	}
+	return opt.filter
+}
+
 // withUpdate configures the app to update an existing ngram output.
 func withUpdate() optionFunc {
 	return func(opt *options) error {
@@ -340,30 +1435,153 @@ func parseArgs(stdOut io.Writer) ([]optionFunc, error) {
 	flag.StringVar(&outPath, "o", "", "Path to where the output will be stored.")
 	flag.StringVar(&outPath, "out", "", "Path to where the output will be stored.")
 
-	var tokenSize int
-	flag.IntVar(&tokenSize, "s", 1, "Ngram size. The number of letters or words that form a single ngram.")
-	flag.IntVar(&tokenSize, "size", 1, "Ngram size. The number of letters or words that form a single ngram.")
+	var tokenSize string
+	flag.StringVar(&tokenSize, "s", "1", "Ngram size. The number of letters or words that form a single ngram. E.g. 2 or a range 1-5.")
+	flag.StringVar(&tokenSize, "size", "1", "Ngram size. The number of letters or words that form a single ngram. E.g. 2 or a range 1-5.")
+
+	var skip int
+	flag.IntVar(&skip, "k", 0, "Skip-gram distance. The maximum number of letters or words that may be skipped in an ngram.")
+	flag.IntVar(&skip, "skip", 0, "Skip-gram distance. The maximum number of letters or words that may be skipped in an ngram.")
 
 	var langCode string
 	flag.StringVar(&langCode, "a", "en", "Alphabet language code. E.g. en = English")
 	flag.StringVar(&langCode, "lang", "en", "Alphabet language code. E.g. en = English")
 
 	var langPath string
-	//TODO: Document the CSV format: #code,name,letters
-	flag.StringVar(&langPath, "languages", "", "Path to a languages definition file.")
+	flag.StringVar(&langPath, "languages", "", "Path to a languages definition file (CSV or JSON).")
 
 	var useLetters bool
-	flag.BoolVar(&useLetters, "l", true, "Create letter ngram combinations. E.g. bigrams st,er,ae,ie.")
-	flag.BoolVar(&useLetters, "letters", true, "Create letter ngram combinations. E.g. bigrams st,er,ae,ie.")
+	flag.BoolVar(&useLetters, "l", false, "Create letter ngram combinations. E.g. bigrams st,er,ae,ie.")
+	flag.BoolVar(&useLetters, "letters", false, "Create letter ngram combinations. E.g. bigrams st,er,ae,ie.")
+
+	var tokenizerNames string
+	flag.StringVar(&tokenizerNames, "t", "", "Comma separated names of the tokenizers to use. E.g. letters,words")
+	flag.StringVar(&tokenizerNames, "tokenizer", "", "Comma separated names of the tokenizers to use. E.g. letters,words")
 
 	var useWords bool
 	flag.BoolVar(&useWords, "w", false, "Create word ngram combinations. E.g. bigrams he jumped, she walked")
 	flag.BoolVar(&useWords, "words", false, "Create word ngram combinations. E.g. bigrams he jumped, she walked")
 
+	var graphemes bool
+	flag.BoolVar(&graphemes, "g", false, "Parse letters as grapheme clusters and multi-rune letters instead of single runes.")
+	flag.BoolVar(&graphemes, "graphemes", false, "Parse letters as grapheme clusters and multi-rune letters instead of single runes.")
+
+	var wordBoundaries bool
+	flag.BoolVar(&wordBoundaries, "word-boundaries", false, "Split the words on punctuation using the apostrophes and hyphens of the language.")
+
+	var stopWords bool
+	flag.BoolVar(&stopWords, "stopwords", false, "Filter the built-in stop words of the language from the word ngrams.")
+
+	var stopWordsPath string
+	flag.StringVar(&stopWordsPath, "stopwords-file", "", "Path to a file of stop words to filter from the word ngrams.")
+
+	var stopWordFilter string
+	flag.StringVar(&stopWordFilter, "stopwords-filter", "remove", "How stop words are filtered: remove, any or all.")
+
+	var allowPath string
+	flag.StringVar(&allowPath, "allow", "", "Path to a file of the only words to keep before word ngrams are formed.")
+
+	var denyPath string
+	flag.StringVar(&denyPath, "deny", "", "Path to a file of words to remove before word ngrams are formed.")
+
+	var top int
+	flag.IntVar(&top, "top", 0, "Only keep the approximate top N most frequent tokens using a bounded amount of memory.")
+
+	var memoryBudget string
+	flag.StringVar(&memoryBudget, "memory", "", "Memory budget after which the frequency tables are spilled to disk. E.g. 8GB")
+
+	var spillDir string
+	flag.StringVar(&spillDir, "temp-dir", "", "Directory in which temporary files are created when the memory budget is exceeded.")
+
+	var stem bool
+	flag.BoolVar(&stem, "stem", false, "Reduce the words to their stem before word ngrams are formed.")
+
+	var stemOutput string
+	flag.StringVar(&stemOutput, "stem-output", "stems", "How stemmed words are written: stems or surface (the most frequent form).")
+
+	var rewrites string
+	flag.StringVar(&rewrites, "rewrite", "", "Comma separated list of tokens to rewrite: number, url and/or email.")
+
+	var filterPath string
+	flag.StringVar(&filterPath, "filter", "", "Path to a file of rules used to rewrite or drop tokens.")
+
+	var minLength int
+	flag.IntVar(&minLength, "min-length", 0, "Drop the tokens that have less than the number of characters.")
+
+	var maxLength int
+	flag.IntVar(&maxLength, "max-length", 0, "Drop the tokens that have more than the number of characters.")
+
+	var minCount int
+	flag.IntVar(&minCount, "min-count", 0, "Only keep the tokens that have a count of at least the number when saving.")
+
+	var keepTop int
+	flag.IntVar(&keepTop, "keep-top", 0, "Only keep the number of most frequent tokens when saving.")
+
+	var coverage float64
+	flag.Float64Var(&coverage, "coverage", 0, "Only keep the most frequent tokens that cover the percentage of the total count when saving. E.g. 95")
+
+	var minPercentage float64
+	flag.Float64Var(&minPercentage, "min-percentage", 0, "Only keep the tokens that make up at least the percentage of the total count when saving. E.g. 0.01")
+
+	var transform bool
+	flag.BoolVar(&transform, "x", false, "Apply the filters to the existing frequency tables given as input.")
+	flag.BoolVar(&transform, "transform", false, "Apply the filters to the existing frequency tables given as input.")
+
+	var tokens bool
+	flag.BoolVar(&tokens, "tokens", false, "Write every ngram token produced to the out path or STDOUT instead of creating frequency tables.")
+
+	var tokenSource bool
+	flag.BoolVar(&tokenSource, "token-source", false, "Write the input source and byte offset along with every token when --tokens is used.")
+
+	var generate int
+	flag.IntVar(&generate, "generate", 0, "Generate the number of words from a Markov model of the existing frequency tables given as input.")
+
+	var seed int64
+	flag.Int64Var(&seed, "seed", 0, "Seed of the random number generator used by --generate. Defaults to a random seed.")
+
+	var wordLength string
+	flag.StringVar(&wordLength, "word-length", "", "Number of letters of the words generated from letter ngrams. E.g. 5 or a range 3-8.")
+
+	var smoothing string
+	flag.StringVar(&smoothing, "smoothing", "", "Smoothing of the transition probabilities used by --generate: none, laplace or kneser-ney.")
+
+	var detect int
+	flag.IntVar(&detect, "detect", 0, "Report the number of most likely languages of each input file.")
+
+	var profiles string
+	flag.StringVar(&profiles, "profiles", "", "Directory containing the <language-code>-letters-<size>.csv files that extend the built-in profiles used by --detect.")
+
+	var method string
+	flag.StringVar(&method, "method", "", "Method used by --detect: cavnar-trenkle or naive-bayes.")
+
+	var filterLines bool
+	flag.BoolVar(&filterLines, "filter-lines", false, "Drop the lines of the input that are not written in the language.")
+
+	var lineThreshold float64
+	flag.Float64Var(&lineThreshold, "line-threshold", -1, "Minimum score (0 to 1) a line needs to be kept by --filter-lines.")
+
+	var alphabetReportPath string
+	flag.StringVar(&alphabetReportPath, "alphabet-report", "", "Write the number of runes that are (and are not) part of the alphabet of each input to this path.")
+
+	var caseMode string
+	flag.StringVar(&caseMode, "case", "lower", "How the case of letters and words are treated: lower, preserve, upper or fold.")
+
 	var discover bool
 	flag.BoolVar(&discover, "d", false, "Discover the non-whitespace letters used and write a languages file to the out path.")
 	flag.BoolVar(&discover, "discover", false, "Discover the non-whitespace letters used and write a languages file to the out path.")
 
+	var code string
+	flag.StringVar(&code, "code", "", "Code of the language written to the languages file by --discover. (default \"unknown\")")
+
+	var name string
+	flag.StringVar(&name, "name", "", "Name of the language written to the languages file by --discover. Defaults to the existing name or the code.")
+
+	var union bool
+	flag.BoolVar(&union, "union", false, "Combine the alphabet suggested by --discover with the letters of the language already in the languages file.")
+
+	var alphabetThreshold float64
+	flag.Float64Var(&alphabetThreshold, "alphabet-threshold", -1, "Minimum share (0 to 1) of the letter occurrences a letter needs to be part of the alphabet suggested by --discover.")
+
 	var update bool
 	flag.BoolVar(&update, "u", false, "Update the existing ngram output file.")
 	flag.BoolVar(&update, "update", false, "Update the existing ngram output file.")
@@ -381,6 +1599,9 @@ func parseArgs(stdOut io.Writer) ([]optionFunc, error) {
 	var availableLangs bool
 	flag.BoolVar(&availableLangs, "available", false, "List the available languages.")
 
+	var availableTokenizers bool
+	flag.BoolVar(&availableTokenizers, "available-tokenizers", false, "List the available tokenizers.")
+
 	flag.Usage = customUsage
 
 	flag.Parse()
@@ -397,9 +1618,18 @@ func parseArgs(stdOut io.Writer) ([]optionFunc, error) {
 		return nil, ErrExitWithNoErr
 	}
 
+	if availableTokenizers {
+		printAvailableTokenizers(stdOut)
+		return nil, ErrExitWithNoErr
+	}
+
 	opts = append(opts, withDefaults())
 	opts = append(opts, withInputPaths(flag.Args()))
-	opts = append(opts, withSize(tokenSize))
+	opts = append(opts, withSizes(tokenSize))
+
+	if skip != 0 {
+		opts = append(opts, withSkip(skip))
+	}
 	opts = append(opts, withLanguageCode(alphabet.LanguageCode(langCode)))
 
 	if outPath != "" {
@@ -418,10 +1648,171 @@ func parseArgs(stdOut io.Writer) ([]optionFunc, error) {
 		opts = append(opts, withWords())
 	}
 
+	if tokenizerNames != "" {
+		for _, name := range strings.Split(tokenizerNames, ",") {
+			opts = append(opts, withTokenizer(strings.TrimSpace(name)))
+		}
+	}
+
+	if graphemes {
+		opts = append(opts, withGraphemes())
+	}
+
+	if wordBoundaries {
+		opts = append(opts, withWordBoundaries())
+	}
+
+	if caseMode != "" {
+		opts = append(opts, withCase(caseMode))
+	}
+
+	if rewrites != "" {
+		opts = append(opts, withRewrites(rewrites))
+	}
+
+	if filterPath != "" {
+		opts = append(opts, withFilterFile(filterPath))
+	}
+
+	if minLength != 0 {
+		opts = append(opts, withMinLength(minLength))
+	}
+
+	if maxLength != 0 {
+		opts = append(opts, withMaxLength(maxLength))
+	}
+
+	if minCount != 0 {
+		opts = append(opts, withMinCount(minCount))
+	}
+
+	if keepTop != 0 {
+		opts = append(opts, withKeepTop(keepTop))
+	}
+
+	if coverage != 0 {
+		opts = append(opts, withCoverage(coverage))
+	}
+
+	if minPercentage != 0 {
+		opts = append(opts, withMinPercentage(minPercentage))
+	}
+
+	if transform {
+		opts = append(opts, withTransform())
+	}
+
+	if tokens {
+		opts = append(opts, withTokens())
+	}
+
+	if tokenSource {
+		opts = append(opts, withTokenSource())
+	}
+
+	if generate != 0 {
+		opts = append(opts, withGenerate(generate))
+	}
+
+	// A seed of 0 is valid, so check whether the flag was given instead of its value
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			opts = append(opts, withSeed(seed))
+		}
+	})
+
+	if wordLength != "" {
+		opts = append(opts, withWordLength(wordLength))
+	}
+
+	if smoothing != "" {
+		opts = append(opts, withSmoothing(smoothing))
+	}
+
+	if detect != 0 {
+		opts = append(opts, withDetect(detect))
+	}
+
+	if filterLines {
+		opts = append(opts, withFilterLines())
+	}
+
+	if alphabetReportPath != "" {
+		opts = append(opts, withAlphabetReport(alphabetReportPath))
+	}
+
+	if lineThreshold != -1 {
+		opts = append(opts, withLineThreshold(lineThreshold))
+	}
+
+	if profiles != "" {
+		opts = append(opts, withProfiles(profiles))
+	}
+
+	if method != "" {
+		opts = append(opts, withMethod(method))
+	}
+
+	if stopWords {
+		opts = append(opts, withBuiltinStopWords())
+	}
+
+	if stopWordsPath != "" {
+		opts = append(opts, withStopWordsFile(stopWordsPath))
+	}
+
+	if stopWordFilter != "" {
+		opts = append(opts, withStopWordFilter(stopWordFilter))
+	}
+
+	if allowPath != "" {
+		opts = append(opts, withAllowListFile(allowPath))
+	}
+
+	if denyPath != "" {
+		opts = append(opts, withDenyListFile(denyPath))
+	}
+
+	if top != 0 {
+		opts = append(opts, withTop(top))
+	}
+
+	if memoryBudget != "" {
+		opts = append(opts, withMemoryBudget(memoryBudget))
+	}
+
+	if spillDir != "" {
+		opts = append(opts, withSpillDir(spillDir))
+	}
+
+	if stem {
+		opts = append(opts, withStem())
+	}
+
+	if stemOutput != "" {
+		opts = append(opts, withStemOutput(stemOutput))
+	}
+
 	if discover {
 		opts = append(opts, withDiscoverLanguage())
 	}
 
+	if code != "" {
+		opts = append(opts, withDiscoverCode(code))
+	}
+
+	if name != "" {
+		opts = append(opts, withDiscoverName(name))
+	}
+
+	if alphabetThreshold != -1 {
+		opts = append(opts, withAlphabetThreshold(alphabetThreshold))
+	}
+
+	if union {
+		opts = append(opts, withUnion())
+	}
+
 	if update {
 		opts = append(opts, withUpdate())
 	}
@@ -457,23 +1848,103 @@ func resolve() optionFunc {
 			return fmt.Errorf("expected at least one input path")
 		}
 
-		// validate language exists
-		if _, ok := opt.languages[opt.langCode]; !ok {
+		// validate language exists and use its code when an alias or language tag was given (e.g. afr or pt-BR)
+		lang, err := opt.languages.Get(opt.langCode)
+		if err != nil {
 			return fmt.Errorf("failed to find the language %q", opt.langCode)
 		}
+		opt.langCode = lang.Code
+
+		// built-in stop words of the language
+		if opt.builtinStopWords {
+			list, err := ngrams.BuiltinStopWords(opt.langCode)
+			if err != nil {
+				return err
+			}
+			opt.stopWords = mergeWordLists(opt.stopWords, list)
+		}
+
+		// built-in stemmer of the language
+		if opt.stem {
+			stemmer, err := ngrams.BuiltinStemmer(opt.langCode)
+			if err != nil {
+				return err
+			}
+			opt.stemmer = stemmer
+		}
+		if opt.stemOutput == "" {
+			opt.stemOutput = stemOutputStems
+		}
+
+		// letters are the default
+		if !opt.letters && !opt.words && len(opt.tokenizers) == 0 {
+			opt.letters = true
+		}
+
+		if opt.top > 0 && opt.memoryBudget > 0 {
+			return fmt.Errorf("--top and --memory can not be used together")
+		}
+
+		if opt.discover && opt.transform {
+			return fmt.Errorf("--discover and --transform can not be used together")
+		}
+
+		if opt.discover {
+			if opt.discoverCode == "" {
+				opt.discoverCode = "unknown"
+			}
+		} else if opt.discoverCode != "" || opt.discoverName != "" || opt.union {
+			return fmt.Errorf("--code, --name and --union can only be used together with --discover")
+		}
+
+		if opt.tokens {
+			if opt.discover || opt.transform || opt.update {
+				return fmt.Errorf("--tokens can not be used together with --discover, --transform or --update")
+			}
+			if opt.stemOutput == stemOutputSurface {
+				return fmt.Errorf("--tokens can only be used with --stem-output stems")
+			}
+		} else if opt.tokenSource {
+			return fmt.Errorf("--token-source can only be used together with --tokens")
+		}
+
+		if opt.generate {
+			if opt.discover || opt.transform || opt.tokens || opt.update {
+				return fmt.Errorf("--generate can not be used together with --discover, --transform, --tokens or --update")
+			}
+			if !opt.seedSet {
+				opt.seed = time.Now().UnixNano()
+			}
+			if opt.minWordLength == 0 {
+				opt.minWordLength = 3
+				opt.maxWordLength = 8
+			}
+		}
+
+		if opt.detect {
+			if opt.discover || opt.transform || opt.tokens || opt.generate || opt.update {
+				return fmt.Errorf("--detect can not be used together with --discover, --transform, --tokens, --generate or --update")
+			}
+		} else if opt.profilesDir != "" && !opt.filterLines {
+			return fmt.Errorf("--profiles can only be used together with --detect or --filter-lines")
+		}
+
+		if opt.alphabetReportPath != "" && (opt.discover || opt.transform || opt.generate || opt.detect) {
+			return fmt.Errorf("--alphabet-report can not be used together with --discover, --transform, --generate or --detect")
+		}
+
+		if opt.filterLines && (opt.discover || opt.transform || opt.generate || opt.detect) {
+			return fmt.Errorf("--filter-lines can not be used together with --discover, --transform, --generate or --detect")
+		}
 
-		// default output path
-		if opt.outPath == "" {
+		// default output path (the tokens, generated text and language report are written to STDOUT by default)
+		if opt.outPath == "" && !opt.tokens && !opt.generate && !opt.detect {
 			if opt.discover {
 				opt.outPath = "./languages.csv"
-			} else {
-				var ngramType string
-				if opt.words {
-					ngramType = "words"
-				} else {
-					ngramType = "letters"
-				}
-				opt.outPath = fmt.Sprintf("./%s-%s-%d.csv", opt.langCode, ngramType, opt.tokenSize)
+			} else if opt.transform {
+				opt.outPath = "./transformed.csv"
+			} else if !opt.isMultipleOutputs() {
+				opt.outPath = opt.defaultOutputPath(opt.tokenizerNames()[0], opt.tokenSize)
 			}
 		}
 
@@ -481,6 +1952,90 @@ func resolve() optionFunc {
 	}
 }
 
+// isSizeRange returns true if a range of ngram sizes will be generated.
+func (opt *options) isSizeRange() bool {
+	return opt.maxTokenSize > opt.tokenSize
+}
+
+// sizes returns the ngram sizes that will be generated.
+func (opt *options) sizes() []int {
+	if !opt.isSizeRange() {
+		return []int{opt.tokenSize}
+	}
+
+	result := make([]int, 0, opt.maxTokenSize-opt.tokenSize+1)
+	for size := opt.tokenSize; size <= opt.maxTokenSize; size++ {
+		result = append(result, size)
+	}
+	return result
+}
+
+// sizeDescription returns the ngram size or range of sizes as shown to the user.
+func (opt *options) sizeDescription() string {
+	if !opt.isSizeRange() {
+		return strconv.Itoa(opt.tokenSize)
+	}
+	return fmt.Sprintf("%d-%d", opt.tokenSize, opt.maxTokenSize)
+}
+
+// tokenizerNames returns the names of the tokenizers that will be used. E.g. letters and/or words.
+func (opt *options) tokenizerNames() []string {
+	names := make([]string, 0, 2+len(opt.tokenizers))
+	if opt.letters {
+		names = append(names, ngrams.TokenizerLetters)
+	}
+	if opt.words {
+		names = append(names, ngrams.TokenizerWords)
+	}
+	return append(names, opt.tokenizers...)
+}
+
+// tokenizerDescription returns the tokenizers being used as shown to the user. E.g. "letter and word".
+func (opt *options) tokenizerDescription() string {
+	names := opt.tokenizerNames()
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, "s")
+	}
+	return strings.Join(names, " and ")
+}
+
+// isMultipleOutputs returns true if more than one frequency table will be generated.
+func (opt *options) isMultipleOutputs() bool {
+	return opt.isSizeRange() || len(opt.tokenizerNames()) > 1
+}
+
+// defaultOutputPath returns the output path used for the tokenizer and ngram size when no --out path was specified.
+func (opt *options) defaultOutputPath(name string, size int) string {
+	if opt.skip > 0 {
+		return fmt.Sprintf("./%s-%s-%d-skip%d.csv", opt.langCode, name, size, opt.skip)
+	}
+	return fmt.Sprintf("./%s-%s-%d.csv", opt.langCode, name, size)
+}
+
+// outputPath returns the path to where the frequency table for the tokenizer and ngram size will be stored.
+// When multiple tables are generated and an --out path was specified then the tokenizer name (if more than
+// one tokenizer is used) and the size (if a range of sizes are generated) is appended to the name of the file.
+// E.g. out.csv becomes out-letters-1.csv, out-words-1.csv etc.
+func (opt *options) outputPath(name string, size int) string {
+	if !opt.isMultipleOutputs() {
+		return opt.outPath
+	}
+
+	if opt.outPath == "" {
+		return opt.defaultOutputPath(name, size)
+	}
+
+	ext := filepath.Ext(opt.outPath)
+	path := strings.TrimSuffix(opt.outPath, ext)
+	if len(opt.tokenizerNames()) > 1 {
+		path = fmt.Sprintf("%s-%s", path, name)
+	}
+	if opt.isSizeRange() {
+		path = fmt.Sprintf("%s-%d", path, size)
+	}
+	return path + ext
+}
+
 func printVersion(w io.Writer) {
 	_, _ = io.WriteString(w, compiledinfo.UsageNameAndVersion()+"\n")
 }
@@ -508,6 +2063,23 @@ func printAvailableLanguages(w io.Writer, langPath string) error {
 	return nil
 }
 
+// mergeWordLists adds the words of the src list to the dst list. If dst is nil then src is returned.
+func mergeWordLists(dst ngrams.WordList, src ngrams.WordList) ngrams.WordList {
+	if dst == nil {
+		return src
+	}
+	for word := range src {
+		dst.Add(word)
+	}
+	return dst
+}
+
+func printAvailableTokenizers(w io.Writer) {
+	for _, name := range ngrams.TokenizerNames() {
+		fmt.Fprintln(w, name)
+	}
+}
+
 // Check if the path exists.
 // If the path exists then (true, nil) is returned.
 // If the path does not exist then (false, nil) is returned.
@@ -593,21 +2165,71 @@ INPUT:
 OPTIONS:
   -a, --lang string
   	Alphabet language code. E.g. en = English (default "en")
+  	ISO 639-2/3 codes and BCP 47 language tags are also accepted. E.g. eng, pt-BR or sr-Latn.
 
   --available
   	List the available languages. Displays the built-in languages if no language file is provided.
 
+  --available-tokenizers
+  	List the names of the available tokenizers that can be used with --tokenizer.
+
+  --case string
+  	How the case of letters and words are treated. (default "lower")
+  	  lower: convert to lowercase using the default unicode rules.
+  	  preserve: keep the case as found in the input (e.g. for proper nouns or typing practice with Shift).
+  	  upper: convert to uppercase using the case mapping rules of the language.
+  	  fold: convert to lowercase using the locale specific rules of the language (e.g. Turkish dotted and dotless i).
+
   -d, --discover
   	Discover the non-whitespace letters used in the input sources and write a languages file to the out path.
+  	The occurrences of each rune are counted and a report of the runes grouped by unicode script and general
+  	category (e.g. Latin Ll, Common Po) is written to STDOUT. Only the letters (and marks) that make up at least
+  	the --alphabet-threshold share of all the letter occurrences are written to the languages file. This leaves
+  	out the punctuation, digits and stray symbols (e.g. an emoji found once).
+  	When the languages file already exists then the language is added, or its row is replaced, and all the
+  	languages are written back sorted by code. Comments are kept where they are. This allows discovering one
+  	language at a time into the same file.
+
+  --code string
+  	Code of the language written to the languages file by --discover. E.g. af (default "unknown")
+
+  --name string
+  	Name of the language written to the languages file by --discover. E.g. Afrikaans (defaults to the name
+  	already in the languages file or the code)
+
+  --union
+  	Combine the alphabet suggested by --discover with the letters of the language already in the languages file
+  	instead of replacing them.
+
+  --alphabet-threshold float
+  	Minimum share (0 to 1) of all the letter occurrences a letter needs to be part of the alphabet suggested
+  	by --discover. (default 0.0001)
+
+  -g, --graphemes
+  	Parse letters as extended grapheme clusters (user-perceived characters) instead of single runes.
+  	Multi-rune letters of the language (e.g. Dutch ij) are counted as a single letter.
 
   -l, --letters
-  	Create letter ngram combinations. E.g. bigrams st,er,ae,ie. (default true)
+  	Create letter ngram combinations. E.g. bigrams st,er,ae,ie.
+  	This is the default if neither --letters nor --words are specified.
+
+  -t, --tokenizer string
+  	Comma separated names of the tokenizers to use. E.g. letters,words
+  	The built-in tokenizers are letters and words (same as --letters and --words). Other tokenizers can be
+  	registered with ngrams.RegisterTokenizer when building a custom version of the app.
+  	Each tokenizer's table is written to its own output file when more than one tokenizer is used:
+  	  <language-code>-<tokenizer>-<size>.csv
+  	  or <out>-<tokenizer>.csv if --out is specified.
 
   -w, --words
   	Create word ngram combinations. E.g. bigrams "he jumped", "she walked"
+  	Both --letters and --words can be specified to create the letter and word frequency tables while only
+  	reading the input files once. Each table is written to its own output file:
+  	  <language-code>-<words|letters>-<size>.csv
+  	  or <out>-<words|letters>.csv if --out is specified.
 
   --languages string
-  	Path to a languages definition file. See the format section for more details.
+  	Path to a languages definition file (CSV or JSON). See the format section for more details.
 
   -o, --out string
   	Path to where the output will be stored. See the format section for more details.
@@ -616,10 +2238,180 @@ OPTIONS:
   	In discover mode the output file will be a languages file.
   	If the --out option is not specified then the output file will be derived in the following way:
   	  <language-code>-<words|letters>-<size>.csv
+  	  <language-code>-<words|letters>-<size>-skip<skip>.csv if --skip is used.
   	  or languages.csv if --discover mode is used.
+  	  or transformed.csv if --transform mode is used.
+  	  or STDOUT if --tokens, --generate or --detect mode is used.
 
-  -s, --size int
+  -s, --size string
   	Ngram size. The number of letters or words that form a single ngram. (default 1)
+  	A range of sizes (e.g. 1-5) can be specified to generate a frequency table for each size while only reading
+  	the input files once. Each table is written to its own output file:
+  	  <language-code>-<words|letters>-<size>.csv
+  	  or <out>-<size>.csv if --out is specified.
+  	  or <out>-<words|letters>-<size>.csv if --out, --letters and --words are specified.
+
+  -k, --skip int
+  	Skip-gram distance. The maximum number of letters or words that may be skipped in total to form an ngram.
+  	Each skipped letter or word is marked with an underscore. E.g. letter bigrams th,he,t_e or words "of _ the".
+  	Real underscores and backslashes in the letters or words are escaped with a backslash. E.g. snake\_case.
+  	The skip is recorded in the metadata of the output file. (default 0)
+
+  --rewrite string
+  	Comma separated list of the kind of tokens to rewrite: number, url and/or email.
+  	Numbers are rewritten as <NUM>, URLs as <URL> and email addresses as <EMAIL>.
+
+  --filter string
+  	Path to a file of rules used to rewrite or drop tokens before they are added to the frequency table.
+  	The rules are applied after --rewrite. See the format section for more details.
+
+  --min-length int
+  	Drop the tokens that have less than the number of characters. Applied after --filter.
+
+  --max-length int
+  	Drop the tokens that have more than the number of characters. Applied after --filter.
+
+  --min-count int
+  	Only keep the tokens that have a count of at least the number when the frequency table is saved.
+
+  --keep-top int
+  	Only keep the number of most frequent tokens when the frequency table is saved.
+
+  --coverage float
+  	Only keep the most frequent tokens that together cover the percentage of the total count. E.g. --coverage 95
+
+  --min-percentage float
+  	Only keep the tokens that make up at least the percentage of the total count. E.g. --min-percentage 0.01
+
+  	The pruning options can be combined in which case a token is only kept if it meets all of them.
+  	The total count before pruning is recorded in the metadata (#meta,total and #meta,pruned) so that
+  	the percentages remain relative to all of the tokens.
+
+  -x, --transform
+  	Instead of parsing text, the input paths are existing frequency tables that are combined and then
+  	transformed by applying --rewrite, --filter, --min-length, --max-length and the pruning options.
+  	The output is written to --out or transformed.csv if --out is not specified.
+
+  --tokens
+  	Instead of creating frequency tables, every ngram token produced is written (one per line and in the order
+  	produced) to --out or STDOUT if --out is not specified. The same tokenizer options as in normal mode are used.
+  	E.g. ngrams --tokens --words --size 2 corpus.txt | sort | uniq -c
+
+  --token-source
+  	Write the input source and byte offset of each token when --tokens is used. The fields are separated by tabs:
+  	  <token>	<source>	<offset>
+  	The offset is that of the rune that completed the ngram. For word ngrams this is the whitespace
+  	following the last word.
+
+  --generate int
+  	Instead of parsing text, the input paths are existing frequency tables (e.g. letter trigrams) that are
+  	combined into a Markov model from which the number of words are generated. E.g. --generate 50
+  	Letter ngrams produce pseudo-words and word ngrams (--words) produce sequences of words. The text is written
+  	to --out or STDOUT if --out is not specified. The tables need to be of the same size and skip-grams
+  	can not be used. Word tables require --words.
+
+  --seed int
+  	Seed of the random number generator used by --generate. The same seed and frequency tables produce the
+  	same text. Defaults to a random seed (displayed with --verbose).
+
+  --word-length string
+  	Number of letters of the words generated from letter ngrams. E.g. 5 or a range 3-8. (default 3-8)
+
+  --smoothing string
+  	Smoothing of the transition probabilities used by --generate. (default "none")
+  	  none: only the observed transitions are used.
+  	  laplace: additive smoothing.
+  	  kneser-ney: interpolated Kneser-Ney smoothing.
+
+  --detect int
+  	Instead of creating frequency tables, report the number of most likely languages of each input file.
+  	E.g. --detect 3 book.txt
+  	The built-in profiles of the languages (see --available) are used and can be extended with --profiles.
+  	The built-in profiles are generated from small samples, use --profiles with profiles created from real
+  	corpora to reliably tell closely related languages (e.g. Zulu and Xhosa) apart.
+  	Each file inside of a zip file is reported separately. The report is written to --out or STDOUT if --out
+  	is not specified. The source is followed by a line for each language:
+  	  <language-code>	<language-name>	<confidence>
+
+  --profiles string
+  	Directory containing the letter frequency tables used as additional language profiles by --detect.
+  	The files are named <language-code>-letters-<size>.csv (as created in normal mode). E.g. af-letters-3.csv
+  	A profile replaces the built-in profile of the same language. Also used by --filter-lines.
+
+  --filter-lines
+  	Drop the lines of the input that are not written in the language (--lang) before the ngrams are formed.
+  	E.g. English lines found in an Afrikaans corpus. Each line is scored by the ratio of its letters that are
+  	part of the alphabet multiplied by the confidence of the language relative to the most likely language
+  	identified from the language profiles (see --detect). Only the alphabet coverage is used when there is no
+  	profile for the language. The number of lines rejected for each input source is reported once the input
+  	has been processed.
+
+  --line-threshold float
+  	Minimum score (0 to 1) a line needs to be kept by --filter-lines. (default 0.5)
+
+  --alphabet-report string
+  	Write the alphabet coverage statistics to this path. For each input source (and in total) the number of
+  	non-whitespace runes that are part of the alphabet of the language (accepted) and those that are not
+  	(rejected) are reported along with the 10 most frequently rejected runes. E.g. Cyrillic text found in an
+  	Afrikaans corpus. The statistics are also displayed in --verbose mode.
+
+  --method string
+  	Method used by --detect to identify the languages. (default "cavnar-trenkle")
+  	  cavnar-trenkle: compare the rank order of the most frequent ngrams.
+  	  naive-bayes: the likelihood of the ngrams given the language profile.
+
+  --word-boundaries
+  	Split the words on punctuation instead of only on whitespace. Only the letters and numbers of a word are
+  	kept along with the apostrophes and hyphens of the language found between them (see the format section).
+  	E.g. "Don’t," becomes don't and "well-known." becomes well-known. The apostrophes and hyphens are
+  	replaced with the first one listed by the language so that don’t and don't are counted as the same word.
+
+  --stopwords
+  	Filter the built-in stop words of the language from the word ngrams. E.g. "the", "of", "die", "van".
+  	Built-in stop words are available for all of the built-in languages.
+
+  --stopwords-file string
+  	Path to a file of stop words to filter from the word ngrams. Can be combined with --stopwords.
+  	See the format section for more details.
+
+  --stopwords-filter string
+  	How stop words are filtered from the word ngrams. (default "remove")
+  	  remove: remove the stop words before the word ngrams are formed.
+  	  any: drop the word ngrams that contain any stop word.
+  	  all: drop the word ngrams that only consist of stop words.
+
+  --allow string
+  	Path to a file of the only words to keep before the word ngrams are formed.
+
+  --deny string
+  	Path to a file of words to remove before the word ngrams are formed.
+
+  --top int
+  	Only keep the approximate top N most frequent tokens of each frequency table. E.g. --top 100000
+  	A bounded amount of memory is used instead of counting every distinct token exactly. The counts
+  	may be overestimated and the error bounds are recorded in the metadata of the output:
+  	  topk_total: the total count of all the tokens seen.
+  	  topk_max_error: the maximum amount by which any count may be overestimated.
+  	  cms_epsilon, cms_delta: a count estimate exceeds the true count by more than epsilon * total
+  	  with a probability of delta.
+
+  --memory string
+  	Memory budget after which the frequency tables are spilled as sorted runs to temporary files. E.g. --memory 8GB
+  	The runs are merged when the output is saved and the counts remain exact. The budget is shared by all of
+  	the frequency tables being generated. Can not be used together with --top.
+
+  --temp-dir string
+  	Directory in which the temporary files are created when the memory budget is exceeded.
+  	Defaults to the system's temporary directory.
+
+  --stem
+  	Reduce the words to their stem before the word ngrams are formed. E.g. "walks", "walked" and "walking" become "walk".
+  	Built-in stemmers are available for: af, ar, da, de, en, es, fi, fr, nl and sv.
+
+  --stem-output string
+  	How the stemmed words are written to the word ngrams. (default "stems")
+  	  stems: write the stems.
+  	  surface: write the most frequent surface form of each stem.
 
   -u, --update
   	Update the existing ngram output file.
@@ -639,17 +2431,56 @@ OPTIONS:
 FORMATS:
   output.csv: Used by --out to write the ngram frequency table.
   	#token,count,percentage
+	#meta,skip,1
 	the,142,0.094522
 	...
 
+  	Lines starting with #meta describe how the table was created and are optional.
+  	The percentage column is optional since the percentages are derived from the counts when loaded.
+
   languages.csv: Used by --languages to provide supported languages.
-  	#code,name,letters
+  	#code,name,letters,multiletters
 	af,Afrikaans,abcdefghijklmnopqrstuvwxyzáêéèëïíîôóúû
+	nl,Dutch,abcdefghijklmnopqrstuvwxyzàäèéëïĳöü,ij
+	...
+
+  	The multiletters column is optional and lists the letters made up of more than one rune (separated by spaces).
+
+  languages.json: The versioned JSON languages format that also describes the script (ISO 15924), writing
+  	direction, locale, uppercase mappings, the apostrophes and hyphens that can be part of a word and the
+  	aliases (e.g. ISO 639-2/3 codes) the language can be found by.
+  	{"version": 1, "languages": [
+	  {"code": "nl", "name": "Dutch", "script": "Latn", "direction": "ltr", "locale": "nl",
+	   "letters": "abcdefghijklmnopqrstuvwxyz...", "multiletters": ["ij"], "uppercase": {"ij": "IJ"},
+	   "apostrophes": "'’", "hyphens": "-", "aliases": ["nld", "dut"]},
+	  ...
+	]}
+
+  	Only version, code and letters are required. Files starting with { are loaded as JSON.
+  	When --discover is used the languages file is written in the JSON format if the --out path ends with .json
+  	and otherwise in the CSV format.
+
+  rules.txt: Used by --filter to rewrite or drop tokens. One rule per line and applied in order.
+  	# comment
+	drop ^\* \*$
+	rewrite <YEAR> \b1[89]\d\d\b
+	number
+	min 2
+	...
+
+  	drop <regex>: drop the tokens matching the regular expression.
+  	rewrite <replacement> <regex>: replace the matches of the regular expression with the replacement.
+  	min <length> or max <length>: drop the tokens with less or more than length characters.
+  	number, url or email: replace numbers, URLs or email addresses with <NUM>, <URL> or <EMAIL>.
+
+  words.txt: Used by --stopwords-file, --allow and --deny to provide a list of words.
+  	# comment
+	the
+	of and
 	...
 
-  	When --discover is used the file format will be a CSV like the following:
-  	  #code,name,letters
-  	  unknown,unknown,abc...
+  	One or more words per line separated by whitespace. Lines starting with # are ignored.
+  	Words are matched case insensitively and leading or trailing punctuation is ignored.
 
 `)
 
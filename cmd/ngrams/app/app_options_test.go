@@ -28,6 +28,8 @@ import (
 	"testing"
 
 	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/langid"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -39,8 +41,31 @@ func TestOptionsWithDefaults(t *testing.T) {
 	assert.Equal(t, alphabet.LanguageCode("en"), opt.langCode)
 	assert.Equal(t, alphabet.BuiltinLanguages(), opt.languages)
 	assert.Equal(t, 1, opt.tokenSize)
+	assert.Equal(t, 0, opt.top)
+	assert.Equal(t, uint64(0), opt.memoryBudget)
+	assert.Equal(t, 0, opt.skip)
+	assert.False(t, opt.letters)
 	assert.False(t, opt.words)
+	assert.False(t, opt.graphemes)
+	assert.Equal(t, ngrams.CaseLower, opt.caseMode)
+	assert.Nil(t, opt.stopWords)
+	assert.Equal(t, ngrams.StopWordsRemove, opt.stopWordFilter)
+	assert.Nil(t, opt.allowList)
+	assert.Nil(t, opt.denyList)
+	assert.Nil(t, opt.filter)
+	assert.Nil(t, opt.pruner)
+	assert.False(t, opt.stem)
+	assert.Nil(t, opt.stemmer)
+	assert.False(t, opt.transform)
 	assert.False(t, opt.discover)
+	assert.False(t, opt.tokens)
+	assert.False(t, opt.tokenSource)
+	assert.False(t, opt.generate)
+	assert.False(t, opt.detect)
+	assert.False(t, opt.filterLines)
+	assert.Equal(t, langid.DefaultLineThreshold, opt.lineThreshold)
+	assert.Equal(t, langid.MethodCavnarTrenkle, opt.method)
+	assert.Equal(t, ngrams.SmoothingNone, opt.smoothing)
 	assert.False(t, opt.update)
 	assert.Equal(t, "", opt.outPath)
 	assert.Empty(t, opt.inputs)
@@ -60,6 +85,12 @@ func TestParseArgs(t *testing.T) {
 	validLanguages := validLanguagesFile(t)
 	defer os.Remove(validLanguages)
 
+	wordList := wordListFile(t)
+	defer os.Remove(wordList)
+
+	rules := rulesFile(t)
+	defer os.Remove(rules)
+
 	testCases := []struct {
 		desc       string
 		args       string
@@ -71,9 +102,31 @@ func TestParseArgs(t *testing.T) {
 		{desc: "invalid size: --size", args: "--size 0 ./in.txt", errMsg: "invalid ngram size 0"},
 		{desc: "size: -s 4", args: "-s 4 ./in.txt", expected: []optionFunc{withSize(4)}},
 		{desc: "size: --size 4", args: "--size 4 ./in.txt", expected: []optionFunc{withSize(4)}},
+		{desc: "size range: -s 1-3", args: "-s 1-3 ./in.txt", expected: []optionFunc{withSizeRange(1, 3)}},
+		{desc: "size range: --size 2-5", args: "--size 2-5 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, []int{2, 3, 4, 5}, opt.sizes())
+		}},
+		{desc: "invalid size range: -s 3-1", args: "-s 3-1 ./in.txt", errMsg: "invalid ngram size range 3-1"},
+		{desc: "invalid size range: -s 0-2", args: "-s 0-2 ./in.txt", errMsg: "invalid ngram size range 0-2"},
+		{desc: "invalid size: -s a", args: "-s a ./in.txt", errMsg: "invalid ngram size \"a\""},
+		{desc: "invalid size range: -s 1-", args: "-s 1- ./in.txt", errMsg: "invalid ngram size \"1-\""},
+
+		{desc: "skip: -k 2", args: "-k 2 ./in.txt", expected: []optionFunc{withSkip(2)}},
+		{desc: "skip: --skip 1", args: "--skip 1 ./in.txt", expected: []optionFunc{withSkip(1)}},
+		{desc: "invalid skip: --skip -1", args: "--skip -1 ./in.txt", errMsg: "invalid skip -1"},
 
 		{desc: "language: --lang af", args: "--lang af ./in.txt", expected: []optionFunc{withLanguageCode("af")}},
 		{desc: "language: -a en", args: "-a en ./in.txt", expected: []optionFunc{withLanguageCode("en")}},
+		{desc: "language alias: -a afr", args: "-a afr ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, alphabet.LanguageCode("af"), opt.langCode)
+		}},
+		{desc: "language tag: -a pt-BR", args: "-a pt-BR --stopwords ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, alphabet.LanguageCode("pt"), opt.langCode)
+			assert.True(t, opt.stopWords.Contains("uma"))
+		}},
+		{desc: "language tag: --lang sr-Latn-RS", args: "--lang sr-Latn-RS ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, alphabet.LanguageCode("sr-Latn"), opt.langCode)
+		}},
 
 		{desc: "invalid languages file: --languages",
 			args:   fmt.Sprintf("--languages %s ./in.txt", invalidLanguages),
@@ -97,11 +150,220 @@ func TestParseArgs(t *testing.T) {
 		{desc: "letters: --letters", args: "--letters ./in.txt", expected: []optionFunc{withLetters()}},
 		{desc: "words: -w", args: "-w ./in.txt", expected: []optionFunc{withWords()}},
 		{desc: "words: --words", args: "--words ./in.txt", expected: []optionFunc{withWords()}},
-		{desc: "mixing letters and words: -w -l", args: "-w -l ./in.txt", expected: []optionFunc{withWords()}},
-		{desc: "mixing letters and words: -l -w", args: "-l -w ./in.txt", expected: []optionFunc{withWords()}},
+		{desc: "mixing letters and words: -w -l", args: "-w -l ./in.txt", expected: []optionFunc{withLetters(), withWords()}},
+		{desc: "mixing letters and words: -l -w", args: "-l -w ./in.txt", expected: []optionFunc{withLetters(), withWords()}},
+		{desc: "default letters", args: "./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.letters)
+			assert.False(t, opt.words)
+		}},
+		{desc: "words only: -w", args: "-w ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.False(t, opt.letters)
+			assert.Equal(t, []string{ngrams.TokenizerWords}, opt.tokenizerNames())
+		}},
+
+		{desc: "tokenizer: -t words", args: "-t words ./in.txt", expected: []optionFunc{withWords()}},
+		{desc: "tokenizer: --tokenizer letters,words", args: "--tokenizer letters,words ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, []string{ngrams.TokenizerLetters, ngrams.TokenizerWords}, opt.tokenizerNames())
+			assert.Equal(t, "letter and word", opt.tokenizerDescription())
+		}},
+		{desc: "invalid tokenizer: -t golang", args: "-t golang ./in.txt", errMsg: "unknown tokenizer \"golang\""},
+
+		{desc: "graphemes: -g", args: "-g ./in.txt", expected: []optionFunc{withGraphemes()}},
+		{desc: "graphemes: --graphemes", args: "--graphemes ./in.txt", expected: []optionFunc{withGraphemes()}},
+		{desc: "word boundaries: --word-boundaries", args: "--word-boundaries ./in.txt", expected: []optionFunc{withWordBoundaries()}},
+
+		{desc: "case: --case preserve", args: "--case preserve ./in.txt", expected: []optionFunc{withCase("preserve")}},
+		{desc: "case: --case fold", args: "--case fold ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, ngrams.CaseFold, opt.caseMode)
+		}},
+		{desc: "invalid case: --case title", args: "--case title ./in.txt", errMsg: "invalid case mode \"title\""},
+
+		{desc: "stop words: --stopwords", args: "--stopwords ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.stopWords.Contains("the"))
+			assert.Equal(t, ngrams.StopWordsRemove, opt.stopWordFilter)
+		}},
+		{desc: "stop words: --stopwords -a af", args: "--stopwords -a af ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.stopWords.Contains("die"))
+		}},
+		{desc: "stop words: --stopwords --stopwords-file", args: fmt.Sprintf("--stopwords --stopwords-file %s ./in.txt", wordList),
+			assertFunc: func(t *testing.T, opt *options) {
+				assert.True(t, opt.stopWords.Contains("the"))
+				assert.True(t, opt.stopWords.Contains("alice"))
+			}},
+		{desc: "missing stop words: --stopwords -a coding",
+			args:   fmt.Sprintf("--stopwords --languages %s -a coding ./in.txt", validLanguages),
+			errMsg: "no built-in stop words for the language \"coding\""},
+		{desc: "stop words filter: --stopwords-filter any", args: "--stopwords-filter any ./in.txt", expected: []optionFunc{withStopWordFilter("any")}},
+		{desc: "invalid stop words filter: --stopwords-filter some", args: "--stopwords-filter some ./in.txt", errMsg: "invalid stop word filter \"some\""},
+		{desc: "top: --top 1000", args: "--top 1000 ./in.txt", expected: []optionFunc{withTop(1000)}},
+		{desc: "invalid top: --top -1", args: "--top -1 ./in.txt", errMsg: "invalid top -1"},
+		{desc: "memory: --memory 512MB", args: "--memory 512MB ./in.txt", expected: []optionFunc{withMemoryBudget("512MB")}},
+		{desc: "memory: --memory 2GiB --temp-dir /tmp/spill", args: "--memory 2GiB --temp-dir /tmp/spill ./in.txt",
+			assertFunc: func(t *testing.T, opt *options) {
+				assert.Equal(t, uint64(2*1024*1024*1024), opt.memoryBudget)
+				assert.Equal(t, "/tmp/spill", opt.spillDir)
+			}},
+		{desc: "invalid memory: --memory lots", args: "--memory lots ./in.txt", errMsg: "invalid memory budget \"lots\""},
+		{desc: "invalid --top and --memory", args: "--top 10 --memory 1GB ./in.txt", errMsg: "--top and --memory can not be used together"},
+		{desc: "prune: --min-count 2 --keep-top 100", args: "--min-count 2 --keep-top 100 ./in.txt",
+			expected: []optionFunc{withMinCount(2), withKeepTop(100)}},
+		{desc: "prune: --coverage 95 --min-percentage 0.01", args: "--coverage 95 --min-percentage 0.01 ./in.txt",
+			expected: []optionFunc{withCoverage(95), withMinPercentage(0.01)}},
+		{desc: "invalid min count: --min-count -1", args: "--min-count -1 ./in.txt", errMsg: "invalid minimum count -1"},
+		{desc: "invalid keep top: --keep-top -1", args: "--keep-top -1 ./in.txt", errMsg: "invalid keep top -1"},
+		{desc: "invalid coverage: --coverage 101", args: "--coverage 101 ./in.txt", errMsg: "invalid coverage 101"},
+		{desc: "invalid min percentage: --min-percentage -1", args: "--min-percentage -1 ./in.txt", errMsg: "invalid minimum percentage -1"},
+		{desc: "stem: --stem", args: "--stem ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			require.NotNil(t, opt.stemmer)
+			assert.Equal(t, "walk", opt.stemmer.Stem("walking"))
+			assert.Equal(t, "stems", opt.stemOutput)
+		}},
+		{desc: "stem: --stem -a af", args: "--stem -a af ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			require.NotNil(t, opt.stemmer)
+			assert.Equal(t, "kat", opt.stemmer.Stem("katte"))
+		}},
+		{desc: "stem output: --stem-output surface", args: "--stem --stem-output surface ./in.txt",
+			assertFunc: func(t *testing.T, opt *options) {
+				assert.True(t, opt.stem)
+				assert.Equal(t, "surface", opt.stemOutput)
+			}},
+		{desc: "invalid stem output: --stem-output roots", args: "--stem-output roots ./in.txt", errMsg: "invalid stem output \"roots\""},
+		{desc: "missing stemmer: --stem -a coding",
+			args:   fmt.Sprintf("--stem --languages %s -a coding ./in.txt", validLanguages),
+			errMsg: "no built-in stemmer for the language \"coding\""},
+		{desc: "allow list: --allow", args: fmt.Sprintf("--allow %s ./in.txt", wordList), assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, ngrams.NewWordList("alice", "rabbit"), opt.allowList)
+		}},
+		{desc: "deny list: --deny", args: fmt.Sprintf("--deny %s ./in.txt", wordList), assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, ngrams.NewWordList("alice", "rabbit"), opt.denyList)
+		}},
+		{desc: "missing deny list: --deny", args: "--deny ./missing.txt ./in.txt", errMsg: "failed to open \"./missing.txt\""},
+
+		{desc: "rewrite: --rewrite number,email", args: "--rewrite number,email ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			require.NotNil(t, opt.filter)
+			assert.Equal(t, 2, opt.filter.Len())
+			token, keep := opt.filter.Apply("a@b.com 42")
+			assert.True(t, keep)
+			assert.Equal(t, "<EMAIL> <NUM>", token)
+		}},
+		{desc: "invalid rewrite: --rewrite dates", args: "--rewrite dates ./in.txt", errMsg: "invalid rewrite \"dates\""},
+		{desc: "filter: --filter", args: fmt.Sprintf("--filter %s --min-length 2 ./in.txt", rules), assertFunc: func(t *testing.T, opt *options) {
+			require.NotNil(t, opt.filter)
+			assert.Equal(t, 3, opt.filter.Len())
+			_, keep := opt.filter.Apply("* *")
+			assert.False(t, keep)
+			_, keep = opt.filter.Apply("a")
+			assert.False(t, keep)
+		}},
+		{desc: "missing filter: --filter", args: "--filter ./missing.txt ./in.txt", errMsg: "failed to open \"./missing.txt\""},
+		{desc: "min length: --min-length 3", args: "--min-length 3 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			_, keep := opt.filter.Apply("ab")
+			assert.False(t, keep)
+		}},
+		{desc: "invalid min length: --min-length -1", args: "--min-length -1 ./in.txt", errMsg: "invalid minimum length -1"},
+		{desc: "max length: --max-length 3", args: "--max-length 3 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			_, keep := opt.filter.Apply("abcd")
+			assert.False(t, keep)
+		}},
+		{desc: "invalid max length: --max-length -2", args: "--max-length -2 ./in.txt", errMsg: "invalid maximum length -2"},
+
+		{desc: "transform: -x", args: "-x ./in.csv", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.transform)
+			assert.Equal(t, "./transformed.csv", opt.outPath)
+		}},
+		{desc: "transform: --transform", args: "--transform ./in.txt", expected: []optionFunc{withTransform()}},
+		{desc: "invalid transform: -x -d", args: "-x -d ./in.txt", errMsg: "--discover and --transform can not be used together"},
 
 		{desc: "discover: -d", args: "-d ./in.txt", expected: []optionFunc{withDiscoverLanguage()}},
 		{desc: "discover: --discover", args: "--discover ./in.txt", expected: []optionFunc{withDiscoverLanguage()}},
+		{desc: "discover: --code --name", args: "-d --code af --name Afrikaans --alphabet-threshold 0.01 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, alphabet.LanguageCode("af"), opt.discoverCode)
+			assert.Equal(t, "Afrikaans", opt.discoverName)
+			assert.Equal(t, 0.01, opt.alphabetThreshold)
+		}},
+		{desc: "discover: default code", args: "-d ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, alphabet.LanguageCode("unknown"), opt.discoverCode)
+			assert.Empty(t, opt.discoverName)
+			assert.False(t, opt.union)
+			assert.Equal(t, alphabet.DefaultDiscoverThreshold, opt.alphabetThreshold)
+		}},
+		{desc: "invalid discover: --code", args: "-d --code a,b ./in.txt", errMsg: "invalid language code \"a,b\""},
+		{desc: "invalid discover: --alphabet-threshold", args: "-d --alphabet-threshold 5 ./in.txt", errMsg: "invalid alphabet threshold 5 (expected a value between 0 and 1)"},
+		{desc: "discover: --union", args: "-d --code af --union ./in.txt", expected: []optionFunc{withDiscoverLanguage(), withDiscoverCode("af"), withUnion()}},
+		{desc: "invalid discover: --code without -d", args: "--code af ./in.txt", errMsg: "--code, --name and --union can only be used together with --discover"},
+		{desc: "invalid discover: --union without -d", args: "--union ./in.txt", errMsg: "--code, --name and --union can only be used together with --discover"},
+
+		{desc: "tokens: --tokens", args: "--tokens ./in.txt", expected: []optionFunc{withTokens()}},
+		{desc: "tokens: --tokens --token-source", args: "--tokens --token-source -w ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.tokens)
+			assert.True(t, opt.tokenSource)
+			assert.Empty(t, opt.outPath)
+		}},
+		{desc: "invalid tokens: --tokens -d", args: "--tokens -d ./in.txt", errMsg: "--tokens can not be used together with --discover, --transform or --update"},
+		{desc: "invalid tokens: --tokens --stem-output surface", args: "--tokens --stem-output surface ./in.txt", errMsg: "--tokens can only be used with --stem-output stems"},
+		{desc: "invalid token source: --token-source", args: "--token-source ./in.txt", errMsg: "--token-source can only be used together with --tokens"},
+
+		{desc: "generate: --generate 10", args: "--generate 10 ./in.csv", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.generate)
+			assert.Equal(t, 10, opt.length)
+			assert.NotZero(t, opt.seed)
+			assert.Equal(t, 3, opt.minWordLength)
+			assert.Equal(t, 8, opt.maxWordLength)
+			assert.Empty(t, opt.outPath)
+		}},
+		{desc: "generate: --seed --word-length --smoothing", args: "--generate 5 --seed 42 --word-length 4-6 --smoothing kneser-ney ./in.csv",
+			assertFunc: func(t *testing.T, opt *options) {
+				assert.Equal(t, int64(42), opt.seed)
+				assert.Equal(t, 4, opt.minWordLength)
+				assert.Equal(t, 6, opt.maxWordLength)
+				assert.Equal(t, ngrams.SmoothingKneserNey, opt.smoothing)
+			}},
+		{desc: "generate: --seed 0", args: "--generate 5 --seed 0 ./in.csv", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.seedSet)
+			assert.Zero(t, opt.seed)
+		}},
+		{desc: "generate: --word-length 5", args: "--generate 5 --word-length 5 ./in.csv", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, 5, opt.minWordLength)
+			assert.Equal(t, 5, opt.maxWordLength)
+		}},
+		{desc: "invalid generate: --generate -1", args: "--generate -1 ./in.csv", errMsg: "invalid length -1"},
+		{desc: "invalid generate: --word-length 6-4", args: "--generate 1 --word-length 6-4 ./in.csv", errMsg: "invalid word length \"6-4\""},
+		{desc: "invalid generate: --smoothing", args: "--generate 1 --smoothing good-turing ./in.csv", errMsg: "invalid smoothing \"good-turing\""},
+		{desc: "invalid generate: --generate -x", args: "--generate 1 -x ./in.csv", errMsg: "--generate can not be used together with"},
+
+		{desc: "detect: --detect 3", args: "--detect 3 --profiles ./profiles ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.detect)
+			assert.Equal(t, 3, opt.detectTop)
+			assert.Equal(t, "./profiles", opt.profilesDir)
+			assert.Equal(t, langid.MethodCavnarTrenkle, opt.method)
+			assert.Empty(t, opt.outPath)
+		}},
+		{desc: "detect: --method naive-bayes", args: "--detect 1 --profiles ./profiles --method naive-bayes ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, langid.MethodNaiveBayes, opt.method)
+		}},
+		{desc: "invalid detect: --detect 0", args: "--detect -2 --profiles ./profiles ./in.txt", errMsg: "invalid number of languages -2"},
+		{desc: "invalid detect: --method", args: "--detect 1 --profiles ./profiles --method markov ./in.txt", errMsg: "invalid language identification method \"markov\""},
+		{desc: "detect: built-in profiles", args: "--detect 1 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.detect)
+			assert.Empty(t, opt.profilesDir)
+		}},
+		{desc: "invalid profiles: --profiles", args: "--profiles ./profiles ./in.txt", errMsg: "--profiles can only be used together with --detect or --filter-lines"},
+
+		{desc: "filter lines: --filter-lines", args: "--filter-lines -a af ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.True(t, opt.filterLines)
+			assert.Equal(t, langid.DefaultLineThreshold, opt.lineThreshold)
+		}},
+		{desc: "filter lines: --line-threshold --profiles", args: "--filter-lines --line-threshold 0.8 --profiles ./profiles ./in.txt",
+			assertFunc: func(t *testing.T, opt *options) {
+				assert.True(t, opt.filterLines)
+				assert.Equal(t, 0.8, opt.lineThreshold)
+				assert.Equal(t, "./profiles", opt.profilesDir)
+			}},
+		{desc: "invalid filter lines: --line-threshold 2", args: "--filter-lines --line-threshold 2 ./in.txt", errMsg: "invalid line threshold 2 (expected a value between 0 and 1)"},
+		{desc: "alphabet report: --alphabet-report", args: "--alphabet-report ./alphabet.txt ./in.txt", expected: []optionFunc{withAlphabetReport("./alphabet.txt")}},
+		{desc: "invalid alphabet report: --alphabet-report --transform", args: "--alphabet-report ./alphabet.txt --transform ./in.csv", errMsg: "--alphabet-report can not be used together with"},
+		{desc: "invalid filter lines: --filter-lines -d", args: "--filter-lines -d ./in.txt", errMsg: "--filter-lines can not be used together with"},
+		{desc: "invalid detect: --detect -d", args: "--detect 1 --profiles ./profiles -d ./in.txt", errMsg: "--detect can not be used together with"},
 
 		{desc: "update: -u", args: "-u ./in.txt", expected: []optionFunc{withUpdate()}},
 		{desc: "update: --update", args: "--update ./in.txt", expected: []optionFunc{withUpdate()}},
@@ -118,6 +380,27 @@ func TestParseArgs(t *testing.T) {
 		{desc: "default output: -s 3 -w -a af", args: "-s 3 -w -a af ./in.txt", assertFunc: func(t *testing.T, opt *options) {
 			assert.Equal(t, "./af-words-3.csv", opt.outPath)
 		}},
+		{desc: "default output: -s 2 -k 1 -w", args: "-s 2 -k 1 -w ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, "./en-words-2-skip1.csv", opt.outPath)
+		}},
+		{desc: "default output: -s 1-3", args: "-s 1-3 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Empty(t, opt.outPath)
+			assert.Equal(t, "./en-letters-1.csv", opt.outputPath(ngrams.TokenizerLetters, 1))
+			assert.Equal(t, "./en-letters-3.csv", opt.outputPath(ngrams.TokenizerLetters, 3))
+		}},
+		{desc: "output: -s 1-2 -o ./test.csv", args: "-s 1-2 -o ./test.csv ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, "./test-1.csv", opt.outputPath(ngrams.TokenizerLetters, 1))
+			assert.Equal(t, "./test-2.csv", opt.outputPath(ngrams.TokenizerLetters, 2))
+		}},
+		{desc: "default output: -l -w -s 2", args: "-l -w -s 2 ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Empty(t, opt.outPath)
+			assert.Equal(t, "./en-letters-2.csv", opt.outputPath(ngrams.TokenizerLetters, 2))
+			assert.Equal(t, "./en-words-2.csv", opt.outputPath(ngrams.TokenizerWords, 2))
+		}},
+		{desc: "output: -l -w -s 1-2 -o ./test.csv", args: "-l -w -s 1-2 -o ./test.csv ./in.txt", assertFunc: func(t *testing.T, opt *options) {
+			assert.Equal(t, "./test-letters-1.csv", opt.outputPath(ngrams.TokenizerLetters, 1))
+			assert.Equal(t, "./test-words-2.csv", opt.outputPath(ngrams.TokenizerWords, 2))
+		}},
 		{desc: "default output: -d", args: "-d ./in.txt", assertFunc: func(t *testing.T, opt *options) {
 			assert.Equal(t, "./languages.csv", opt.outPath)
 		}},
@@ -178,6 +461,24 @@ func invalidLanguagesFile(t *testing.T) string {
 	return f.Name()
 }
 
+func rulesFile(t *testing.T) string {
+	f, err := os.CreateTemp("", "rules.txt")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, _ = io.WriteString(f, "# comment\nnumber\ndrop ^\\* \\*$\n")
+	return f.Name()
+}
+
+func wordListFile(t *testing.T) string {
+	f, err := os.CreateTemp("", "words.txt")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, _ = io.WriteString(f, "# comment\nAlice\nrabbit\n")
+	return f.Name()
+}
+
 func validLanguagesFile(t *testing.T) string {
 	f, err := os.CreateTemp("", "valid-lang.csv")
 	require.NoError(t, err)
@@ -52,6 +52,24 @@ func TestApp(t *testing.T) {
 	outPath := tempOutputPath()
 	defer os.Remove(outPath)
 
+	profilesDir := profilesDirectory(t)
+
+	alphabetReportPath := filepath.Join(t.TempDir(), "alphabet.txt")
+	spillDir := t.TempDir()
+
+	mixedPath := filepath.Join(t.TempDir(), "mixed.txt")
+	require.NoError(t, os.WriteFile(mixedPath, []byte("Daar is koffie in die kan.\nThe quick brown fox jumps over the lazy dog.\nOns gaan môre see toe.\n"), 0644))
+
+	rulesPath := filepath.Join(t.TempDir(), "rules.txt")
+	require.NoError(t, os.WriteFile(rulesPath, []byte("drop ^the \n"), 0644))
+
+	languagesJSONPath := filepath.Join(t.TempDir(), "languages.json")
+	require.NoError(t, os.WriteFile(languagesJSONPath, []byte(`{"version": 1, "languages": [
+		{"code": "fr", "name": "French", "script": "Latn", "letters": "xyz", "apostrophes": "'"}]}`), 0644))
+
+	languagesPath := filepath.Join(t.TempDir(), "languages.csv")
+	require.NoError(t, os.WriteFile(languagesPath, []byte("#code,name,letters,multiletters\nfr,Français,xyz\naf,Afrikaans,ôê,ij\n"), 0644))
+
 	testCases := []struct {
 		desc     string
 		args     string
@@ -119,6 +137,12 @@ func TestApp(t *testing.T) {
 			assert.NoError(t, err)
 		}},
 
+		{desc: "available tokenizers", args: "--available-tokenizers", testFunc: func(t *testing.T) {
+			stdOut, _, err := runMain()
+			assert.Contains(t, stdOut, "letters\nwords\n")
+			assert.NoError(t, err)
+		}},
+
 		{desc: "available languages", args: fmt.Sprintf("--available --languages %s", validLanguages), testFunc: func(t *testing.T) {
 			stdOut, _, err := runMain()
 			assert.Contains(t, stdOut, "coding : Coding")
@@ -220,9 +244,423 @@ func TestApp(t *testing.T) {
 			compareTwoFrequencyTableFiles(t, outPath, outputFRAliceW3)
 		}},
 
+		// Multiple sizes
+
+		{desc: "letters 1-3 en-alice-partial", args: fmt.Sprintf("-s 1-3 -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			ext := filepath.Ext(outPath)
+			base := strings.TrimSuffix(outPath, ext)
+			for size := 1; size <= 3; size++ {
+				defer os.Remove(fmt.Sprintf("%s-%d%s", base, size, ext))
+			}
+
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+			compareTwoFrequencyTableFiles(t, fmt.Sprintf("%s-1%s", base, ext), outputENAlice1)
+			compareTwoFrequencyTableFiles(t, fmt.Sprintf("%s-2%s", base, ext), outputENAlice2)
+			compareTwoFrequencyTableFiles(t, fmt.Sprintf("%s-3%s", base, ext), outputENAlice3)
+		}},
+
+		{desc: "letters and words en-alice-partial", args: fmt.Sprintf("-l -w -s 2 -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			ext := filepath.Ext(outPath)
+			base := strings.TrimSuffix(outPath, ext)
+			lettersPath := fmt.Sprintf("%s-letters%s", base, ext)
+			wordsPath := fmt.Sprintf("%s-words%s", base, ext)
+			defer os.Remove(lettersPath)
+			defer os.Remove(wordsPath)
+
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+			compareTwoFrequencyTableFiles(t, lettersPath, outputENAlice2)
+			compareTwoFrequencyTableFiles(t, wordsPath, outputENAliceW2)
+		}},
+
+		// Stop words
+
+		{desc: "word bigrams without stop words", args: fmt.Sprintf("-w -s 2 --stopwords --stopwords-filter any -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			assert.Greater(t, ft.Len(), 0)
+			_, exists := ft.Get("of the")
+			assert.False(t, exists)
+		}},
+
+		// Word boundaries
+
+		{desc: "word monograms split on punctuation", args: fmt.Sprintf("-w --word-boundaries -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			_, exists := ft.Get("don't")
+			assert.True(t, exists)
+			_, exists = ft.Get("don’t")
+			assert.False(t, exists)
+			for _, token := range ft.Tokens() {
+				assert.NotContains(t, token, ",")
+			}
+		}},
+
+		// Top-K
+
+		{desc: "word monograms top 10", args: fmt.Sprintf("-w --top 10 -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			assert.Equal(t, 10, ft.Len())
+			_, exists := ft.Get("the")
+			assert.True(t, exists)
+			top, _ := ft.Metadata(ngrams.MetadataTopK)
+			assert.Equal(t, "10", top)
+			_, exists = ft.Metadata(ngrams.MetadataTopKMaxError)
+			assert.True(t, exists)
+		}},
+
+		// Memory budget
+
+		{desc: "word bigrams with memory budget", args: fmt.Sprintf("-w -s 2 --memory 4KB --temp-dir %s -o %s %s", t.TempDir(), outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+			compareTwoFrequencyTableFiles(t, outPath, outputENAliceW2)
+		}},
+
+		// Pruning
+
+		{desc: "word bigrams pruned", args: fmt.Sprintf("-w -s 2 --min-count 2 -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			expected, err := ngrams.LoadFrequenciesFromFile(outputENAliceW2)
+			require.NoError(t, err)
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			assert.Less(t, ft.Len(), expected.Len())
+			for _, freq := range ft.Entries() {
+				assert.GreaterOrEqual(t, freq.Count, int64(2))
+				exp, _ := expected.Get(freq.Token)
+				assert.Equal(t, exp.Percentage, freq.Percentage, freq.Token)
+			}
+			_, exists := ft.Metadata(ngrams.MetadataTotal)
+			assert.True(t, exists)
+		}},
+		{desc: "transform and prune existing tables", args: fmt.Sprintf("-x --keep-top 5 -o %s %s", outPath, outputENAliceW2), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			assert.Equal(t, 5, ft.Len())
+			pruned, _ := ft.Metadata(ngrams.MetadataPruned)
+			assert.NotEqual(t, "0", pruned)
+		}},
+
+		// Stemming
+
+		{desc: "word monograms stems", args: fmt.Sprintf("-w --stem -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			_, exists := ft.Get("consid")
+			assert.True(t, exists)
+			_, exists = ft.Get("considering")
+			assert.False(t, exists)
+			stemmer, _ := ft.Metadata(ngrams.MetadataStemmer)
+			assert.Equal(t, "en", stemmer)
+		}},
+		{desc: "word monograms surface forms", args: fmt.Sprintf("-w --stem --stem-output surface -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			_, exists := ft.Get("consid")
+			assert.False(t, exists)
+			_, exists = ft.Get("considering")
+			assert.True(t, exists)
+		}},
+		{desc: "word bigrams surface forms with memory budget", args: fmt.Sprintf("-w -s 2 --stem --stem-output surface --memory 4KB --temp-dir %s -o %s %s", spillDir, outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			// The output matches the table kept in memory
+			expectedPath := filepath.Join(t.TempDir(), "expected.csv")
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+			os.Args = strings.Split(fmt.Sprintf("ngrams -w -s 2 --stem --stem-output surface -o %s %s", expectedPath, inputENAlice), " ")
+			_, _, err = runMain()
+			require.NoError(t, err)
+			compareTwoFrequencyTableFiles(t, outPath, expectedPath)
+
+			entries, err := os.ReadDir(spillDir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		}},
+
+		// Filters
+
+		{desc: "word monograms with rewritten numbers", args: fmt.Sprintf("-w --rewrite number --min-length 2 -o %s %s", outPath, inputENAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			for _, token := range ft.Tokens() {
+				assert.GreaterOrEqual(t, len([]rune(token)), 2, token)
+			}
+		}},
+
+		{desc: "transform existing tables", args: fmt.Sprintf("-x --filter %s -o %s %s %s", rulesPath, outPath, outputENAliceW2, outputENAliceW2), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			original, err := ngrams.LoadFrequenciesFromFile(outputENAliceW2)
+			require.NoError(t, err)
+			before, exists := original.Get("of the")
+			require.True(t, exists)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			after, exists := ft.Get("of the")
+			require.True(t, exists)
+			assert.Equal(t, before.Count*2, after.Count)
+
+			for _, token := range ft.Tokens() {
+				assert.False(t, strings.HasPrefix(token, "the "), token)
+			}
+		}},
+
+		// Skip-grams
+
+		{desc: "skip-gram bigrams en-control", args: fmt.Sprintf("-s 2 -k 1 -o %s %s", outPath, inputENControl), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+
+			skip, exists := ft.Metadata(ngrams.MetadataSkip)
+			assert.True(t, exists)
+			assert.Equal(t, "1", skip)
+
+			_, exists = ft.Get("t_e")
+			assert.True(t, exists)
+			_, exists = ft.Get("th")
+			assert.True(t, exists)
+		}},
+
+		// Tokens
+
+		{desc: "tokens to stdout", args: fmt.Sprintf("--tokens -w -s 2 -v %s", inputENAlice), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Contains(t, stdErr, "Streaming 2 word ngrams")
+			assert.Contains(t, stdErr, "Alphabet coverage (en - English):")
+
+			expected, err := ngrams.LoadFrequenciesFromFile(outputENAliceW2)
+			require.NoError(t, err)
+
+			ft := ngrams.NewFrequencyTable()
+			for _, line := range strings.Split(strings.TrimSuffix(stdOut, "\n"), "\n") {
+				ft.Add(line, 1)
+			}
+			ft.Update()
+			compareTwoFrequencyTables(t, expected, ft)
+		}},
+
+		{desc: "tokens with source", args: fmt.Sprintf("--tokens --token-source -o %s %s", outPath, inputENControl), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdOut)
+			assert.Empty(t, stdErr)
+
+			data, err := os.ReadFile(outPath)
+			require.NoError(t, err)
+			lines := strings.Split(string(data), "\n")
+			assert.Equal(t, fmt.Sprintf("t\t%s\t0", inputENControl), lines[0])
+			assert.Equal(t, fmt.Sprintf("h\t%s\t1", inputENControl), lines[1])
+		}},
+
+		{desc: "tokens with tokenizer and size", args: fmt.Sprintf("--tokens -l -w -s 1-2 --token-source %s", inputENControl), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			lines := strings.Split(strings.TrimSuffix(stdOut, "\n"), "\n")
+			assert.Equal(t, fmt.Sprintf("t\tletters\t1\t%s\t0", inputENControl), lines[0])
+
+			seen := make(map[string]bool)
+			for _, line := range lines {
+				fields := strings.Split(line, "\t")
+				require.Len(t, fields, 5)
+				seen[fields[1]+" "+fields[2]] = true
+			}
+			assert.Equal(t, map[string]bool{"letters 1": true, "letters 2": true, "words 1": true, "words 2": true}, seen)
+		}},
+
+		// Generate
+
+		{desc: "generate letters", args: fmt.Sprintf("--generate 20 --seed 7 --word-length 4 %s", outputENAlice3), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			words := strings.Fields(stdOut)
+			assert.Len(t, words, 20)
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+			again, _, err := runMain()
+			require.NoError(t, err)
+			assert.Equal(t, stdOut, again)
+		}},
+
+		{desc: "generate words", args: fmt.Sprintf("--generate 30 -w --smoothing laplace -o %s %s", outPath, outputENAliceW2), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			data, err := os.ReadFile(outPath)
+			require.NoError(t, err)
+			assert.Len(t, strings.Fields(string(data)), 30)
+		}},
+
+		{desc: "generate from monograms", args: fmt.Sprintf("--generate 5 %s", outputENAlice1), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			assert.Error(t, err)
+			assert.Contains(t, stdErr, "failed to split the ngram")
+		}},
+
+		{desc: "generate from words without -w", args: fmt.Sprintf("--generate 5 %s", outputENAliceW2), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			assert.Error(t, err)
+			assert.Contains(t, stdErr, "contains more than one word")
+		}},
+
+		{desc: "generate from skip-grams", args: fmt.Sprintf("-s 2 -k 1 -o %s %s", outPath, inputENControl), testFunc: func(t *testing.T) {
+			_, _, err := runMain()
+			require.NoError(t, err)
+
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+			os.Args = strings.Split(fmt.Sprintf("ngrams --generate 5 %s", outPath), " ")
+			_, stdErr, err := runMain()
+			assert.Error(t, err)
+			assert.Contains(t, stdErr, "skip-grams (skip 1) can not be used")
+		}},
+
+		// Detect
+
+		{desc: "detect", args: fmt.Sprintf("--detect 2 --profiles %s %s %s", profilesDir, inputENControl, inputAFControl), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+			require.Len(t, lines, 6)
+			assert.Equal(t, inputENControl, lines[0])
+			assert.True(t, strings.HasPrefix(lines[1], "  en\tEnglish\t"))
+			assert.Equal(t, inputAFControl, lines[3])
+			assert.True(t, strings.HasPrefix(lines[4], "  af\tAfrikaans\t"))
+		}},
+
+		{desc: "detect naive-bayes", args: fmt.Sprintf("--detect 1 --method naive-bayes --profiles %s -o %s %s", profilesDir, outPath, inputFRAlice), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			data, err := os.ReadFile(outPath)
+			require.NoError(t, err)
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			require.Len(t, lines, 2)
+			assert.True(t, strings.HasPrefix(lines[1], "  fr\tFrench\t"))
+		}},
+
+		{desc: "detect built-in", args: fmt.Sprintf("--detect 1 %s %s", inputENControl, inputFRAlice), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+
+			lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+			require.Len(t, lines, 4)
+			assert.True(t, strings.HasPrefix(lines[1], "  en\tEnglish\t"))
+			assert.True(t, strings.HasPrefix(lines[3], "  fr\tFrench\t"))
+		}},
+
+		{desc: "detect without profiles", args: fmt.Sprintf("--detect 1 --profiles %s %s", t.TempDir(), inputENControl), testFunc: func(t *testing.T) {
+			_, stdErr, err := runMain()
+			assert.Error(t, err)
+			assert.Contains(t, stdErr, "no profiles")
+		}},
+
+		// Filter lines
+
+		{desc: "filter lines", args: fmt.Sprintf("--filter-lines -a af -w -o %s %s", outPath, mixedPath), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdErr)
+			assert.Equal(t, fmt.Sprintf("Rejected lines not written in Afrikaans (af):\n  %s\t1 of 3\n", mixedPath), stdOut)
+
+			ft, err := ngrams.LoadFrequenciesFromFile(outPath)
+			require.NoError(t, err)
+			_, exists := ft.Get("koffie")
+			assert.True(t, exists)
+			_, exists = ft.Get("fox")
+			assert.False(t, exists)
+		}},
+
+		{desc: "filter lines tokens", args: fmt.Sprintf("--filter-lines --tokens -a af -w %s", mixedPath), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Contains(t, stdErr, "1 of 3")
+			assert.NotContains(t, stdOut, "fox")
+			assert.Contains(t, stdOut, "koffie")
+		}},
+
+		// Coverage
+
+		{desc: "alphabet report", args: fmt.Sprintf("-a af -o %s --alphabet-report %s %s", outPath, alphabetReportPath, mixedPath), testFunc: func(t *testing.T) {
+			stdOut, stdErr, err := runMain()
+			require.NoError(t, err)
+			assert.Empty(t, stdOut)
+			assert.Empty(t, stdErr)
+
+			data, err := os.ReadFile(alphabetReportPath)
+			require.NoError(t, err)
+			lines := strings.Split(string(data), "\n")
+			assert.Equal(t, "Alphabet coverage (af - Afrikaans):", lines[0])
+			assert.Equal(t, mixedPath+": 96.00% accepted (72 accepted, 3 rejected)", lines[1])
+			assert.Equal(t, "  '.' U+002E 3", lines[2])
+			assert.Equal(t, "Total: 96.00% accepted (72 accepted, 3 rejected)", lines[3])
+		}},
+
+		{desc: "alphabet report verbose", args: fmt.Sprintf("-a af -v -o %s %s", outPath, mixedPath), testFunc: func(t *testing.T) {
+			stdOut, _, err := runMain()
+			require.NoError(t, err)
+			assert.Contains(t, stdOut, "Alphabet coverage (af - Afrikaans):\n"+mixedPath+": 96.00% accepted")
+		}},
+
 		// Discover
 
 		{desc: "discover fr", args: fmt.Sprintf("-d -o %s %s", outPath, inputFRAlice), testFunc: func(t *testing.T) {
+			os.Remove(outPath)
 			_, stdErr, err := runMain()
 			require.NoError(t, err)
 			assert.Empty(t, stdErr)
@@ -233,9 +671,91 @@ func TestApp(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, "unknown", lang.Name)
 
-			assert.ElementsMatch(t, []rune(`!"'()*,-.:;?[]_abcdefghijlmnopqrstuvxyzàâçèéêîôùûœ`), []rune(lang.Letters))
+			assert.Equal(t, "abcdefghijlmnopqrstuvxyzàâçèéêîôùûœ", lang.Letters)
+		}},
+
+		{desc: "discover code name", args: fmt.Sprintf("-d --code fr --name French --alphabet-threshold 0.001 -o %s %s", outPath, inputFRAlice),
+			testFunc: func(t *testing.T) {
+				stdOut, stdErr, err := runMain()
+				require.NoError(t, err)
+				assert.Empty(t, stdErr)
+				assert.Contains(t, stdOut, "Suggested alphabet (threshold 0.001): ")
+				assert.Contains(t, stdOut, "\nLatin Ll: ")
+				assert.Contains(t, stdOut, "\nCommon Po: ")
+
+				langs, err := alphabet.LoadLanguagesFromFile(outPath)
+				require.NoError(t, err)
+				lang, err := langs.Get("fr")
+				require.NoError(t, err)
+				assert.Equal(t, "French", lang.Name)
+				// The rarely used letters (e.g. î, ô, ù, û and œ) are left out
+				assert.Equal(t, "abcdefghijlmnopqrstuvxyzàçèéê", lang.Letters)
+			}},
+
+		{desc: "discover into existing languages", args: fmt.Sprintf("-d --code fr --alphabet-threshold 0.001 -o %s %s", languagesPath, inputFRAlice),
+			testFunc: func(t *testing.T) {
+				_, stdErr, err := runMain()
+				require.NoError(t, err)
+				assert.Empty(t, stdErr)
+
+				langs, err := alphabet.LoadLanguagesFromFile(languagesPath)
+				require.NoError(t, err)
+				assert.Len(t, langs, 2)
+
+				lang, err := langs.Get("fr")
+				require.NoError(t, err)
+				assert.Equal(t, "Français", lang.Name)
+				assert.Equal(t, "abcdefghijlmnopqrstuvxyzàçèéê", lang.Letters)
+
+				lang, err = langs.Get("af")
+				require.NoError(t, err)
+				assert.Equal(t, "ôê", lang.Letters)
+				assert.Equal(t, []string{"ij"}, lang.MultiLetters)
+
+				data, err := os.ReadFile(languagesPath)
+				require.NoError(t, err)
+				assert.Equal(t, "#code,name,letters,multiletters\naf,Afrikaans,ôê,ij\nfr,Français,abcdefghijlmnopqrstuvxyzàçèéê\n", string(data))
+			}},
+
+		{desc: "discover into json languages", args: fmt.Sprintf("-d --code fr --alphabet-threshold 0.001 -o %s %s", languagesJSONPath, inputFRAlice),
+			testFunc: func(t *testing.T) {
+				_, stdErr, err := runMain()
+				require.NoError(t, err)
+				assert.Empty(t, stdErr)
+
+				data, err := os.ReadFile(languagesJSONPath)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), `"version": 1`)
+
+				langs, err := alphabet.LoadLanguagesFromFile(languagesJSONPath)
+				require.NoError(t, err)
+				assert.Equal(t, alphabet.Language{Name: "French", Code: "fr", Letters: "abcdefghijlmnopqrstuvxyzàçèéê",
+					Script: "Latn", Apostrophes: "'"}, langs["fr"])
+			}},
+
+		{desc: "available json languages", args: fmt.Sprintf("--available --languages %s", languagesJSONPath), testFunc: func(t *testing.T) {
+			stdOut, _, err := runMain()
+			require.NoError(t, err)
+			assert.Contains(t, stdOut, "fr : French")
 		}},
 
+		{desc: "discover union", args: fmt.Sprintf("-d --code af --union --alphabet-threshold 0.01 -o %s %s", languagesPath, inputAFControl),
+			testFunc: func(t *testing.T) {
+				_, stdErr, err := runMain()
+				require.NoError(t, err)
+				assert.Empty(t, stdErr)
+
+				langs, err := alphabet.LoadLanguagesFromFile(languagesPath)
+				require.NoError(t, err)
+				assert.Len(t, langs, 2)
+
+				lang, err := langs.Get("af")
+				require.NoError(t, err)
+				assert.Equal(t, "Afrikaans", lang.Name)
+				assert.Equal(t, "adefgijklmnoprstuvwyêô", lang.Letters)
+				assert.Equal(t, []string{"ij"}, lang.MultiLetters)
+			}},
+
 		// Update
 
 		{desc: "update af", args: fmt.Sprintf("-u -a af -s 2 -o %s %s", outPath, inputAFControl), testFunc: func(t *testing.T) {
@@ -323,6 +843,10 @@ func compareTwoFrequencyTableFiles(t *testing.T, a string, b string) {
 	bft, err := ngrams.LoadFrequenciesFromFile(b)
 	require.NoError(t, err)
 
+	compareTwoFrequencyTables(t, aft, bft)
+}
+
+func compareTwoFrequencyTables(t *testing.T, aft *ngrams.FrequencyTable, bft *ngrams.FrequencyTable) {
 	ae := aft.EntriesSortedByCount()
 	be := bft.EntriesSortedByCount()
 	assert.Equal(t, len(ae), len(be), "not the same number of rows")
@@ -350,6 +874,7 @@ const (
 	outputAFControl1 = ngramTestData + "freq-1-af-control.csv"
 	outputAFControl2 = ngramTestData + "freq-2-af-control.csv"
 
+	outputENAlice1 = ngramTestData + "freq-1-en-alice.csv"
 	outputENAlice2 = ngramTestData + "freq-2-en-alice.csv"
 	outputENAlice3 = ngramTestData + "freq-3-en-alice.csv"
 
@@ -365,3 +890,24 @@ const (
 	outputFRAliceW2 = ngramTestData + "freq-2w-fr-alice.csv"
 	outputFRAliceW3 = ngramTestData + "freq-3w-fr-alice.csv"
 )
+
+// profilesDirectory creates a directory of language profiles from the letter frequency tables of the test data.
+func profilesDirectory(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	profiles := map[string]string{
+		outputENAlice1:   "en-letters-1.csv",
+		outputENAlice2:   "en-letters-2.csv",
+		outputAFControl1: "af-letters-1.csv",
+		outputAFControl2: "af-letters-2.csv",
+		outputFRAlice2:   "fr-letters-2.csv",
+		outputFRAlice3:   "fr-letters-3.csv",
+	}
+	for src, dest := range profiles {
+		data, err := os.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, dest), data, 0644))
+	}
+	return dir
+}
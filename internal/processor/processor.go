@@ -32,8 +32,23 @@ import (
 )
 
 // ProcessFunc is provided to the processor and will be called on each input source that needs processing.
+// The name of the input source can be retrieved from the context using [Source].
 type ProcessFunc func(ctx context.Context, r io.Reader) error
 
+type sourceKey struct{}
+
+// Source returns the name of the input source being processed, as provided by the context passed to
+// the [ProcessFunc]. This is the path of the file or the path of the file inside of a zip file
+// (e.g. archive.zip/dir/file.txt). An empty string is returned if the context does not contain a source.
+func Source(ctx context.Context) string {
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return source
+}
+
+func withSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
 // Processor is used to process multiple input sources.
 type Processor struct {
 	progressReporter ProgressReporter
@@ -98,7 +113,7 @@ func (p *Processor) processFile(ctx context.Context, path string, fn ProcessFunc
 	}()
 
 	r := p.progressReporter.Reader(bufio.NewReader(f))
-	err = fn(ctx, r)
+	err = fn(withSource(ctx, path), r)
 	if err != nil {
 		return err
 	}
@@ -166,7 +181,7 @@ func (p *Processor) processZipFile(ctx context.Context, path string, fn ProcessF
 		}
 
 		r := p.progressReporter.Reader(bufio.NewReader(zr))
-		err = fn(ctx, r)
+		err = fn(withSource(ctx, path+"/"+f.Name), r)
 		if err != nil {
 			closer(zr)
 			return err
@@ -113,6 +113,21 @@ func TestProcessorZipWithProgress(t *testing.T) {
 	assert.Equal(t, int64(19+25), reporter.addTotal)
 }
 
+func TestProcessorSource(t *testing.T) {
+	paths := []string{"testdata/1.txt", "testdata/a.zip"}
+
+	sources := make([]string, 0, 3)
+	p := processor.NewProcessor()
+	err := p.ProcessFiles(context.Background(), paths, func(ctx context.Context, r io.Reader) error {
+		sources = append(sources, processor.Source(ctx))
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"testdata/1.txt", "testdata/a.zip/a/1.txt", "testdata/a.zip/a/b/2.txt"}, sources)
+	assert.Empty(t, processor.Source(context.Background()))
+}
+
 //-----------------------------------------------------------------------------
 
 type MockProgressReporter struct {
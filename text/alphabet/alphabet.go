@@ -20,15 +20,73 @@
 package alphabet
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/maps"
 )
 
 //go:generate go run generate_languages.go
 
-// LanguageCode describes an ISO 639 set 1 language code.
+// LanguageCode describes a language code. This is the ISO 639 set 1 code (e.g. af) or the ISO 639 set 2 or 3
+// code (e.g. afr) for languages without one, optionally followed by BCP 47 subtags (e.g. sr-Latn, pt-BR).
 type LanguageCode string
 
+// NormalizeCode returns the code in the casing recommended by BCP 47 (e.g. pt_br becomes pt-BR and
+// SR-LATN becomes sr-Latn). Underscores are replaced with hyphens and surrounding whitespace is removed.
+func NormalizeCode(code LanguageCode) LanguageCode {
+	subtags := strings.Split(strings.ReplaceAll(strings.TrimSpace(string(code)), "_", "-"), "-")
+	for i, subtag := range subtags {
+		subtag = strings.ToLower(subtag)
+		switch {
+		case i == 0:
+		case len(subtag) == 4 && isAlpha(subtag):
+			// Script (e.g. Latn)
+			subtag = strings.ToUpper(subtag[:1]) + subtag[1:]
+		case len(subtag) == 2 && isAlpha(subtag):
+			// Region (e.g. BR)
+			subtag = strings.ToUpper(subtag)
+		}
+		subtags[i] = subtag
+	}
+	return LanguageCode(strings.Join(subtags, "-"))
+}
+
+func isAlpha(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// Direction describes the writing direction of a language.
+type Direction string
+
+const (
+	// LeftToRight is the writing direction of most languages (e.g. Latin and Cyrillic scripts).
+	// An empty direction also means left to right.
+	LeftToRight Direction = "ltr"
+	// RightToLeft is the writing direction of languages like Arabic and Hebrew.
+	RightToLeft Direction = "rtl"
+)
+
+// ParseDirection returns the writing direction for the given name (ltr or rtl).
+func ParseDirection(name string) (Direction, error) {
+	switch d := Direction(strings.ToLower(name)); d {
+	case "":
+		return "", nil
+	case LeftToRight, RightToLeft:
+		return d, nil
+	}
+	return "", fmt.Errorf("invalid writing direction %q (expected ltr or rtl)", name)
+}
+
 // Language describes the alphabet letters found in a language.
 type Language struct {
 	// Name of the language (e.g. Afrikaans).
@@ -37,6 +95,27 @@ type Language struct {
 	Code LanguageCode
 	// Letters (in UTF-8 and in lowercase) found in the language.
 	Letters string
+	// MultiLetters are letters (in UTF-8 and in lowercase) that are made up of more than one rune
+	// but are considered to be a single letter in the language (e.g. Dutch ij, Welsh ll, Hungarian dzs).
+	MultiLetters []string
+	// Locale (BCP 47) used for the language specific case mapping rules (e.g. tr for the Turkish dotted and dotless i).
+	// The language code is used when no locale is specified.
+	Locale string
+	// Script is the ISO 15924 code of the writing system (e.g. Latn, Arab).
+	Script string
+	// Direction is the writing direction. Empty means left to right.
+	Direction Direction
+	// UpperCase maps the letters (in lowercase) for which the uppercase differs from the default unicode
+	// rules to their uppercase (e.g. German ß to ẞ, Dutch ij to IJ).
+	UpperCase map[string]string
+	// Apostrophes are the runes that can be part of a word (e.g. English don't). The first one is used in
+	// place of the others when the words are split on punctuation (see ngrams.WithWordBoundaries).
+	Apostrophes string
+	// Hyphens are the runes that can join the parts of a compound word (e.g. well-known). The first one is
+	// used in place of the others when the words are split on punctuation.
+	Hyphens string
+	// Aliases are the other codes the language can be found by (e.g. the ISO 639 set 2 codes deu and ger).
+	Aliases []LanguageCode
 }
 
 // LanguageMap is used to map from a language code to info about the language.
@@ -49,11 +128,202 @@ func (l Language) ContainsRune(r rune) bool {
 	return strings.ContainsRune(l.Letters, r)
 }
 
+// ContainsLetter returns true if the language contains the letter.
+// A letter is either a single rune, one of the multi-rune letters of the language or a grapheme cluster
+// in which every rune is part of the language (e.g. a Devanagari conjunct).
+// Like [Language.ContainsRune] the letter is assumed to be in lowercase.
+func (l Language) ContainsLetter(letter string) bool {
+	if letter == "" {
+		return false
+	}
+
+	if l.IsMultiLetter(letter) {
+		return true
+	}
+
+	for _, r := range letter {
+		if !l.ContainsRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsMultiLetter returns true if the letter is one of the multi-rune letters of the language.
+func (l Language) IsMultiLetter(letter string) bool {
+	return slices.Contains(l.MultiLetters, letter)
+}
+
+// IsLetter returns true if the rune, regardless of its case, is part of the language.
+func (l Language) IsLetter(r rune) bool {
+	return l.ContainsRune(l.ToLower(r))
+}
+
+// SpecialCase returns the locale specific case mapping rules for the language or nil if the
+// default unicode rules apply.
+func (l Language) SpecialCase() unicode.SpecialCase {
+	locale := l.Locale
+	if locale == "" {
+		locale = string(l.Code)
+	}
+
+	// Only the primary language subtag is needed (e.g. tr-CY)
+	primary, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	switch primary {
+	case "tr", "tur":
+		return unicode.TurkishCase
+	case "az", "aze":
+		return unicode.AzeriCase
+	}
+	return nil
+}
+
+// ToLower maps the rune to lowercase using the case mapping rules of the language.
+func (l Language) ToLower(r rune) rune {
+	if sc := l.SpecialCase(); sc != nil {
+		return sc.ToLower(r)
+	}
+	return unicode.ToLower(r)
+}
+
+// IsRightToLeft returns true if the language is written from right to left.
+func (l Language) IsRightToLeft() bool {
+	return l.Direction == RightToLeft
+}
+
+// IsApostrophe returns true if the rune is one of the apostrophes that can be part of a word.
+func (l Language) IsApostrophe(r rune) bool {
+	return strings.ContainsRune(l.Apostrophes, r)
+}
+
+// IsHyphen returns true if the rune is one of the hyphens that can join the parts of a compound word.
+func (l Language) IsHyphen(r rune) bool {
+	return strings.ContainsRune(l.Hyphens, r)
+}
+
+// ToUpper maps the rune to uppercase using the case mapping rules of the language.
+// Only the uppercase mappings of the language that result in a single rune are used.
+func (l Language) ToUpper(r rune) rune {
+	if upper, exists := l.UpperCase[string(r)]; exists {
+		if runes := []rune(upper); len(runes) == 1 {
+			return runes[0]
+		}
+	}
+	if sc := l.SpecialCase(); sc != nil {
+		return sc.ToUpper(r)
+	}
+	return unicode.ToUpper(r)
+}
+
+// ToLowerString maps the string to lowercase using the case mapping rules of the language.
+func (l Language) ToLowerString(s string) string {
+	if sc := l.SpecialCase(); sc != nil {
+		return strings.ToLowerSpecial(sc, s)
+	}
+	return strings.ToLower(s)
+}
+
+// ToUpperString maps the string to uppercase using the case mapping rules of the language, including
+// the uppercase mappings of multi-rune letters (e.g. Dutch ij to IJ).
+func (l Language) ToUpperString(s string) string {
+	sc := l.SpecialCase()
+	if len(l.UpperCase) == 0 {
+		if sc != nil {
+			return strings.ToUpperSpecial(sc, s)
+		}
+		return strings.ToUpper(s)
+	}
+
+	// Longest letters first so that e.g. "dzs" is mapped before "dz"
+	letters := maps.Keys(l.UpperCase)
+	slices.SortFunc(letters, func(a, b string) int {
+		if c := cmp.Compare(len(b), len(a)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+next:
+	for s != "" {
+		for _, letter := range letters {
+			if strings.HasPrefix(s, letter) {
+				sb.WriteString(l.UpperCase[letter])
+				s = s[len(letter):]
+				continue next
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s)
+		if sc != nil {
+			r = sc.ToUpper(r)
+		} else {
+			r = unicode.ToUpper(r)
+		}
+		sb.WriteRune(r)
+		s = s[size:]
+	}
+	return sb.String()
+}
+
 // Get the language for the given code or return an error.
+// See [LanguageMap.Resolve] for how aliases and BCP 47 language tags are found.
 func (lm LanguageMap) Get(code LanguageCode) (Language, error) {
-	lang, exists := lm[code]
+	resolved, exists := lm.Resolve(code)
 	if !exists {
 		return Language{}, fmt.Errorf("no language found with code %q", code)
 	}
-	return lang, nil
+	return lm[resolved], nil
+}
+
+// Resolve returns the code of the language in the map that the given code refers to.
+// The code is matched case insensitively against the codes and then the aliases of the languages.
+// For BCP 47 language tags the subtags are removed from the end until a language is found
+// (e.g. sr-Latn-RS finds sr-Latn and pt-BR finds pt). An alias can also be used as the primary language
+// subtag (e.g. srp-Latn finds sr-Latn).
+func (lm LanguageMap) Resolve(code LanguageCode) (LanguageCode, bool) {
+	if _, exists := lm[code]; exists {
+		return code, true
+	}
+
+	tag := NormalizeCode(code)
+	if tag == "" {
+		return "", false
+	}
+
+	if primary, rest, found := strings.Cut(string(tag), "-"); found {
+		if resolved, exists := lm.lookup(LanguageCode(primary)); exists && !strings.Contains(string(resolved), "-") {
+			tag = resolved + "-" + LanguageCode(rest)
+		}
+	}
+
+	for {
+		if resolved, exists := lm.lookup(tag); exists {
+			return resolved, true
+		}
+
+		i := strings.LastIndex(string(tag), "-")
+		if i < 0 {
+			return "", false
+		}
+		tag = tag[:i]
+	}
+}
+
+// lookup returns the code of the language with the normalized code or alias.
+func (lm LanguageMap) lookup(tag LanguageCode) (LanguageCode, bool) {
+	for code := range lm {
+		if NormalizeCode(code) == tag {
+			return code, true
+		}
+	}
+	for code, lang := range lm {
+		for _, alias := range lang.Aliases {
+			if NormalizeCode(alias) == tag {
+				return code, true
+			}
+		}
+	}
+	return "", false
 }
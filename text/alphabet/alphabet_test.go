@@ -33,23 +33,29 @@ func TestGeneratedLanguages(t *testing.T) {
 	// some of the expected output
 	lang, err := alphabet.Builtin("af")
 	require.NoError(t, err)
-	assert.Equal(t, lang, alphabet.Language{Name: "Afrikaans", Code: "af", Letters: "abcdefghijklmnopqrstuvwxyzáêéèëïíîôóúû"})
+	assert.Equal(t, lang, alphabet.Language{Name: "Afrikaans", Code: "af", Letters: "abcdefghijklmnopqrstuvwxyzáêéèëïíîôóúû",
+		Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []alphabet.LanguageCode{"afr"}})
 
 	lang, err = alphabet.Builtin("en")
 	require.NoError(t, err)
-	assert.Equal(t, lang, alphabet.Language{Name: "English", Code: "en", Letters: "abcdefghijklmnopqrstuvwxyz"})
+	assert.Equal(t, lang, alphabet.Language{Name: "English", Code: "en", Letters: "abcdefghijklmnopqrstuvwxyz",
+		Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []alphabet.LanguageCode{"eng"}})
 
 	lang, err = alphabet.Builtin("es")
 	require.NoError(t, err)
-	assert.Equal(t, lang, alphabet.Language{Name: "Spanish", Code: "es", Letters: "abcdefghijklmnopqrstuvwxyzáéíñóúü"})
+	assert.Equal(t, lang, alphabet.Language{Name: "Spanish", Code: "es", Letters: "abcdefghijklmnopqrstuvwxyzáéíñóúü",
+		Script: "Latn", Hyphens: "-", Aliases: []alphabet.LanguageCode{"spa"}})
 
 	lang, err = alphabet.Builtin("da")
 	require.NoError(t, err)
-	assert.Equal(t, lang, alphabet.Language{Name: "Danish", Code: "da", Letters: "abcdefghijklmnopqrstuvwxyzæøå"})
+	assert.Equal(t, lang, alphabet.Language{Name: "Danish", Code: "da", Letters: "abcdefghijklmnopqrstuvwxyzæøå",
+		Script: "Latn", Hyphens: "-", Aliases: []alphabet.LanguageCode{"dan"}})
 
 	lang, err = alphabet.Builtin("ar")
 	require.NoError(t, err)
-	assert.Equal(t, lang, alphabet.Language{Name: "Arabic", Code: "ar", Letters: "أابتثجحخدذرزسشصضطظعغفقكلمنهؤوئىيء"})
+	assert.Equal(t, lang, alphabet.Language{Name: "Arabic", Code: "ar", Letters: "أابتثجحخدذرزسشصضطظعغفقكلمنهؤوئىيء",
+		Script: "Arab", Direction: alphabet.RightToLeft, Aliases: []alphabet.LanguageCode{"ara"}})
+	assert.True(t, lang.IsRightToLeft())
 
 	_, err = alphabet.Builtin("golang")
 	assert.ErrorContains(t, err, "no built-in language found with code \"golang\"")
@@ -66,6 +72,21 @@ func TestGeneratedLanguages(t *testing.T) {
 		{code: "da", check: rune('æ'), expected: true},
 		{code: "da", check: rune('Æ'), expected: false},
 		{code: "ar", check: rune('ض'), expected: true},
+		{code: "el", check: rune('ς'), expected: true},
+		{code: "ru", check: rune('ё'), expected: true},
+		{code: "ru", check: rune('і'), expected: false},
+		{code: "uk", check: rune('ї'), expected: true},
+		{code: "he", check: rune('ץ'), expected: true},
+		{code: "hi", check: rune('क'), expected: true},
+		{code: "tr", check: rune('ı'), expected: true},
+		{code: "pl", check: rune('ł'), expected: true},
+		{code: "cs", check: rune('ř'), expected: true},
+		{code: "pt", check: rune('ã'), expected: true},
+		{code: "it", check: rune('ì'), expected: true},
+		{code: "zu", check: rune('q'), expected: true},
+		{code: "xh", check: rune('x'), expected: true},
+		{code: "sr", check: rune('ђ'), expected: true},
+		{code: "sr-Latn", check: rune('đ'), expected: true},
 	}
 	for i, tC := range testCases {
 		t.Run(fmt.Sprintf("RuneCheck-%d", i), func(t *testing.T) {
@@ -74,4 +95,197 @@ func TestGeneratedLanguages(t *testing.T) {
 			assert.Equal(t, tC.expected, lang.ContainsRune(tC.check))
 		})
 	}
+
+	lang, err = alphabet.Builtin("nl")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ij"}, lang.MultiLetters)
+}
+
+func TestContainsLetter(t *testing.T) {
+	lang := alphabet.Language{
+		Name:         "Test",
+		Code:         "test",
+		Letters:      "abcdeijklsz\u0301",
+		MultiLetters: []string{"ij", "dzs"},
+	}
+
+	testCases := []struct {
+		letter   string
+		expected bool
+	}{
+		{letter: "a", expected: true},
+		{letter: "x", expected: false},
+		{letter: "", expected: false},
+		{letter: "ij", expected: true},
+		{letter: "dzs", expected: true},
+		{letter: "e\u0301", expected: true},
+		{letter: "x\u0301", expected: false},
+		{letter: "👍🏽", expected: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.letter, func(t *testing.T) {
+			assert.Equal(t, tC.expected, lang.ContainsLetter(tC.letter))
+		})
+	}
+
+	assert.True(t, lang.IsMultiLetter("ij"))
+	assert.False(t, lang.IsMultiLetter("i"))
+}
+
+func TestBuiltinAliases(t *testing.T) {
+	testCases := []struct {
+		code     alphabet.LanguageCode
+		expected alphabet.LanguageCode
+	}{
+		{code: "af", expected: "af"},
+		{code: "afr", expected: "af"},
+		{code: "AF", expected: "af"},
+		{code: "deu", expected: "de"},
+		{code: "ger", expected: "de"},
+		{code: "iw", expected: "he"},
+		{code: "pt-BR", expected: "pt"},
+		{code: "pt_br", expected: "pt"},
+		{code: "por-PT", expected: "pt"},
+		{code: "sr", expected: "sr"},
+		{code: "sr-Cyrl", expected: "sr"},
+		{code: "sr-Cyrl-RS", expected: "sr"},
+		{code: "sr-Latn", expected: "sr-Latn"},
+		{code: "sr-latn-rs", expected: "sr-Latn"},
+		{code: "srp-Latn", expected: "sr-Latn"},
+		{code: "tr-CY", expected: "tr"},
+		{code: "zul", expected: "zu"},
+	}
+	for _, tC := range testCases {
+		t.Run(string(tC.code), func(t *testing.T) {
+			lang, err := alphabet.Builtin(tC.code)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, lang.Code)
+			assert.Equal(t, tC.expected, alphabet.MustBuiltin(tC.code).Code)
+		})
+	}
+
+	for _, code := range []alphabet.LanguageCode{"", "-", "xx", "xx-Latn", "Latn", "golang"} {
+		_, err := alphabet.Builtin(code)
+		assert.ErrorContains(t, err, fmt.Sprintf("no built-in language found with code %q", code))
+	}
+	assert.Panics(t, func() { alphabet.MustBuiltin("xx") })
+}
+
+func TestLanguageMapResolve(t *testing.T) {
+	languages := alphabet.LanguageMap{
+		"nb":  alphabet.Language{Name: "Norwegian Bokmål", Code: "nb", Aliases: []alphabet.LanguageCode{"nob", "no"}},
+		"nn":  alphabet.Language{Name: "Norwegian Nynorsk", Code: "nn", Aliases: []alphabet.LanguageCode{"nno"}},
+		"zza": alphabet.Language{Name: "Zaza", Code: "zza"},
+	}
+
+	code, exists := languages.Resolve("no-NO")
+	assert.True(t, exists)
+	assert.Equal(t, alphabet.LanguageCode("nb"), code)
+
+	code, exists = languages.Resolve("ZZA")
+	assert.True(t, exists)
+	assert.Equal(t, alphabet.LanguageCode("zza"), code)
+
+	_, exists = languages.Resolve("nyn")
+	assert.False(t, exists)
+
+	lang, err := languages.Get("nno")
+	require.NoError(t, err)
+	assert.Equal(t, "Norwegian Nynorsk", lang.Name)
+}
+
+func TestNormalizeCode(t *testing.T) {
+	testCases := []struct {
+		code     alphabet.LanguageCode
+		expected alphabet.LanguageCode
+	}{
+		{code: "EN", expected: "en"},
+		{code: " pt_br ", expected: "pt-BR"},
+		{code: "SR-LATN-rs", expected: "sr-Latn-RS"},
+		{code: "es-419", expected: "es-419"},
+		{code: "de-CH-1996", expected: "de-CH-1996"},
+	}
+	for _, tC := range testCases {
+		t.Run(string(tC.code), func(t *testing.T) {
+			assert.Equal(t, tC.expected, alphabet.NormalizeCode(tC.code))
+		})
+	}
+}
+
+func TestLanguageCaseMapping(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	assert.Nil(t, en.SpecialCase())
+	assert.Equal(t, 'i', en.ToLower('I'))
+	assert.Equal(t, 'I', en.ToUpper('i'))
+	assert.Equal(t, "istanbul", en.ToLowerString("ISTANBUL"))
+	assert.Equal(t, "ISTANBUL", en.ToUpperString("istanbul"))
+	assert.True(t, en.IsLetter('Q'))
+	assert.False(t, en.IsLetter('É'))
+
+	tr := alphabet.MustBuiltin("tr")
+	assert.NotNil(t, tr.SpecialCase())
+	assert.Equal(t, 'ı', tr.ToLower('I'))
+	assert.Equal(t, 'İ', tr.ToUpper('i'))
+	assert.Equal(t, "ıstanbul", tr.ToLowerString("ISTANBUL"))
+	assert.Equal(t, "İSTANBUL", tr.ToUpperString("istanbul"))
+	assert.True(t, tr.IsLetter('I'))
+	assert.True(t, tr.IsLetter('İ'))
+
+	// The locale takes precedence over the language code
+	custom := alphabet.Language{Name: "Custom", Code: "custom", Letters: "abc", Locale: "az-Latn"}
+	assert.Equal(t, 'ı', custom.ToLower('I'))
+	custom.Locale = "en-GB"
+	assert.Equal(t, 'i', custom.ToLower('I'))
+}
+
+func TestLanguageUpperCase(t *testing.T) {
+	de := alphabet.MustBuiltin("de")
+	assert.Equal(t, 'ẞ', de.ToUpper('ß'))
+	assert.Equal(t, "STRAẞE", de.ToUpperString("straße"))
+	assert.Equal(t, "straße", de.ToLowerString("STRAẞE"))
+
+	nl := alphabet.MustBuiltin("nl")
+	// Multi-rune mappings are only applied to strings
+	assert.Equal(t, 'J', nl.ToUpper('j'))
+	assert.Equal(t, "IJSSELMEER", nl.ToUpperString("ijsselmeer"))
+
+	hu := alphabet.Language{Name: "Test", Code: "test", Letters: "adsz", UpperCase: map[string]string{"dz": "Dz", "dzs": "Dzs"}}
+	assert.Equal(t, "DzsADz", hu.ToUpperString("dzsadz"))
+}
+
+func TestLanguageWordRules(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	assert.True(t, en.IsApostrophe('\''))
+	assert.True(t, en.IsApostrophe('’'))
+	assert.False(t, en.IsApostrophe('-'))
+	assert.True(t, en.IsHyphen('-'))
+	assert.False(t, en.IsRightToLeft())
+
+	ar := alphabet.MustBuiltin("ar")
+	assert.False(t, ar.IsApostrophe('\''))
+	assert.False(t, ar.IsHyphen('-'))
+}
+
+func TestParseDirection(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected alphabet.Direction
+		errMsg   string
+	}{
+		{name: "", expected: ""},
+		{name: "ltr", expected: alphabet.LeftToRight},
+		{name: "RTL", expected: alphabet.RightToLeft},
+		{name: "up", errMsg: "invalid writing direction \"up\" (expected ltr or rtl)"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			d, err := alphabet.ParseDirection(tC.name)
+			if tC.errMsg != "" {
+				assert.ErrorContains(t, err, tC.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, d)
+		})
+	}
 }
@@ -21,16 +21,23 @@ package alphabet
 
 import (
 	"bufio"
+	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/andrejacobs/go-analyse/internal/processor"
 	"github.com/andrejacobs/go-collection/collection"
+	"golang.org/x/exp/maps"
 )
 
 // DiscoverLetters produces a slice containing the unique non-whitespace lowercased letters found in the io.Reader.
@@ -91,17 +98,85 @@ func DiscoverLettersFromFile(ctx context.Context, path string) ([]rune, error) {
 
 //-----------------------------------------------------------------------------
 
-// DiscoverProcessor is used to discover the unique non-whitespace lowercased letters found in the input sources.
+// DefaultDiscoverThreshold is the minimum share of the letter occurrences a letter needs to be part of
+// the alphabet suggested by the [DiscoverProcessor].
+const DefaultDiscoverThreshold = 0.0001
+
+// DiscoverOption is used to configure the [DiscoverProcessor].
+type DiscoverOption func(p *DiscoverProcessor)
+
+// WithLanguage configures the code and name of the language written to the languages file.
+// The default code is "unknown". When the name is empty then the name of the language already in the
+// languages file or otherwise the code is used.
+func WithLanguage(code LanguageCode, name string) DiscoverOption {
+	return func(p *DiscoverProcessor) {
+		p.code = code
+		p.name = name
+	}
+}
+
+// WithUnion configures the suggested alphabet to be combined with the letters of the language already in
+// the languages file instead of replacing them.
+func WithUnion() DiscoverOption {
+	return func(p *DiscoverProcessor) {
+		p.union = true
+	}
+}
+
+// WithThreshold configures the minimum share (between 0 and 1) of all the letter occurrences a letter needs
+// to be part of the suggested alphabet. E.g. 0.0001 drops the letters that make up less than 0.01%.
+func WithThreshold(threshold float64) DiscoverOption {
+	return func(p *DiscoverProcessor) {
+		p.threshold = threshold
+	}
+}
+
+// DiscoveredRune is a rune found by the [DiscoverProcessor] along with the number of times it was found and
+// its unicode script (e.g. Latin, Cyrillic or Common) and general category (e.g. Ll, Nd or Po).
+type DiscoveredRune struct {
+	Rune     rune
+	Count    int64
+	Script   string
+	Category string
+}
+
+// IsLetter returns true if the rune is a letter or a mark (e.g. a Devanagari vowel sign).
+func (d DiscoveredRune) IsLetter() bool {
+	return strings.HasPrefix(d.Category, "L") || strings.HasPrefix(d.Category, "M")
+}
+
+// DiscoverGroup are the discovered runes of the same unicode script and general category.
+type DiscoverGroup struct {
+	Script   string
+	Category string
+	// Count is the total number of times the runes of the group were found.
+	Count int64
+	// Runes are sorted by count (highest first).
+	Runes []DiscoveredRune
+}
+
+// DiscoverProcessor is used to discover the non-whitespace lowercased letters found in the input sources.
+// The occurrences of each rune are counted so that the runes can be grouped by unicode script and category
+// and an alphabet can be suggested that excludes the rarely used letters (e.g. a stray emoji or a typo).
 type DiscoverProcessor struct {
-	proc    *processor.Processor
-	letters collection.Set[rune]
+	proc      *processor.Processor
+	counts    map[rune]int64
+	code      LanguageCode
+	name      string
+	threshold float64
+	union     bool
 }
 
 // NewDiscoverProcessor creates a new processor and does not report progress.
-func NewDiscoverProcessor() *DiscoverProcessor {
+func NewDiscoverProcessor(opts ...DiscoverOption) *DiscoverProcessor {
 	p := &DiscoverProcessor{
-		proc:    processor.NewProcessor(),
-		letters: collection.NewSet[rune](),
+		proc:      processor.NewProcessor(),
+		counts:    make(map[rune]int64),
+		code:      "unknown",
+		threshold: DefaultDiscoverThreshold,
+	}
+	for _, apply := range opts {
+		apply(p)
 	}
 	return p
 }
@@ -113,18 +188,87 @@ func (p *DiscoverProcessor) SetProgressReporter(reporter processor.ProgressRepor
 
 // Letters return the discovered runes. Sounds like a tomb raider story :-D.
 func (p *DiscoverProcessor) Letters() []rune {
-	return p.letters.Items()
+	return maps.Keys(p.counts)
+}
+
+// Runes returns the discovered runes sorted by count (highest first).
+func (p *DiscoverProcessor) Runes() []DiscoveredRune {
+	result := make([]DiscoveredRune, 0, len(p.counts))
+	for r, count := range p.counts {
+		result = append(result, DiscoveredRune{Rune: r, Count: count, Script: runeScript(r), Category: runeCategory(r)})
+	}
+	slices.SortFunc(result, func(a, b DiscoveredRune) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Rune, b.Rune)
+	})
+	return result
+}
+
+// Groups returns the discovered runes grouped by unicode script and general category.
+// The groups are sorted by count (highest first) and then by script and category.
+func (p *DiscoverProcessor) Groups() []DiscoverGroup {
+	index := make(map[[2]string]int)
+	var result []DiscoverGroup
+	for _, d := range p.Runes() {
+		key := [2]string{d.Script, d.Category}
+		i, exists := index[key]
+		if !exists {
+			i = len(result)
+			index[key] = i
+			result = append(result, DiscoverGroup{Script: d.Script, Category: d.Category})
+		}
+		result[i].Count += d.Count
+		result[i].Runes = append(result[i].Runes, d)
+	}
+
+	slices.SortFunc(result, func(a, b DiscoverGroup) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(a.Script, b.Script); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Category, b.Category)
+	})
+	return result
+}
+
+// SuggestedAlphabet returns the sorted letters (and marks) of which the share of all the letter occurrences
+// is at least the threshold. See [WithThreshold].
+func (p *DiscoverProcessor) SuggestedAlphabet() []rune {
+	var total int64
+	runes := p.Runes()
+	for _, d := range runes {
+		if d.IsLetter() {
+			total += d.Count
+		}
+	}
+
+	var result []rune
+	for _, d := range runes {
+		if d.IsLetter() && float64(d.Count)/float64(total) >= p.threshold {
+			result = append(result, d.Rune)
+		}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// Language returns the language with the suggested alphabet.
+func (p *DiscoverProcessor) Language() Language {
+	name := p.name
+	if name == "" {
+		name = string(p.code)
+	}
+	return Language{Code: p.code, Name: name, Letters: string(p.SuggestedAlphabet())}
 }
 
 // ProcessFiles updates the discovered letters from the given input paths.
 func (p *DiscoverProcessor) ProcessFiles(ctx context.Context, paths []string) error {
 	err := p.proc.ProcessFiles(ctx, paths, func(ctx context.Context, r io.Reader) error {
-		runes, err := DiscoverLetters(ctx, r)
-		if err != nil {
-			return err
-		}
-		p.letters.InsertSlice(runes)
-		return nil
+		return countRunes(ctx, r, p.counts)
 	})
 
 	if err != nil {
@@ -134,32 +278,162 @@ func (p *DiscoverProcessor) ProcessFiles(ctx context.Context, paths []string) er
 	return nil
 }
 
-// Save the languages file to the given file path.
-func (p *DiscoverProcessor) Save(path string) error {
-	//AJ### TODO: Need to do "atomic" save and replace
-	f, err := os.Create(path)
+// countRunes counts the non-whitespace lowercased runes read from the io.Reader.
+func countRunes(ctx context.Context, input io.Reader, counts map[rune]int64) error {
+	rd := bufio.NewReader(input)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			r, _, err := rd.ReadRune()
+			if err != nil {
+				if err == io.EOF {
+					// Done reading
+					return nil
+				}
+				return err
+			}
+
+			// Ignore white space
+			if unicode.IsSpace(r) {
+				continue
+			}
+
+			counts[unicode.ToLower(r)]++
+		}
+	}
+}
+
+// WriteReport writes a human readable report of the discovered runes grouped by unicode script and
+// category. The letters that are part of the suggested alphabet are marked with a *.
+func (p *DiscoverProcessor) WriteReport(w io.Writer) error {
+	var total int64
+	for _, count := range p.counts {
+		total += count
+	}
+
+	suggested := p.SuggestedAlphabet()
+	_, err := fmt.Fprintf(w, "Discovered %d runes (%d unique)\nSuggested alphabet (threshold %v): %s\n",
+		total, len(p.counts), p.threshold, string(suggested))
 	if err != nil {
-		return fmt.Errorf("failed to save the languages file %q. %w", path, err)
+		return err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: failed to close %s. %v", path, err)
+
+	for _, group := range p.Groups() {
+		_, err := fmt.Fprintf(w, "\n%s %s: %d (%s%%)\n", group.Script, group.Category, group.Count, percentage(group.Count, total))
+		if err != nil {
+			return err
 		}
-	}()
+		for _, d := range group.Runes {
+			mark := ""
+			if _, found := slices.BinarySearch(suggested, d.Rune); found {
+				mark = " *"
+			}
+			_, err := fmt.Fprintf(w, "  %q %U %d (%s%%)%s\n", d.Rune, d.Rune, d.Count, percentage(d.Count, total), mark)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	_, err = io.WriteString(f, "#code,name,letters\n")
-	if err != nil {
-		return fmt.Errorf("failed to write csv header to %q. %w", path, err)
+func percentage(count int64, total int64) string {
+	return strconv.FormatFloat(float64(count)*100/float64(total), 'f', 2, 64)
+}
+
+// Save the language with the suggested alphabet to the languages file at the given file path.
+// If the file already exists then the languages are loaded and the language is added or its row is
+// updated (see [WithUnion]). The rest of the information about an existing language (e.g. the multi-rune
+// letters) is kept. All the languages are written back sorted by code in the format chosen by
+// [SaveLanguagesToFile]. The comments and other rows of an existing CSV file are kept where they are.
+// The file is replaced only once it has been written completely.
+// See [WithLanguage] and [WithThreshold].
+func (p *DiscoverProcessor) Save(path string) error {
+	languages := make(LanguageMap)
+
+	data, err := os.ReadFile(path)
+	exists := err == nil
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to open %q. %w", path, err)
+	}
+	if exists {
+		loaded, err := LoadLanguages(bytes.NewReader(data))
+		if err != nil && !errors.Is(err, ErrNoLanguages) {
+			return fmt.Errorf("failed to load languages from %q. %w", path, err)
+		}
+		if loaded != nil {
+			languages = loaded
+		}
 	}
 
-	runes := p.Letters()
-	slices.Sort(runes)
-	letters := strings.ReplaceAll(string(runes), `"`, `""`)
+	lang := p.Language()
+	if existing, exists := languages[lang.Code]; exists {
+		letters := lang.Letters
+		if p.union {
+			runes := []rune(existing.Letters + letters)
+			slices.Sort(runes)
+			letters = string(slices.Compact(runes))
+		}
 
-	_, err = io.WriteString(f, `unknown,unknown,"`+letters+`"`)
-	if err != nil {
-		return fmt.Errorf("failed to write csv header to %q. %w", path, err)
+		// Keep the rest of the information about the language (e.g. the multi-rune letters and script)
+		lang = existing
+		lang.Letters = letters
+		if p.name != "" {
+			lang.Name = p.name
+		}
 	}
 
-	return nil
+	if exists && !isJSONPath(path) && !isJSON(bufio.NewReader(bytes.NewReader(data))) {
+		return updateLanguageCSV(path, data, lang)
+	}
+
+	languages[lang.Code] = lang
+	return SaveLanguagesToFile(path, languages)
+}
+
+//-----------------------------------------------------------------------------
+
+var (
+	scriptNames   []string
+	categoryNames []string
+	namesOnce     sync.Once
+)
+
+func loadNames() {
+	namesOnce.Do(func() {
+		scriptNames = maps.Keys(unicode.Scripts)
+		slices.Sort(scriptNames)
+
+		for name := range unicode.Categories {
+			// Only the two letter general categories (e.g. Lu and not L or the LC alias of Lu, Ll and Lt)
+			if len(name) == 2 && name != "LC" {
+				categoryNames = append(categoryNames, name)
+			}
+		}
+		slices.Sort(categoryNames)
+	})
+}
+
+// runeScript returns the name of the unicode script of the rune (e.g. Latin) or Unknown.
+func runeScript(r rune) string {
+	loadNames()
+	for _, name := range scriptNames {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return "Unknown"
+}
+
+// runeCategory returns the unicode general category of the rune (e.g. Ll) or Cn if the rune is unassigned.
+func runeCategory(r rune) string {
+	loadNames()
+	for _, name := range categoryNames {
+		if unicode.Is(unicode.Categories[name], r) {
+			return name
+		}
+	}
+	return "Cn"
 }
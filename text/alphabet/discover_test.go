@@ -20,6 +20,7 @@
 package alphabet_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
@@ -91,7 +92,128 @@ func TestDiscoverProcessor(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "unknown", lang.Name)
 	assert.Equal(t, alphabet.LanguageCode("unknown"), lang.Code)
-	assert.ElementsMatch(t, expected, []rune(lang.Letters))
+	// Punctuation is not part of the suggested alphabet
+	assert.Equal(t, "abdefghiklmnoqrstuvwyàçèéô善士改武道", lang.Letters)
+}
+
+func TestDiscoverProcessorSaveExisting(t *testing.T) {
+	dir := t.TempDir()
+	temp := filepath.Join(dir, "languages.csv")
+	require.NoError(t, os.WriteFile(temp, []byte("#code,name,letters\n# Used by the tests\nzz,Last,xyz\nshort row\nxx,Example,xyz,ij\n"), 0644))
+
+	input := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(input, []byte(strings.Repeat("aab ", 10)), 0644))
+
+	// Add a new language
+	p := alphabet.NewDiscoverProcessor(alphabet.WithLanguage("aa", "First"))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+	require.NoError(t, p.Save(temp))
+
+	// Replace the letters of an existing language and keep its name
+	p = alphabet.NewDiscoverProcessor(alphabet.WithLanguage("zz", ""))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+	require.NoError(t, p.Save(temp))
+
+	// Combine with the letters of an existing language
+	p = alphabet.NewDiscoverProcessor(alphabet.WithLanguage("xx", "Renamed"), alphabet.WithUnion())
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+	require.NoError(t, p.Save(temp))
+
+	data, err := os.ReadFile(temp)
+	require.NoError(t, err)
+	// The languages are sorted by code and the comments and other rows are kept where they are
+	assert.Equal(t, "#code,name,letters\n# Used by the tests\naa,First,ab\nshort row\nxx,Renamed,abxyz,ij\nzz,Last,ab\n", string(data))
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "languages.csv", entries[0].Name())
+}
+
+func TestDiscoverProcessorSaveAppend(t *testing.T) {
+	temp := filepath.Join(t.TempDir(), "languages.csv")
+	require.NoError(t, os.WriteFile(temp, []byte("# No languages yet"), 0644))
+
+	input := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(input, []byte("aab"), 0644))
+
+	p := alphabet.NewDiscoverProcessor(alphabet.WithLanguage("aa", "First"))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+	require.NoError(t, p.Save(temp))
+
+	data, err := os.ReadFile(temp)
+	require.NoError(t, err)
+	assert.Equal(t, "# No languages yet\naa,First,ab\n", string(data))
+}
+
+func TestDiscoverProcessorGroups(t *testing.T) {
+	p := alphabet.NewDiscoverProcessor()
+	err := p.ProcessFiles(context.Background(), []string{"testdata/discover.txt"})
+	require.NoError(t, err)
+
+	runes := p.Runes()
+	assert.Equal(t, alphabet.DiscoveredRune{Rune: 'e', Count: 60, Script: "Latin", Category: "Ll"}, runes[0])
+
+	groups := p.Groups()
+	require.Len(t, groups, 4)
+	assert.Equal(t, "Latin", groups[0].Script)
+	assert.Equal(t, "Ll", groups[0].Category)
+	assert.Equal(t, "Common", groups[1].Script)
+	assert.Equal(t, "Po", groups[1].Category)
+	assert.Equal(t, "Han", groups[2].Script)
+	assert.Equal(t, "Lo", groups[2].Category)
+	assert.Equal(t, int64(15), groups[2].Count)
+	assert.Len(t, groups[2].Runes, 5)
+	assert.Equal(t, "Pd", groups[3].Category)
+
+	var total int64
+	for _, g := range groups {
+		total += g.Count
+	}
+	var expected int64
+	for _, d := range runes {
+		expected += d.Count
+	}
+	assert.Equal(t, expected, total)
+}
+
+func TestDiscoverProcessorThreshold(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "input.txt")
+	require.NoError(t, os.WriteFile(input, []byte(strings.Repeat("aab ", 500)+"c 😀 1, Ω"), 0644))
+
+	p := alphabet.NewDiscoverProcessor(alphabet.WithLanguage("xx", "Example"), alphabet.WithThreshold(0.0005))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+
+	assert.Equal(t, "abcω", string(p.SuggestedAlphabet()))
+	assert.Equal(t, alphabet.Language{Code: "xx", Name: "Example", Letters: "abcω"}, p.Language())
+
+	p = alphabet.NewDiscoverProcessor(alphabet.WithThreshold(0.01))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{input}))
+	assert.Equal(t, "ab", string(p.SuggestedAlphabet()))
+
+	var buf bytes.Buffer
+	require.NoError(t, p.WriteReport(&buf))
+	assert.Equal(t, `Discovered 1505 runes (7 unique)
+Suggested alphabet (threshold 0.01): ab
+
+Latin Ll: 1501 (99.73%)
+  'a' U+0061 1000 (66.45%) *
+  'b' U+0062 500 (33.22%) *
+  'c' U+0063 1 (0.07%)
+
+Common Nd: 1 (0.07%)
+  '1' U+0031 1 (0.07%)
+
+Common Po: 1 (0.07%)
+  ',' U+002C 1 (0.07%)
+
+Common So: 1 (0.07%)
+  '😀' U+1F600 1 (0.07%)
+
+Greek Ll: 1 (0.07%)
+  'ω' U+03C9 1 (0.07%)
+`, buf.String())
 }
 
 //-----------------------------------------------------------------------------
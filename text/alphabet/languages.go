@@ -26,33 +26,48 @@ import (
 )
 
 var languages = LanguageMap{
-	"af": Language{Name: "Afrikaans", Code: "af", Letters: "abcdefghijklmnopqrstuvwxyzáêéèëïíîôóúû"},
-	"ar": Language{Name: "Arabic", Code: "ar", Letters: "أابتثجحخدذرزسشصضطظعغفقكلمنهؤوئىيء"},
-	"da": Language{Name: "Danish", Code: "da", Letters: "abcdefghijklmnopqrstuvwxyzæøå"},
-	"de": Language{Name: "German", Code: "de", Letters: "abcdefghijklmnopqrstuvwxyzäöüß"},
-	"en": Language{Name: "English", Code: "en", Letters: "abcdefghijklmnopqrstuvwxyz"},
-	"es": Language{Name: "Spanish", Code: "es", Letters: "abcdefghijklmnopqrstuvwxyzáéíñóúü"},
-	"et": Language{Name: "Estonian", Code: "et", Letters: "abcdefghijklmnopqrstuvwxyzäöõü"},
-	"fi": Language{Name: "Finnish", Code: "fi", Letters: "abcdefghijklmnopqrstuvwxyzäö"},
-	"fr": Language{Name: "French", Code: "fr", Letters: "abcdefghijklmnopqrstuvwxyzàâæçéèêëîïôœùûüÿ"},
-	"nl": Language{Name: "Dutch", Code: "nl", Letters: "abcdefghijklmnopqrstuvwxyzàäèéëïĳöü"},
-	"sv": Language{Name: "Swedish", Code: "sv", Letters: "abcdefghijklmnopqrstuvwxyzåäö"},
+	"af":      Language{Name: "Afrikaans", Code: "af", Letters: "abcdefghijklmnopqrstuvwxyzáêéèëïíîôóúû", Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"afr"}},
+	"ar":      Language{Name: "Arabic", Code: "ar", Letters: "أابتثجحخدذرزسشصضطظعغفقكلمنهؤوئىيء", Script: "Arab", Direction: RightToLeft, Aliases: []LanguageCode{"ara"}},
+	"cs":      Language{Name: "Czech", Code: "cs", Letters: "aábcčdďeéěfghiíjklmnňoópqrřsštťuúůvwxyýzž", MultiLetters: []string{"ch"}, Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"ces", "cze"}},
+	"da":      Language{Name: "Danish", Code: "da", Letters: "abcdefghijklmnopqrstuvwxyzæøå", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"dan"}},
+	"de":      Language{Name: "German", Code: "de", Letters: "abcdefghijklmnopqrstuvwxyzäöüß", Script: "Latn", UpperCase: map[string]string{"ß": "ẞ"}, Hyphens: "-", Aliases: []LanguageCode{"deu", "ger"}},
+	"el":      Language{Name: "Greek", Code: "el", Letters: "αβγδεζηθικλμνξοπρσςτυφχψωάέήίόύώϊϋΐΰ", Script: "Grek", Hyphens: "-", Aliases: []LanguageCode{"ell", "gre"}},
+	"en":      Language{Name: "English", Code: "en", Letters: "abcdefghijklmnopqrstuvwxyz", Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"eng"}},
+	"es":      Language{Name: "Spanish", Code: "es", Letters: "abcdefghijklmnopqrstuvwxyzáéíñóúü", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"spa"}},
+	"et":      Language{Name: "Estonian", Code: "et", Letters: "abcdefghijklmnopqrstuvwxyzäöõü", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"est"}},
+	"fi":      Language{Name: "Finnish", Code: "fi", Letters: "abcdefghijklmnopqrstuvwxyzäö", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"fin"}},
+	"fr":      Language{Name: "French", Code: "fr", Letters: "abcdefghijklmnopqrstuvwxyzàâæçéèêëîïôœùûüÿ", Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"fra", "fre"}},
+	"he":      Language{Name: "Hebrew", Code: "he", Letters: "אבגדהוזחטיכךלמםנןסעפףצץקרשת", Script: "Hebr", Direction: RightToLeft, Hyphens: "־-", Aliases: []LanguageCode{"heb", "iw"}},
+	"hi":      Language{Name: "Hindi", Code: "hi", Letters: "अआइईउऊऋएऐओऔकखगघङचछजझञटठडढणतथदधनपफबभमयरलवशषसहािीुूृेैोौंःँ़्", Script: "Deva", Aliases: []LanguageCode{"hin"}},
+	"it":      Language{Name: "Italian", Code: "it", Letters: "abcdefghijklmnopqrstuvwxyzàèéìíîòóùú", Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"ita"}},
+	"nl":      Language{Name: "Dutch", Code: "nl", Letters: "abcdefghijklmnopqrstuvwxyzàäèéëïĳöü", MultiLetters: []string{"ij"}, Script: "Latn", UpperCase: map[string]string{"ij": "IJ"}, Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"nld", "dut"}},
+	"pl":      Language{Name: "Polish", Code: "pl", Letters: "aąbcćdeęfghijklłmnńoóprsśtuvwxyzźż", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"pol"}},
+	"pt":      Language{Name: "Portuguese", Code: "pt", Letters: "abcdefghijklmnopqrstuvwxyzàáâãçéêíóôõú", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"por"}},
+	"ru":      Language{Name: "Russian", Code: "ru", Letters: "абвгдеёжзийклмнопрстуфхцчшщъыьэюя", Script: "Cyrl", Hyphens: "-", Aliases: []LanguageCode{"rus"}},
+	"sr":      Language{Name: "Serbian", Code: "sr", Letters: "абвгдђежзијклљмнњопрстћуфхцчџш", Script: "Cyrl", Hyphens: "-", Aliases: []LanguageCode{"srp", "sr-Cyrl"}},
+	"sr-Latn": Language{Name: "Serbian (Latin)", Code: "sr-Latn", Letters: "abcčćdđefghijklmnoprsštuvzž", MultiLetters: []string{"dž", "lj", "nj"}, Script: "Latn", Hyphens: "-"},
+	"sv":      Language{Name: "Swedish", Code: "sv", Letters: "abcdefghijklmnopqrstuvwxyzåäö", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"swe"}},
+	"tr":      Language{Name: "Turkish", Code: "tr", Letters: "abcçdefgğhıijklmnoöprsştuüvyzâîû", Script: "Latn", Apostrophes: "'’", Hyphens: "-", Aliases: []LanguageCode{"tur"}},
+	"uk":      Language{Name: "Ukrainian", Code: "uk", Letters: "абвгґдеєжзиіїйклмнопрстуфхцчшщьюя", Script: "Cyrl", Apostrophes: "'’ʼ", Hyphens: "-", Aliases: []LanguageCode{"ukr"}},
+	"xh":      Language{Name: "Xhosa", Code: "xh", Letters: "abcdefghijklmnopqrstuvwxyz", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"xho"}},
+	"zu":      Language{Name: "Zulu", Code: "zu", Letters: "abcdefghijklmnopqrstuvwxyz", Script: "Latn", Hyphens: "-", Aliases: []LanguageCode{"zul"}},
 }
 
-// Builtin returns the built-in language for the given ISO 639 set 1 language.
+// Builtin returns the built-in language for the given language code, alias or BCP 47 language tag.
+// See [LanguageMap.Resolve] for more details.
 func Builtin(code LanguageCode) (Language, error) {
-	lang, exists := languages[code]
+	resolved, exists := languages.Resolve(code)
 	if !exists {
 		return Language{}, fmt.Errorf("no built-in language found with code %q", code)
 	}
-	return lang, nil
+	return languages[resolved], nil
 }
 
-// MustBuiltin returns the built-in language for the given ISO 639 set 1 language or panics.
+// MustBuiltin returns the built-in language for the given language code, alias or BCP 47 language tag or panics.
 func MustBuiltin(code LanguageCode) Language {
-	lang, exists := languages[code]
-	if !exists {
-		panic(fmt.Errorf("no built-in language found with code %q", code))
+	lang, err := Builtin(code)
+	if err != nil {
+		panic(err)
 	}
 	return lang
 }
@@ -20,26 +20,61 @@
 package alphabet
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
 
-	_ "golang.org/x/exp/maps"
+	"golang.org/x/exp/maps"
 )
 
 // ErrNoLanguages is returned when no languages could be loaded.
 var ErrNoLanguages = errors.New("no languages")
 
+// LanguagesFormatVersion is the version of the JSON languages format written by [SaveLanguagesJSON].
+const LanguagesFormatVersion = 1
+
 // LoadLanguages parses a set of languages from an io.Reader.
+// The input is either in the JSON format (see [SaveLanguagesJSON]) or in the CSV format.
+// The JSON format is detected by the input starting with a {.
 //
-// Expected CSV format in UTF-8: code,name,letters
+// Expected CSV format in UTF-8: code,name,letters[,multiletters]
+// The optional multiletters column is a space separated list of letters that are made up of
+// more than one rune (e.g. Dutch ij).
 // Lines starting with a # is ignored.
 func LoadLanguages(r io.Reader) (LanguageMap, error) {
+	bufR := bufio.NewReader(r)
+	if isJSON(bufR) {
+		return loadLanguagesJSON(bufR)
+	}
+	return loadLanguagesCSV(bufR)
+}
+
+// isJSON peeks at the first non-whitespace byte to see if the input is in the JSON format.
+func isJSON(r *bufio.Reader) bool {
+	for n := 1; ; n++ {
+		peek, _ := r.Peek(n)
+		if len(peek) < n {
+			return false
+		}
+		if b := peek[n-1]; !unicode.IsSpace(rune(b)) {
+			return b == '{'
+		}
+	}
+}
+
+func loadLanguagesCSV(r io.Reader) (LanguageMap, error) {
 	result := make(LanguageMap)
 	csvR := csv.NewReader(r)
+	csvR.FieldsPerRecord = -1
 
 	for {
 		record, err := csvR.Read()
@@ -65,6 +100,10 @@ func LoadLanguages(r io.Reader) (LanguageMap, error) {
 			Letters: strings.ToLower(record[2]),
 		}
 
+		if len(record) > 3 {
+			l.MultiLetters = strings.Fields(strings.ToLower(record[3]))
+		}
+
 		result[code] = l
 	}
 
@@ -95,3 +134,319 @@ func LoadLanguagesFromFile(path string) (LanguageMap, error) {
 
 	return result, nil
 }
+
+// SaveLanguages writes the languages sorted by code to the io.Writer in the CSV format used by [LoadLanguages].
+// Only the code, name, letters and multi-rune letters are written. See [SaveLanguagesJSON] for the format that
+// keeps all the information about a language.
+func SaveLanguages(w io.Writer, languages LanguageMap) error {
+	csvW := csv.NewWriter(w)
+	if err := csvW.Write([]string{"#code", "name", "letters", "multiletters"}); err != nil {
+		return fmt.Errorf("failed to write the csv header. %w", err)
+	}
+
+	codes := maps.Keys(languages)
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		if err := csvW.Write(languageRecord(languages[code])); err != nil {
+			return fmt.Errorf("failed to write the language %q. %w", code, err)
+		}
+	}
+
+	csvW.Flush()
+	if err := csvW.Error(); err != nil {
+		return fmt.Errorf("failed to write the languages. %w", err)
+	}
+	return nil
+}
+
+// languageRecord returns the CSV row of the language: code,name,letters[,multiletters]
+func languageRecord(lang Language) []string {
+	record := []string{string(lang.Code), lang.Name, lang.Letters}
+	if len(lang.MultiLetters) > 0 {
+		record = append(record, strings.Join(lang.MultiLetters, " "))
+	}
+	return record
+}
+
+// SaveLanguagesToFile writes the languages to the file. The JSON format (see [SaveLanguagesJSON]) is used
+// when the file has the .json extension, otherwise the CSV format (see [SaveLanguages]) is used.
+// The languages are first written to a temporary file that then replaces the file, so that an existing
+// file is left untouched when the languages could not be written.
+func SaveLanguagesToFile(path string, languages LanguageMap) error {
+	save := SaveLanguages
+	if isJSONPath(path) {
+		save = SaveLanguagesJSON
+	}
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		return save(w, languages)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the languages file %q. %w", path, err)
+	}
+	return nil
+}
+
+// isJSONPath returns true if the file has the .json extension.
+func isJSONPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// writeFileAtomic writes to a temporary file in the same directory as the file and then renames it over
+// the file. The permissions of an existing file are kept.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tempPath := f.Name()
+
+	err = write(f)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// updateLanguageCSV adds or replaces the row of the language in the existing CSV languages file (data) and
+// writes all the languages back sorted by code. The comments and rows that are not languages are kept where
+// they are and the languages fill the places of the language rows in sorted order. A new language adds a
+// place after the last language row (or at the end of the file).
+func updateLanguageCSV(path string, data []byte, lang Language) error {
+	// Each line is either kept as is (raw) or is the place of a language row (raw is the leading blank lines)
+	type line struct {
+		raw      []byte
+		language bool
+	}
+	var lines []line
+	records := [][]string{languageRecord(lang)}
+	found := false
+	last := -1
+
+	csvR := csv.NewReader(bytes.NewReader(data))
+	csvR.FieldsPerRecord = -1
+	var start int64
+	for {
+		record, err := csvR.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse csv. %w", err)
+		}
+		end := csvR.InputOffset()
+		raw := data[start:end]
+		start = end
+
+		if len(record) < 3 || strings.HasPrefix(record[0], "#") {
+			lines = append(lines, line{raw: raw})
+			continue
+		}
+
+		// The row of the language being saved is replaced and any duplicate rows of it are dropped
+		if LanguageCode(record[0]) == lang.Code {
+			if found {
+				continue
+			}
+			found = true
+		} else {
+			records = append(records, record)
+		}
+		blank := raw[:len(raw)-len(bytes.TrimLeft(raw, "\r\n"))]
+		lines = append(lines, line{raw: blank, language: true})
+		last = len(lines) - 1
+	}
+	trailing := data[start:]
+
+	// A new language needs a place of its own
+	if !found {
+		if last < 0 {
+			last = len(lines) - 1
+		}
+		lines = slices.Insert(lines, last+1, line{language: true})
+	}
+
+	slices.SortStableFunc(records, func(a, b []string) int {
+		return strings.Compare(a[0], b[0])
+	})
+
+	var out bytes.Buffer
+	csvW := csv.NewWriter(&out)
+	for _, l := range lines {
+		// The last line of the file might not have ended with a newline
+		if out.Len() > 0 && !bytes.HasSuffix(out.Bytes(), []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		out.Write(l.raw)
+		if !l.language {
+			continue
+		}
+		if err := csvW.Write(records[0]); err != nil {
+			return fmt.Errorf("failed to write the language %q. %w", records[0][0], err)
+		}
+		csvW.Flush()
+		records = records[1:]
+	}
+	if err := csvW.Error(); err != nil {
+		return fmt.Errorf("failed to write the languages. %w", err)
+	}
+	out.Write(trailing)
+
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write(out.Bytes())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save the languages file %q. %w", path, err)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// JSON format
+
+// languagesFile is the versioned JSON languages format.
+type languagesFile struct {
+	Version   int            `json:"version"`
+	Languages []languageJSON `json:"languages"`
+}
+
+type languageJSON struct {
+	Code         LanguageCode      `json:"code"`
+	Name         string            `json:"name"`
+	Script       string            `json:"script,omitempty"`
+	Direction    Direction         `json:"direction,omitempty"`
+	Locale       string            `json:"locale,omitempty"`
+	Letters      string            `json:"letters"`
+	MultiLetters []string          `json:"multiletters,omitempty"`
+	UpperCase    map[string]string `json:"uppercase,omitempty"`
+	Apostrophes  string            `json:"apostrophes,omitempty"`
+	Hyphens      string            `json:"hyphens,omitempty"`
+	Aliases      []LanguageCode    `json:"aliases,omitempty"`
+}
+
+func loadLanguagesJSON(r io.Reader) (LanguageMap, error) {
+	var file languagesFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to parse json. %w", err)
+	}
+
+	if file.Version < 1 || file.Version > LanguagesFormatVersion {
+		return nil, fmt.Errorf("unsupported languages format version %d (expected %d)", file.Version, LanguagesFormatVersion)
+	}
+
+	result := make(LanguageMap)
+	for _, l := range file.Languages {
+		if l.Code == "" {
+			return nil, fmt.Errorf("missing language code for %q", l.Name)
+		}
+
+		direction, err := ParseDirection(string(l.Direction))
+		if err != nil {
+			return nil, fmt.Errorf("invalid language %q. %w", l.Code, err)
+		}
+
+		if l.Script != "" && len(l.Script) != 4 {
+			return nil, fmt.Errorf("invalid language %q. invalid script %q (expected an ISO 15924 code e.g. Latn)", l.Code, l.Script)
+		}
+
+		lang := Language{
+			Name:        l.Name,
+			Code:        l.Code,
+			Letters:     strings.ToLower(l.Letters),
+			Locale:      l.Locale,
+			Script:      l.Script,
+			Direction:   direction,
+			Apostrophes: l.Apostrophes,
+			Hyphens:     l.Hyphens,
+			Aliases:     l.Aliases,
+		}
+		for _, letter := range l.MultiLetters {
+			lang.MultiLetters = append(lang.MultiLetters, strings.ToLower(letter))
+		}
+		if len(l.UpperCase) > 0 {
+			lang.UpperCase = make(map[string]string, len(l.UpperCase))
+			for letter, upper := range l.UpperCase {
+				lang.UpperCase[strings.ToLower(letter)] = upper
+			}
+		}
+
+		result[l.Code] = lang
+	}
+
+	if len(result) < 1 {
+		return nil, ErrNoLanguages
+	}
+
+	return result, nil
+}
+
+// SaveLanguagesJSON writes the languages sorted by code to the io.Writer in the versioned JSON format.
+// Unlike the CSV format, all the information about a language (e.g. the script and writing direction)
+// is written.
+//
+//	{
+//	  "version": 1,
+//	  "languages": [
+//	    {
+//	      "code": "de",
+//	      "name": "German",
+//	      "script": "Latn",
+//	      "letters": "abcdefghijklmnopqrstuvwxyzäöüß",
+//	      "uppercase": {"ß": "ẞ"},
+//	      "hyphens": "-"
+//	    }
+//	  ]
+//	}
+func SaveLanguagesJSON(w io.Writer, languages LanguageMap) error {
+	codes := maps.Keys(languages)
+	slices.Sort(codes)
+
+	file := languagesFile{
+		Version:   LanguagesFormatVersion,
+		Languages: make([]languageJSON, 0, len(codes)),
+	}
+	for _, code := range codes {
+		lang := languages[code]
+		file.Languages = append(file.Languages, languageJSON{
+			Code:         code,
+			Name:         lang.Name,
+			Script:       lang.Script,
+			Direction:    lang.Direction,
+			Locale:       lang.Locale,
+			Letters:      lang.Letters,
+			MultiLetters: lang.MultiLetters,
+			UpperCase:    lang.UpperCase,
+			Apostrophes:  lang.Apostrophes,
+			Hyphens:      lang.Hyphens,
+			Aliases:      lang.Aliases,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(file); err != nil {
+		return fmt.Errorf("failed to write the languages. %w", err)
+	}
+	return nil
+}
@@ -20,6 +20,9 @@
 package alphabet_test
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -48,6 +51,20 @@ coding,Coding,{}[]/$^%
 	assert.Equal(t, languages["coding"], alphabet.Language{Name: "Coding", Code: "coding", Letters: `{}[]/$^%`})
 }
 
+func TestLoadLanguagesMultiLetters(t *testing.T) {
+	r := strings.NewReader(`#code,name,letters,multiletters
+en,English,abcdefghijklmnopqrstuvwxyz
+hu,Hungarian,abcdefghijklmnopqrstuvwxyzáéíóöőúüű,CS DZ dzs gy ly ny sz ty zs
+`)
+
+	languages, err := alphabet.LoadLanguages(r)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(languages))
+
+	assert.Empty(t, languages["en"].MultiLetters)
+	assert.Equal(t, []string{"cs", "dz", "dzs", "gy", "ly", "ny", "sz", "ty", "zs"}, languages["hu"].MultiLetters)
+}
+
 func TestLoadLanguagesFromFile(t *testing.T) {
 	languages, err := alphabet.LoadLanguagesFromFile("testdata/languages.csv")
 	require.NoError(t, err)
@@ -55,18 +72,94 @@ func TestLoadLanguagesFromFile(t *testing.T) {
 	assert.Contains(t, languages, alphabet.LanguageCode("en"))
 	assert.Contains(t, languages, alphabet.LanguageCode("af"))
 
-	// generated languages.go should be the exact same as loading "testdata/languages.csv"
+	// The CSV format only contains the letters of the generated languages.go
 	for k, v := range languages {
 		b, err := alphabet.Builtin(k)
 		require.NoError(t, err)
 
-		assert.Equal(t, v, b)
+		assert.Equal(t, b.Name, v.Name)
+		assert.Equal(t, b.Letters, v.Letters)
+		assert.Equal(t, b.MultiLetters, v.MultiLetters)
 	}
 
 	_, err = alphabet.LoadLanguagesFromFile("testdata/naf.csv")
 	require.ErrorContains(t, err, "failed to open \"testdata/naf.csv\"")
 }
 
+func TestLoadLanguagesJSON(t *testing.T) {
+	languages, err := alphabet.LoadLanguagesFromFile("testdata/languages.json")
+	require.NoError(t, err)
+
+	// generated languages.go should be the exact same as loading "testdata/languages.json"
+	assert.Equal(t, alphabet.BuiltinLanguages(), languages)
+
+	r := strings.NewReader(`
+	{"version": 1, "languages": [
+		{"code": "tr", "name": "Turkish", "locale": "tr-TR", "script": "Latn", "direction": "LTR", "letters": "ABCÇ"},
+		{"code": "xx", "name": "Example", "letters": "ab", "multiletters": ["AB"], "uppercase": {"AB": "Ab"}}
+	]}`)
+	languages, err = alphabet.LoadLanguages(r)
+	require.NoError(t, err)
+	assert.Equal(t, alphabet.Language{Name: "Turkish", Code: "tr", Letters: "abcç", Locale: "tr-TR", Script: "Latn",
+		Direction: alphabet.LeftToRight}, languages["tr"])
+	assert.Equal(t, alphabet.Language{Name: "Example", Code: "xx", Letters: "ab", MultiLetters: []string{"ab"},
+		UpperCase: map[string]string{"ab": "Ab"}}, languages["xx"])
+}
+
+func TestLoadLanguagesJSONInvalid(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		input  string
+		errMsg string
+	}{
+		{desc: "not json", input: "{code,name", errMsg: "failed to parse json"},
+		{desc: "missing version", input: `{"languages": [{"code": "en", "letters": "abc"}]}`,
+			errMsg: "unsupported languages format version 0 (expected 1)"},
+		{desc: "future version", input: `{"version": 2, "languages": [{"code": "en", "letters": "abc"}]}`,
+			errMsg: "unsupported languages format version 2 (expected 1)"},
+		{desc: "missing code", input: `{"version": 1, "languages": [{"name": "English", "letters": "abc"}]}`,
+			errMsg: "missing language code for \"English\""},
+		{desc: "direction", input: `{"version": 1, "languages": [{"code": "en", "direction": "up", "letters": "abc"}]}`,
+			errMsg: "invalid language \"en\". invalid writing direction \"up\""},
+		{desc: "script", input: `{"version": 1, "languages": [{"code": "en", "script": "Latin", "letters": "abc"}]}`,
+			errMsg: "invalid script \"Latin\""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := alphabet.LoadLanguages(strings.NewReader(tC.input))
+			assert.ErrorContains(t, err, tC.errMsg)
+		})
+	}
+
+	_, err := alphabet.LoadLanguages(strings.NewReader(`{"version": 1, "languages": []}`))
+	assert.ErrorIs(t, err, alphabet.ErrNoLanguages)
+}
+
+func TestSaveLanguagesJSON(t *testing.T) {
+	languages := alphabet.BuiltinLanguages()
+
+	var buf bytes.Buffer
+	require.NoError(t, alphabet.SaveLanguagesJSON(&buf, languages))
+	assert.Contains(t, buf.String(), `"version": 1`)
+	assert.Contains(t, buf.String(), `"ß": "ẞ"`)
+
+	loaded, err := alphabet.LoadLanguages(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, languages, loaded)
+
+	data, err := os.ReadFile("testdata/languages.json")
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, alphabet.SaveLanguagesJSON(&buf, languages))
+	assert.Equal(t, string(data), buf.String())
+
+	temp := filepath.Join(t.TempDir(), "languages.json")
+	require.NoError(t, alphabet.SaveLanguagesToFile(temp, languages))
+	loaded, err = alphabet.LoadLanguagesFromFile(temp)
+	require.NoError(t, err)
+	assert.Equal(t, languages, loaded)
+}
+
 func TestLoadLanguagesEmpty(t *testing.T) {
 	r := strings.NewReader("")
 
@@ -82,8 +175,8 @@ func TestLanguageMapGet(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, alphabet.LanguageCode("af"), lang.Code)
 
-	_, err = languages.Get("zu")
-	assert.ErrorContains(t, err, "no language found with code \"zu\"")
+	_, err = languages.Get("xx")
+	assert.ErrorContains(t, err, "no language found with code \"xx\"")
 }
 
 func TestLoadLanguagesReadFailed(t *testing.T) {
@@ -91,3 +184,33 @@ func TestLoadLanguagesReadFailed(t *testing.T) {
 	_, err := alphabet.LoadLanguages(&r)
 	assert.ErrorContains(t, err, "failed to parse csv")
 }
+
+func TestSaveLanguages(t *testing.T) {
+	languages := alphabet.LanguageMap{
+		"hu": alphabet.Language{Code: "hu", Name: "Hungarian", Letters: "abc", MultiLetters: []string{"cs", "dz"}},
+		"af": alphabet.Language{Code: "af", Name: "Afrikaans", Letters: "abcê"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, alphabet.SaveLanguages(&buf, languages))
+	assert.Equal(t, "#code,name,letters,multiletters\naf,Afrikaans,abcê\nhu,Hungarian,abc,cs dz\n", buf.String())
+
+	loaded, err := alphabet.LoadLanguages(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, languages, loaded)
+}
+
+func TestSaveLanguagesToFile(t *testing.T) {
+	languages, err := alphabet.LoadLanguagesFromFile("testdata/languages.csv")
+	require.NoError(t, err)
+
+	temp := filepath.Join(t.TempDir(), "languages.csv")
+	require.NoError(t, alphabet.SaveLanguagesToFile(temp, languages))
+
+	loaded, err := alphabet.LoadLanguagesFromFile(temp)
+	require.NoError(t, err)
+	assert.Equal(t, languages, loaded)
+
+	err = alphabet.SaveLanguagesToFile(filepath.Join(t.TempDir(), "naf", "languages.csv"), languages)
+	assert.ErrorContains(t, err, "failed to save the languages file")
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+)
+
+//go:generate go run generate_profiles.go
+
+// The built-in profiles contain the most frequent letter monograms, bigrams and trigrams of each of the
+// built-in languages and are generated from the sample corpora in testdata/corpus.
+//
+//go:embed profiles/*.csv
+var builtinProfiles embed.FS
+
+// BuiltinProfileCodes returns the sorted codes of the languages that have a built-in profile.
+func BuiltinProfileCodes() []alphabet.LanguageCode {
+	entries, err := builtinProfiles.ReadDir("profiles")
+	if err != nil {
+		panic(fmt.Errorf("failed to read the built-in profiles. %w", err))
+	}
+
+	result := make([]alphabet.LanguageCode, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, alphabet.LanguageCode(strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))))
+	}
+	slices.Sort(result)
+	return result
+}
+
+// BuiltinProfile returns the built-in profile of the language. Profiles are available for all of the
+// languages in [alphabet.BuiltinLanguages].
+func BuiltinProfile(code alphabet.LanguageCode) (*Profile, error) {
+	f, err := builtinProfiles.Open(fmt.Sprintf("profiles/%s.csv", code))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no built-in profile for the language %q", code)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	ft, err := ngrams.LoadFrequencies(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the built-in profile of %q. %w", code, err)
+	}
+	return NewProfile(code, languageName(code), ft), nil
+}
+
+// BuiltinProfiles returns the built-in profiles sorted by language code.
+func BuiltinProfiles() ([]*Profile, error) {
+	codes := BuiltinProfileCodes()
+	result := make([]*Profile, 0, len(codes))
+	for _, code := range codes {
+		p, err := BuiltinProfile(code)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// MergeProfiles returns the profiles extended with the others (e.g. user built profiles combined with the
+// built-in profiles). A profile is replaced by the other profile of the same language.
+// The result is sorted by language code.
+func MergeProfiles(profiles []*Profile, others ...*Profile) []*Profile {
+	byCode := make(map[alphabet.LanguageCode]*Profile, len(profiles)+len(others))
+	for _, p := range profiles {
+		byCode[p.Code] = p
+	}
+	for _, p := range others {
+		byCode[p.Code] = p
+	}
+
+	result := make([]*Profile, 0, len(byCode))
+	for _, p := range byCode {
+		result = append(result, p)
+	}
+	slices.SortFunc(result, func(a, b *Profile) int {
+		return strings.Compare(string(a.Code), string(b.Code))
+	})
+	return result
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/langid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+)
+
+func TestBuiltinProfiles(t *testing.T) {
+	codes := maps.Keys(alphabet.BuiltinLanguages())
+	assert.ElementsMatch(t, codes, langid.BuiltinProfileCodes())
+
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, len(codes))
+
+	for _, p := range profiles {
+		lang, err := alphabet.Builtin(p.Code)
+		require.NoError(t, err)
+		assert.Equal(t, lang.Name, p.Name)
+		assert.Equal(t, []int{1, 2, 3}, p.Sizes())
+	}
+
+	_, err = langid.BuiltinProfile("xx")
+	assert.ErrorContains(t, err, `no built-in profile for the language "xx"`)
+}
+
+func TestBuiltinProfilesIdentify(t *testing.T) {
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		code alphabet.LanguageCode
+		text string
+	}{
+		{code: "af", text: "Ek het gister saam met my broer na die stad gegaan om nuwe skoene te koop."},
+		{code: "ar", text: "ذهبت أمس مع أخي إلى المدينة لشراء حذاء جديد."},
+		{code: "da", text: "Jeg tog i går med min bror ind til byen for at købe nye sko."},
+		{code: "de", text: "Ich bin gestern mit meinem Bruder in die Stadt gefahren, um neue Schuhe zu kaufen."},
+		{code: "en", text: "Yesterday I went with my brother to the city to buy new shoes."},
+		{code: "es", text: "Ayer fui con mi hermano a la ciudad para comprar zapatos nuevos."},
+		{code: "et", text: "Ma käisin eile koos oma vennaga linnas uusi kingi ostmas."},
+		{code: "fi", text: "Kävin eilen veljeni kanssa kaupungissa ostamassa uusia kenkiä."},
+		{code: "fr", text: "Hier, je suis allé en ville avec mon frère pour acheter de nouvelles chaussures."},
+		{code: "nl", text: "Gisteren ben ik met mijn broer naar de stad gegaan om nieuwe schoenen te kopen."},
+		{code: "sv", text: "Igår åkte jag med min bror till staden för att köpa nya skor."},
+		{code: "cs", text: "Včera jsem jel s bratrem do města, abychom si koupili nové boty."},
+		{code: "el", text: "Χθες πήγα με τον αδερφό μου στην πόλη για να αγοράσουμε καινούργια παπούτσια."},
+		{code: "he", text: "אתמול נסעתי עם אחי לעיר כדי לקנות נעליים חדשות."},
+		{code: "hi", text: "कल मैं अपने भाई के साथ नए जूते खरीदने के लिए शहर गया था।"},
+		{code: "it", text: "Ieri sono andato in città con mio fratello per comprare delle scarpe nuove."},
+		{code: "pl", text: "Wczoraj pojechałem z bratem do miasta, żeby kupić nowe buty."},
+		{code: "pt", text: "Ontem fui com o meu irmão à cidade para comprar sapatos novos."},
+		{code: "ru", text: "Вчера я ездил с братом в город, чтобы купить новые ботинки."},
+		{code: "sr", text: "Јуче сам са братом ишао у град да купимо нове ципеле."},
+		{code: "sr-Latn", text: "Juče sam sa bratom išao u grad da kupimo nove cipele."},
+		{code: "tr", text: "Dün yeni ayakkabı almak için kardeşimle birlikte şehre gittim."},
+		{code: "uk", text: "Учора я їздив із братом до міста, щоб купити нові черевики."},
+		{code: "xh", text: "Rhoqo kusasa ndisela ikofu kwaye ndifunda iincwadi ecaleni komlilo."},
+		{code: "zu", text: "Izolo ngiye edolobheni nomfowethu siyothenga izicathulo ezintsha."},
+	}
+
+	for _, method := range []langid.Method{langid.MethodCavnarTrenkle, langid.MethodNaiveBayes} {
+		id, err := langid.NewIdentifier(profiles, langid.WithMethod(method))
+		require.NoError(t, err)
+
+		for _, tc := range testCases {
+			t.Run(method.String()+"/"+string(tc.code), func(t *testing.T) {
+				results, err := id.IdentifyText(tc.text)
+				require.NoError(t, err)
+				assert.Equal(t, tc.code, results[0].Code)
+			})
+		}
+	}
+}
+
+// Sentences that are not part of the sample corpora used to generate the built-in profiles. Afrikaans, Dutch
+// and English need to be told apart reliably by the --filter-lines of an Afrikaans corpus.
+var heldOutSentences = map[alphabet.LanguageCode][]string{
+	"af": {
+		"Ons bure het gisteraand 'n groot braai gehou en die hele straat was genooi.",
+		"Sy wou nie na die dokter gaan nie, want sy was bang vir die naald.",
+		"Hoeveel kos 'n kaartjie vir die trein na Kaapstad?",
+		"Die boer moes sy skape vroeër as gewoonlik inbring omdat dit begin hael het.",
+		"My ma sê altyd dat 'n mens nooit te oud is om iets nuuts te leer nie.",
+		"Ek kan nie glo dat die vakansie al verby is nie.",
+		"Hulle het die ou huis gekoop en dit self oorgeverf.",
+		"Waar het jy jou sleutels laas gesien?",
+		"Die span het die wedstryd met twee punte gewen.",
+		"Ons moet more vroeg opstaan as ons die son wil sien opkom.",
+	},
+	"nl": {
+		"Onze buren hebben gisteravond een groot feest gegeven en de hele straat was uitgenodigd.",
+		"Ze wilde niet naar de dokter gaan, want ze was bang voor de naald.",
+		"Hoeveel kost een kaartje voor de trein naar Amsterdam?",
+		"De boer moest zijn schapen eerder dan normaal binnenhalen omdat het begon te hagelen.",
+		"Mijn moeder zegt altijd dat je nooit te oud bent om iets nieuws te leren.",
+		"Ik kan niet geloven dat de vakantie alweer voorbij is.",
+		"Ze hebben het oude huis gekocht en het zelf geschilderd.",
+		"Waar heb je je sleutels voor het laatst gezien?",
+		"Het team heeft de wedstrijd met twee punten gewonnen.",
+		"We moeten morgen vroeg opstaan als we de zon willen zien opkomen.",
+	},
+	"en": {
+		"Our neighbours had a big barbecue last night and the whole street was invited.",
+		"She did not want to go to the doctor because she was scared of the needle.",
+		"How much does a ticket for the train to London cost?",
+		"The farmer had to bring his sheep in earlier than usual because it started to hail.",
+		"My mother always says that you are never too old to learn something new.",
+		"I can not believe that the holiday is already over.",
+		"They bought the old house and painted it themselves.",
+		"Where did you last see your keys?",
+		"The team won the match by two points.",
+		"We have to get up early tomorrow if we want to see the sun rise.",
+	},
+}
+
+func TestBuiltinProfilesHeldOut(t *testing.T) {
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+
+	for _, method := range []langid.Method{langid.MethodCavnarTrenkle, langid.MethodNaiveBayes} {
+		id, err := langid.NewIdentifier(profiles, langid.WithMethod(method))
+		require.NoError(t, err)
+
+		for code, sentences := range heldOutSentences {
+			t.Run(method.String()+"/"+string(code), func(t *testing.T) {
+				for _, sentence := range sentences {
+					results, err := id.IdentifyText(sentence)
+					require.NoError(t, err)
+					assert.Equal(t, code, results[0].Code, sentence)
+				}
+			})
+		}
+	}
+
+	f, err := langid.NewLineFilter(alphabet.MustBuiltin("af"), profiles)
+	require.NoError(t, err)
+	for code, sentences := range heldOutSentences {
+		for _, sentence := range sentences {
+			assert.Equal(t, code == "af", f.Keep(sentence), sentence)
+		}
+	}
+}
+
+func TestBuiltinProfilesConfidence(t *testing.T) {
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+
+	for _, method := range []langid.Method{langid.MethodCavnarTrenkle, langid.MethodNaiveBayes} {
+		t.Run(method.String(), func(t *testing.T) {
+			id, err := langid.NewIdentifier(profiles, langid.WithMethod(method))
+			require.NoError(t, err)
+
+			results, err := id.IdentifyText("Yesterday I went with my brother to the city to buy new shoes.")
+			require.NoError(t, err)
+			assert.Equal(t, alphabet.LanguageCode("en"), results[0].Code)
+			assert.Greater(t, results[0].Confidence, 0.9)
+		})
+	}
+}
+
+func TestMergeProfiles(t *testing.T) {
+	builtin, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+
+	en, err := langid.LoadProfile("en", filepath.Join(testdata, "freq-1-en-alice.csv"))
+	require.NoError(t, err)
+	zz, err := langid.LoadProfile("zz", filepath.Join(testdata, "freq-1-af-control.csv"))
+	require.NoError(t, err)
+
+	merged := langid.MergeProfiles(builtin, zz, en)
+	require.Len(t, merged, len(builtin)+1)
+	assert.Equal(t, alphabet.LanguageCode("zz"), merged[len(merged)-1].Code)
+
+	for _, p := range merged {
+		if p.Code == "en" {
+			assert.Same(t, en, p)
+		}
+	}
+}
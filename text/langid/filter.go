@@ -0,0 +1,266 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/andrejacobs/go-analyse/internal/processor"
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+)
+
+// DefaultLineThreshold is the minimum score a line needs to be kept by the [LineFilter].
+const DefaultLineThreshold = 0.5
+
+// LineFilterOption is used to configure the [LineFilter].
+type LineFilterOption func(f *LineFilter)
+
+// WithThreshold configures the minimum score (between 0 and 1) a line needs to be kept.
+func WithThreshold(threshold float64) LineFilterOption {
+	return func(f *LineFilter) {
+		f.threshold = threshold
+	}
+}
+
+// LineStats are the number of lines read and rejected from an input source by the [LineFilter].
+type LineStats struct {
+	Source   string
+	Lines    int64
+	Rejected int64
+}
+
+// LineFilter drops the lines of the input that are not written in the target language. E.g. the English
+// lines found in an Afrikaans corpus.
+//
+// Each line is scored by multiplying the ratio of its letters that are part of the alphabet of the language
+// (the coverage) with the confidence of the language relative to the most likely language identified from
+// the ngram profiles. A score of 1 means all the letters are part of the alphabet and the language is the
+// most likely one. Lines that only contain whitespace or no letters at all are kept.
+//
+// The naive Bayes method is used to identify the language of each line because the rank order of the few
+// ngrams found in a single line is not reliable enough for the Cavnar-Trenkle method. When there is no profile
+// for the language (e.g. a custom alphabet) the lines are only scored by their coverage.
+type LineFilter struct {
+	language  alphabet.Language
+	id        *Identifier
+	threshold float64
+
+	mu    sync.Mutex
+	stats []*LineStats
+
+	// Scratch space (*lineScratch) reused to identify the language of the lines
+	scratch sync.Pool
+}
+
+// lineScratch is the tokenizer and ngrams of a line reused between lines to avoid creating a new frequency
+// table and tokenizer for every line.
+type lineScratch struct {
+	tok     ngrams.Tokenizer
+	entries []ngrams.Frequency
+	emit    ngrams.EmitFunc
+}
+
+// NewLineFilter creates a filter that keeps the lines scored against the language using the profiles of the
+// candidate languages. See [BuiltinProfiles]. If the profiles do not include the language then only the
+// alphabet coverage of the lines is used.
+func NewLineFilter(language alphabet.Language, profiles []*Profile, opts ...LineFilterOption) (*LineFilter, error) {
+	f := &LineFilter{
+		language:  language,
+		threshold: DefaultLineThreshold,
+	}
+	for _, apply := range opts {
+		apply(f)
+	}
+
+	if f.threshold < 0 || f.threshold > 1 {
+		return nil, fmt.Errorf("invalid threshold %v (expected a value between 0 and 1)", f.threshold)
+	}
+	if !slices.ContainsFunc(profiles, func(p *Profile) bool { return p.Code == language.Code }) {
+		return f, nil
+	}
+
+	id, err := NewIdentifier(profiles, WithMethod(MethodNaiveBayes))
+	if err != nil {
+		return nil, err
+	}
+	f.id = id
+	f.scratch.New = func() any {
+		scratch := &lineScratch{tok: ngrams.NewLetterTokenizer(id.language, id.sizes)}
+		scratch.emit = func(_ int, token string) error {
+			scratch.entries = append(scratch.entries, ngrams.Frequency{Token: token, Count: 1})
+			return nil
+		}
+		return scratch
+	}
+	return f, nil
+}
+
+// Score returns the score (between 0 and 1) of the line for the language of the filter.
+func (f *LineFilter) Score(line string) float64 {
+	coverage, ok := f.coverage(line)
+	if !ok {
+		return 1
+	}
+	if f.id == nil || coverage == 0 {
+		return coverage
+	}
+	return coverage * f.confidence(line)
+}
+
+// Keep returns true if the score of the line is at least the threshold of the filter.
+func (f *LineFilter) Keep(line string) bool {
+	coverage, ok := f.coverage(line)
+	if !ok {
+		return true
+	}
+	// The score can not be more than the coverage, so there is no need to identify the language
+	if f.id == nil || coverage < f.threshold {
+		return coverage >= f.threshold
+	}
+	return coverage*f.confidence(line) >= f.threshold
+}
+
+// coverage returns the ratio of the letters of the line that are part of the alphabet of the language.
+// False is returned if the line does not contain any letters.
+func (f *LineFilter) coverage(line string) (float64, bool) {
+	var letters, covered int
+	for _, r := range line {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if f.language.IsLetter(r) {
+			covered++
+		}
+	}
+	if letters == 0 {
+		return 0, false
+	}
+	return float64(covered) / float64(letters), true
+}
+
+// confidence returns the confidence of the language relative to the most likely language of the line.
+func (f *LineFilter) confidence(line string) float64 {
+	scratch := f.scratch.Get().(*lineScratch)
+	defer f.scratch.Put(scratch)
+
+	scratch.entries = scratch.entries[:0]
+	for _, r := range line {
+		_ = scratch.tok.Next(r, scratch.emit)
+	}
+	_ = scratch.tok.End(scratch.emit)
+	if len(scratch.entries) == 0 {
+		// None of the ngrams are part of the profiled languages
+		return 0
+	}
+
+	var confidence, best float64
+	for _, r := range f.id.naiveBayes(scratch.entries) {
+		if r.Code == f.language.Code {
+			confidence = r.Confidence
+		}
+		best = max(best, r.Confidence)
+	}
+	return confidence / best
+}
+
+// Reader returns an io.Reader that only reads the lines of r that are kept by the filter.
+// The number of lines read and rejected are recorded for the input source identified by the context.
+// The signature matches [ngrams.InputFilter] so that the filter can be used in front of the tokenizers.
+func (f *LineFilter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	stats := &LineStats{Source: processor.Source(ctx)}
+	f.mu.Lock()
+	f.stats = append(f.stats, stats)
+	f.mu.Unlock()
+
+	return &lineFilterReader{
+		filter: f,
+		input:  bufio.NewReader(r),
+		stats:  stats,
+	}
+}
+
+// Stats returns the number of lines read and rejected of each input source in the order they were read.
+func (f *LineFilter) Stats() []LineStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]LineStats, 0, len(f.stats))
+	for _, stats := range f.stats {
+		result = append(result, *stats)
+	}
+	return result
+}
+
+//-----------------------------------------------------------------------------
+
+// lineFilterReader reads the lines of the input and only passes on the ones kept by the filter.
+type lineFilterReader struct {
+	filter *LineFilter
+	input  *bufio.Reader
+	stats  *LineStats
+	// Remainder of the current line that has not been read yet
+	pending string
+	err     error
+}
+
+func (r *lineFilterReader) Read(p []byte) (int, error) {
+	for r.pending == "" {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		line, err := r.input.ReadString('\n')
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return 0, err
+			}
+			r.err = io.EOF
+		}
+		if line == "" {
+			continue
+		}
+
+		keep := strings.TrimSpace(line) == "" || r.filter.Keep(line)
+		r.filter.mu.Lock()
+		r.stats.Lines++
+		if !keep {
+			r.stats.Rejected++
+		}
+		r.filter.mu.Unlock()
+
+		if keep {
+			r.pending = line
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/langid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mixedAfrikaans = `Goeie môre, my vrou, hier is 'n soentjie vir jou.
+The quick brown fox jumps over the lazy dog.
+
+Daar is koffie in die kan en die kinders speel in die tuin.
+Please subscribe to our newsletter for more updates.
+2024
+Ons gaan môre saam met die hele familie see toe.
+`
+
+func newTestLineFilter(t *testing.T, opts ...langid.LineFilterOption) *langid.LineFilter {
+	t.Helper()
+
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+	f, err := langid.NewLineFilter(alphabet.MustBuiltin("af"), profiles, opts...)
+	require.NoError(t, err)
+	return f
+}
+
+func TestLineFilterReader(t *testing.T) {
+	expected := `Goeie môre, my vrou, hier is 'n soentjie vir jou.
+
+Daar is koffie in die kan en die kinders speel in die tuin.
+2024
+Ons gaan môre saam met die hele familie see toe.
+`
+
+	f := newTestLineFilter(t)
+
+	data, err := io.ReadAll(f.Reader(context.Background(), strings.NewReader(mixedAfrikaans)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+
+	assert.Equal(t, []langid.LineStats{{Lines: 7, Rejected: 2}}, f.Stats())
+}
+
+func TestLineFilterScore(t *testing.T) {
+	f := newTestLineFilter(t)
+
+	assert.InDelta(t, 1.0, f.Score("Die kinders speel in die tuin."), 0.001)
+	assert.Less(t, f.Score("The children are playing in the garden."), langid.DefaultLineThreshold)
+	assert.Equal(t, 1.0, f.Score("1234 ---"))
+	assert.Equal(t, 0.0, f.Score("Привет мир"))
+
+	assert.True(t, f.Keep("Die kinders speel in die tuin."))
+	assert.False(t, f.Keep("Привет мир"))
+}
+
+func TestLineFilterThreshold(t *testing.T) {
+	f := newTestLineFilter(t, langid.WithThreshold(0))
+	data, err := io.ReadAll(f.Reader(context.Background(), strings.NewReader(mixedAfrikaans)))
+	require.NoError(t, err)
+	assert.Equal(t, mixedAfrikaans, string(data))
+
+	profiles := loadTestProfiles(t)
+
+	_, err = langid.NewLineFilter(alphabet.MustBuiltin("af"), profiles, langid.WithThreshold(1.5))
+	assert.ErrorContains(t, err, "invalid threshold 1.5")
+
+}
+
+func TestLineFilterWithoutProfile(t *testing.T) {
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+
+	language := alphabet.Language{Name: "Custom", Code: "xx", Letters: "abcdefghijklmnopqrstuvwxyz"}
+	f, err := langid.NewLineFilter(language, profiles)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, f.Score("The children are playing in the garden."))
+	assert.InDelta(t, 0.5, f.Score("abcd абвг"), 0.001)
+	assert.Equal(t, 0.0, f.Score("Привет мир"))
+
+	data, err := io.ReadAll(f.Reader(context.Background(), strings.NewReader("Die kinders speel.\nПривет мир\n")))
+	require.NoError(t, err)
+	assert.Equal(t, "Die kinders speel.\n", string(data))
+}
+
+func TestLineFilterSmallReads(t *testing.T) {
+	f := newTestLineFilter(t)
+	r := f.Reader(context.Background(), strings.NewReader("Die kinders speel in die tuin.\nThe children are playing in the garden."))
+
+	var sb strings.Builder
+	buffer := make([]byte, 3)
+	for {
+		n, err := r.Read(buffer)
+		sb.Write(buffer[:n])
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "Die kinders speel in die tuin.\n", sb.String())
+}
+
+func TestLineFilterConcurrentScore(t *testing.T) {
+	f := newTestLineFilter(t)
+	lines := strings.Split(mixedAfrikaans, "\n")
+
+	expected := make([]float64, len(lines))
+	for i, line := range lines {
+		expected[i] = f.Score(line)
+		assert.Equal(t, expected[i] >= langid.DefaultLineThreshold, f.Keep(line), line)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				for k, line := range lines {
+					assert.Equal(t, expected[k], f.Score(line), line)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkLineFilterKeep(b *testing.B) {
+	profiles, err := langid.BuiltinProfiles()
+	require.NoError(b, err)
+	f, err := langid.NewLineFilter(alphabet.MustBuiltin("af"), profiles)
+	require.NoError(b, err)
+	lines := strings.Split(mixedAfrikaans, "\n")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			_ = f.Keep(line)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+//go:build ignore
+// +build ignore
+
+// Generates the built-in language profiles (profiles/<language-code>.csv) from the sample corpora
+// (testdata/corpus/<language-code>.txt). The samples are short everyday texts written for this repository:
+// about 5 KB for Afrikaans, Dutch and English and 1 to 3 KB for the other languages. Profiles generated from
+// real corpora are more reliable, especially for closely related languages like Zulu and Xhosa.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"golang.org/x/exp/maps"
+)
+
+const (
+	outputDir = "profiles"
+	inputDir  = "testdata/corpus"
+	// The number of most frequent ngrams of each size that are kept
+	profileLength = 300
+)
+
+var sizes = []int{1, 2, 3}
+
+func main() {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		die(err)
+	}
+
+	languages := alphabet.BuiltinLanguages()
+	codes := maps.Keys(languages)
+	slices.Sort(codes)
+
+	for _, code := range codes {
+		if err := generateProfile(languages[code]); err != nil {
+			die(err)
+		}
+	}
+}
+
+func die(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+func generateProfile(lang alphabet.Language) error {
+	inputPath := filepath.Join(inputDir, string(lang.Code)+".txt")
+	outputPath := filepath.Join(outputDir, string(lang.Code)+".csv")
+	fmt.Printf("Generating %s from %s\n", outputPath, inputPath)
+
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("no sample corpus found for the built-in language %q. %w", lang.Code, err)
+	}
+	defer f.Close()
+
+	ft := ngrams.NewFrequencyTable()
+	if err := ft.ParseTokens(context.Background(), f, ngrams.NewLetterTokenizer(lang, sizes)); err != nil {
+		return err
+	}
+
+	// Only keep the most frequent ngrams of each size
+	bySize := make(map[int][]ngrams.Frequency)
+	for _, freq := range ft.Entries() {
+		size := utf8.RuneCountInString(freq.Token)
+		bySize[size] = append(bySize[size], freq)
+	}
+
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// The percentage column is left out to keep the profiles compact
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"#token", "count"}); err != nil {
+		return err
+	}
+	for _, size := range sizes {
+		entries := bySize[size]
+		slices.SortFunc(entries, func(a, b ngrams.Frequency) int {
+			if c := cmp.Compare(b.Count, a.Count); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Token, b.Token)
+		})
+		for _, freq := range keep(entries) {
+			if err := w.Write([]string{freq.Token, strconv.FormatInt(freq.Count, 10)}); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// keep returns the most frequent ngrams (sorted by count) that are kept in the profile. The ngrams that have
+// the same count as the first ngram that does not fit are left out as well. The sample corpora are small and
+// which of the many ngrams seen only once (or twice) are kept would otherwise be decided by their alphabetical
+// order instead of their frequency.
+func keep(entries []ngrams.Frequency) []ngrams.Frequency {
+	if len(entries) <= profileLength {
+		return entries
+	}
+	n := profileLength
+	for n > 0 && entries[n-1].Count == entries[profileLength].Count {
+		n--
+	}
+	return entries[:n]
+}
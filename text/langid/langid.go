@@ -0,0 +1,375 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/internal/processor"
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"golang.org/x/exp/maps"
+)
+
+// Method specifies how a document is scored against the profiles of the languages.
+type Method int
+
+const (
+	// MethodCavnarTrenkle compares the rank order of the most frequent ngrams of the document with those of
+	// the language (the "out-of-place" distance). The confidence is derived from how much further the
+	// language is from the document than the closest language.
+	MethodCavnarTrenkle Method = iota
+	// MethodNaiveBayes calculates the likelihood of the ngrams of the document given the language using
+	// Laplace smoothing. The confidence is the posterior probability of the language assuming all the
+	// languages are equally likely.
+	MethodNaiveBayes
+)
+
+var methodNames = []string{"cavnar-trenkle", "naive-bayes"}
+
+// String returns the name of the method.
+func (m Method) String() string {
+	if m < 0 || int(m) >= len(methodNames) {
+		return fmt.Sprintf("Method(%d)", int(m))
+	}
+	return methodNames[m]
+}
+
+// ParseMethod returns the method for the given name (cavnar-trenkle or naive-bayes).
+func ParseMethod(name string) (Method, error) {
+	for i, n := range methodNames {
+		if strings.EqualFold(n, name) {
+			return Method(i), nil
+		}
+	}
+	return MethodCavnarTrenkle, fmt.Errorf("invalid language identification method %q", name)
+}
+
+// DefaultProfileLength is the number of most frequent ngrams compared by the Cavnar-Trenkle method.
+const DefaultProfileLength = 300
+
+// cavnarTrenkleTemperature scales the normalised distances (between 0 and 1) before the softmax.
+// A language that is 5% of the maximum distance further away than the best language is about 12 times
+// less likely.
+const cavnarTrenkleTemperature = 0.02
+
+// ErrNoNgrams is returned when no ngrams made up of the letters of the profiled languages were found.
+var ErrNoNgrams = errors.New("no ngrams of the profiled languages found")
+
+// Option is used to configure the [Identifier].
+type Option func(id *Identifier)
+
+// WithMethod configures the method used to score the languages.
+func WithMethod(method Method) Option {
+	return func(id *Identifier) {
+		id.method = method
+	}
+}
+
+// WithProfileLength configures the number of most frequent ngrams compared by the Cavnar-Trenkle method.
+func WithProfileLength(length int) Option {
+	return func(id *Identifier) {
+		id.length = length
+	}
+}
+
+// Result is the score of a candidate language for a document.
+type Result struct {
+	Code alphabet.LanguageCode
+	Name string
+	// Score is the out-of-place distance (lower is better) for Cavnar-Trenkle or the log likelihood
+	// (higher is better) for naive Bayes.
+	Score float64
+	// Confidence is between 0 and 1 and the confidences of all the candidate languages add up to 1.
+	Confidence float64
+}
+
+// Identifier ranks the candidate languages of documents.
+type Identifier struct {
+	profiles []*Profile
+	method   Method
+	length   int
+
+	// language contains all the letters of the profiles and is used to tokenize the documents
+	language alphabet.Language
+	sizes    []int
+	// ranks of the most frequent ngrams of each profile (Cavnar-Trenkle)
+	ranks []map[string]int
+	// vocabulary is the number of distinct ngrams of each size across all the profiles (naive Bayes)
+	vocabulary map[int]int
+	// logProbs are the log probabilities of the ngrams of each profile and unseen those of the ngrams of each
+	// size not found in the profile (naive Bayes)
+	logProbs []map[string]float64
+	unseen   []map[int]float64
+	// scored are the ngram sizes used by naive Bayes. These are the sizes found in all the profiles so that
+	// a profile is not penalized for the sizes it does not have.
+	scored map[int]bool
+}
+
+// NewIdentifier creates an identifier that ranks the languages of the profiles.
+// The Cavnar-Trenkle method is used by default.
+func NewIdentifier(profiles []*Profile, opts ...Option) (*Identifier, error) {
+	id := &Identifier{
+		profiles: profiles,
+		length:   DefaultProfileLength,
+	}
+	for _, apply := range opts {
+		apply(id)
+	}
+
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("expected at least one language profile")
+	}
+	if id.length < 1 {
+		return nil, fmt.Errorf("invalid profile length %d", id.length)
+	}
+
+	letters := make(map[rune]struct{})
+	sizes := make(map[int]struct{})
+	vocabulary := make(map[int]map[string]struct{})
+	id.ranks = make([]map[string]int, 0, len(profiles))
+
+	for _, p := range profiles {
+		for _, token := range p.ranked {
+			size := 0
+			for _, r := range token {
+				letters[r] = struct{}{}
+				size++
+			}
+			sizes[size] = struct{}{}
+			if vocabulary[size] == nil {
+				vocabulary[size] = make(map[string]struct{})
+			}
+			vocabulary[size][token] = struct{}{}
+		}
+		id.ranks = append(id.ranks, rankMap(p, id.length))
+	}
+
+	runes := maps.Keys(letters)
+	slices.Sort(runes)
+	id.language = alphabet.Language{Name: "profiles", Code: "profiles", Letters: string(runes)}
+
+	id.sizes = maps.Keys(sizes)
+	slices.Sort(id.sizes)
+
+	id.vocabulary = make(map[int]int, len(vocabulary))
+	for size, tokens := range vocabulary {
+		id.vocabulary[size] = len(tokens)
+	}
+
+	id.scored = make(map[int]bool, len(id.sizes))
+	for _, size := range id.sizes {
+		id.scored[size] = true
+		for _, p := range profiles {
+			if !slices.Contains(p.Sizes(), size) {
+				id.scored[size] = false
+				break
+			}
+		}
+	}
+	if !slices.Contains(maps.Values(id.scored), true) {
+		// No size is shared by all the profiles
+		for size := range id.scored {
+			id.scored[size] = true
+		}
+	}
+
+	// Laplace smoothed probabilities
+	id.logProbs = make([]map[string]float64, 0, len(profiles))
+	id.unseen = make([]map[int]float64, 0, len(profiles))
+	for _, p := range profiles {
+		logProbs := make(map[string]float64, len(p.counts))
+		for token, count := range p.counts {
+			size := utf8.RuneCountInString(token)
+			logProbs[token] = math.Log(float64(count+1) / float64(p.totals[size]+int64(id.vocabulary[size])))
+		}
+		unseen := make(map[int]float64, len(id.sizes))
+		for _, size := range id.sizes {
+			unseen[size] = math.Log(1 / float64(p.totals[size]+int64(id.vocabulary[size])))
+		}
+		id.logProbs = append(id.logProbs, logProbs)
+		id.unseen = append(id.unseen, unseen)
+	}
+	return id, nil
+}
+
+// rankMap returns the rank of each of the first length ngrams of the profile. Ngrams with the same count
+// share the same rank so that their alphabetical order does not count towards the distance.
+func rankMap(p *Profile, length int) map[string]int {
+	ranked := p.ranked[:min(length, len(p.ranked))]
+	result := make(map[string]int, len(ranked))
+	rank := 0
+	for i, token := range ranked {
+		if i > 0 && p.counts[token] != p.counts[ranked[i-1]] {
+			rank = i
+		}
+		result[token] = rank
+	}
+	return result
+}
+
+// Method returns the method used to score the languages.
+func (id *Identifier) Method() Method {
+	return id.method
+}
+
+// Identify returns the candidate languages of the document read from the io.Reader sorted from the most to
+// the least likely. [ErrNoNgrams] is returned if the document does not contain any of the profiled ngrams.
+func (id *Identifier) Identify(ctx context.Context, input io.Reader) ([]Result, error) {
+	doc := ngrams.NewFrequencyTable()
+	if err := doc.ParseTokens(ctx, input, ngrams.NewLetterTokenizer(id.language, id.sizes)); err != nil {
+		return nil, err
+	}
+	return id.IdentifyTable(doc)
+}
+
+// IdentifyText returns the candidate languages of the text. See [Identifier.Identify].
+func (id *Identifier) IdentifyText(text string) ([]Result, error) {
+	return id.Identify(context.Background(), strings.NewReader(text))
+}
+
+// IdentifyFunc is called with the candidate languages of each input source processed by [Identifier.IdentifyFiles].
+// If an error is returned then processing stops and the error is returned.
+type IdentifyFunc func(source string, results []Result, err error) error
+
+// IdentifyFiles identifies the languages of each of the input paths. Each file inside of a zip file is
+// identified separately. Errors identifying a source (e.g. [ErrNoNgrams]) are passed on to fn.
+func (id *Identifier) IdentifyFiles(ctx context.Context, paths []string, fn IdentifyFunc) error {
+	proc := processor.NewProcessor()
+	return proc.ProcessFiles(ctx, paths, func(ctx context.Context, r io.Reader) error {
+		results, err := id.Identify(ctx, r)
+		return fn(processor.Source(ctx), results, err)
+	})
+}
+
+// IdentifyTable returns the candidate languages of the document from its letter ngram frequency table.
+func (id *Identifier) IdentifyTable(doc *ngrams.FrequencyTable) ([]Result, error) {
+	if doc.Len() == 0 {
+		return nil, ErrNoNgrams
+	}
+
+	var results []Result
+	if id.method == MethodNaiveBayes {
+		results = id.naiveBayes(doc.Entries())
+	} else {
+		results = id.cavnarTrenkle(doc)
+	}
+
+	slices.SortStableFunc(results, func(a, b Result) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+	return results, nil
+}
+
+func (id *Identifier) cavnarTrenkle(doc *ngrams.FrequencyTable) []Result {
+	entries := doc.EntriesSortedByCount()
+	entries = entries[:min(id.length, len(entries))]
+
+	// The maximum distance is when none of the ngrams of the document are found in the profile
+	maxDistance := float64(len(entries) * id.length)
+
+	// Ngrams of the document with the same count share the same rank (see rankMap)
+	ranks := make([]int, len(entries))
+	for i := 1; i < len(entries); i++ {
+		ranks[i] = ranks[i-1]
+		if entries[i].Count != entries[i-1].Count {
+			ranks[i] = i
+		}
+	}
+
+	results := make([]Result, 0, len(id.profiles))
+	best := math.Inf(1)
+	for i, p := range id.profiles {
+		distance := 0
+		for j, freq := range entries {
+			rank := ranks[j]
+			profileRank, exists := id.ranks[i][freq.Token]
+			if !exists {
+				distance += id.length
+				continue
+			}
+			distance += abs(rank - profileRank)
+		}
+
+		best = min(best, float64(distance))
+		results = append(results, Result{Code: p.Code, Name: p.Name, Score: float64(distance)})
+	}
+
+	// Softmax of the negative normalised distances. The confidence of a language falls off with how much
+	// further it is from the document than the best language, instead of how close it is in absolute terms.
+	var total float64
+	for i := range results {
+		results[i].Confidence = math.Exp(-(results[i].Score - best) / maxDistance / cavnarTrenkleTemperature)
+		total += results[i].Confidence
+	}
+	for i := range results {
+		results[i].Confidence /= total
+	}
+	return results
+}
+
+// naiveBayes scores the languages by the ngram entries of the document. The same token may be found in more
+// than one entry.
+func (id *Identifier) naiveBayes(entries []ngrams.Frequency) []Result {
+	results := make([]Result, 0, len(id.profiles))
+	best := math.Inf(-1)
+	for i, p := range id.profiles {
+		var score float64
+		for _, freq := range entries {
+			size := utf8.RuneCountInString(freq.Token)
+			if !id.scored[size] {
+				continue
+			}
+			logProb, exists := id.logProbs[i][freq.Token]
+			if !exists {
+				logProb = id.unseen[i][size]
+			}
+			score += float64(freq.Count) * logProb
+		}
+		best = max(best, score)
+		results = append(results, Result{Code: p.Code, Name: p.Name, Score: score})
+	}
+
+	// Posterior probabilities (softmax of the log likelihoods)
+	var total float64
+	for i := range results {
+		results[i].Confidence = math.Exp(results[i].Score - best)
+		total += results[i].Confidence
+	}
+	for i := range results {
+		results[i].Confidence /= total
+	}
+	return results
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
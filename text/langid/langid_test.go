@@ -0,0 +1,234 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/langid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testdata = "../ngrams/testdata"
+
+func loadTestProfiles(t *testing.T) []*langid.Profile {
+	t.Helper()
+
+	en, err := langid.LoadProfile("en",
+		filepath.Join(testdata, "freq-1-en-alice.csv"),
+		filepath.Join(testdata, "freq-2-en-alice.csv"))
+	require.NoError(t, err)
+
+	fr, err := langid.LoadProfile("fr",
+		filepath.Join(testdata, "freq-1-fr-alice.csv"),
+		filepath.Join(testdata, "freq-2-fr-alice.csv"))
+	require.NoError(t, err)
+
+	af, err := langid.LoadProfile("af",
+		filepath.Join(testdata, "freq-1-af-control.csv"),
+		filepath.Join(testdata, "freq-2-af-control.csv"))
+	require.NoError(t, err)
+
+	return []*langid.Profile{af, en, fr}
+}
+
+func TestLoadProfile(t *testing.T) {
+	p, err := langid.LoadProfile("en",
+		filepath.Join(testdata, "freq-1-en-alice.csv"),
+		filepath.Join(testdata, "freq-3-en-alice.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, alphabet.LanguageCode("en"), p.Code)
+	assert.Equal(t, "English", p.Name)
+	assert.Equal(t, []int{1, 3}, p.Sizes())
+	assert.Positive(t, p.Len())
+
+	p, err = langid.LoadProfile("xx", filepath.Join(testdata, "freq-1-af-control.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "xx", p.Name)
+
+	_, err = langid.LoadProfile("en", filepath.Join(testdata, "not-found.csv"))
+	assert.ErrorContains(t, err, `failed to load the profile of "en"`)
+}
+
+func TestLoadProfilesFromDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"freq-1-en-alice.csv":    "en-letters-1.csv",
+		"freq-2-en-alice.csv":    "en-letters-2.csv",
+		"freq-1-af-control.csv":  "af-letters-1.csv",
+		"freq-1w-af-control.csv": "af-words-1.csv",
+	}
+	for src, dest := range files {
+		data, err := os.ReadFile(filepath.Join(testdata, src))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, dest), data, 0o644))
+	}
+
+	profiles, err := langid.LoadProfilesFromDir(dir)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, alphabet.LanguageCode("af"), profiles[0].Code)
+	assert.Equal(t, "Afrikaans", profiles[0].Name)
+	assert.Equal(t, []int{1}, profiles[0].Sizes())
+	assert.Equal(t, alphabet.LanguageCode("en"), profiles[1].Code)
+	assert.Equal(t, []int{1, 2}, profiles[1].Sizes())
+
+	_, err = langid.LoadProfilesFromDir(t.TempDir())
+	assert.ErrorContains(t, err, "no profiles")
+
+	_, err = langid.LoadProfilesFromDir(filepath.Join(dir, "not-found"))
+	assert.ErrorContains(t, err, "failed to read the profiles directory")
+}
+
+func TestParseMethod(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected langid.Method
+		err      bool
+	}{
+		{name: "cavnar-trenkle", expected: langid.MethodCavnarTrenkle},
+		{name: "Naive-Bayes", expected: langid.MethodNaiveBayes},
+		{name: "other", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := langid.ParseMethod(tc.name)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, m)
+		})
+	}
+
+	assert.Equal(t, "naive-bayes", langid.MethodNaiveBayes.String())
+}
+
+func TestIdentify(t *testing.T) {
+	profiles := loadTestProfiles(t)
+
+	testCases := []struct {
+		path     string
+		expected alphabet.LanguageCode
+	}{
+		{path: "en-control.txt", expected: "en"},
+		{path: "af-control.txt", expected: "af"},
+		{path: "fr-alice-partial.txt", expected: "fr"},
+	}
+
+	for _, method := range []langid.Method{langid.MethodCavnarTrenkle, langid.MethodNaiveBayes} {
+		id, err := langid.NewIdentifier(profiles, langid.WithMethod(method))
+		require.NoError(t, err)
+		assert.Equal(t, method, id.Method())
+
+		for _, tc := range testCases {
+			t.Run(method.String()+"/"+tc.path, func(t *testing.T) {
+				f, err := os.Open(filepath.Join(testdata, tc.path))
+				require.NoError(t, err)
+				defer f.Close()
+
+				results, err := id.Identify(context.Background(), f)
+				require.NoError(t, err)
+				require.Len(t, results, len(profiles))
+				assert.Equal(t, tc.expected, results[0].Code)
+
+				var total float64
+				for i, r := range results {
+					total += r.Confidence
+					if i > 0 {
+						assert.GreaterOrEqual(t, results[i-1].Confidence, r.Confidence)
+					}
+				}
+				assert.InDelta(t, 1.0, total, 1e-9)
+			})
+		}
+	}
+}
+
+func TestIdentifyText(t *testing.T) {
+	id, err := langid.NewIdentifier(loadTestProfiles(t), langid.WithProfileLength(100))
+	require.NoError(t, err)
+
+	results, err := id.IdentifyText("The quick brown fox jumps over the lazy dog and the cat.")
+	require.NoError(t, err)
+	assert.Equal(t, alphabet.LanguageCode("en"), results[0].Code)
+	assert.Equal(t, "English", results[0].Name)
+
+	_, err = id.IdentifyText("1234 !!")
+	assert.ErrorIs(t, err, langid.ErrNoNgrams)
+}
+
+func TestIdentifyNaiveBayesMixedSizes(t *testing.T) {
+	profiles := loadTestProfiles(t)
+
+	// Only the bigrams are shared by all the profiles
+	fr, err := langid.LoadProfile("fr",
+		filepath.Join(testdata, "freq-2-fr-alice.csv"),
+		filepath.Join(testdata, "freq-3-fr-alice.csv"))
+	require.NoError(t, err)
+	builtin, err := langid.BuiltinProfiles()
+	require.NoError(t, err)
+	profiles = langid.MergeProfiles(builtin, append(profiles[:2], fr)...)
+
+	id, err := langid.NewIdentifier(profiles, langid.WithMethod(langid.MethodNaiveBayes))
+	require.NoError(t, err)
+
+	f, err := os.Open(filepath.Join(testdata, "fr-alice-partial.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	results, err := id.Identify(context.Background(), f)
+	require.NoError(t, err)
+	assert.Equal(t, alphabet.LanguageCode("fr"), results[0].Code)
+}
+
+func TestNewIdentifierErrors(t *testing.T) {
+	_, err := langid.NewIdentifier(nil)
+	assert.Error(t, err)
+
+	_, err = langid.NewIdentifier(loadTestProfiles(t), langid.WithProfileLength(0))
+	assert.ErrorContains(t, err, "invalid profile length")
+}
+
+func TestIdentifyFiles(t *testing.T) {
+	id, err := langid.NewIdentifier(loadTestProfiles(t), langid.WithMethod(langid.MethodNaiveBayes))
+	require.NoError(t, err)
+
+	found := make(map[string]alphabet.LanguageCode)
+	err = id.IdentifyFiles(context.Background(),
+		[]string{filepath.Join(testdata, "en-control.txt"), filepath.Join(testdata, "af-control.txt")},
+		func(source string, results []langid.Result, err error) error {
+			require.NoError(t, err)
+			found[source] = results[0].Code
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]alphabet.LanguageCode{
+		filepath.Join(testdata, "en-control.txt"): "en",
+		filepath.Join(testdata, "af-control.txt"): "af",
+	}, found)
+}
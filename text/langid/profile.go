@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package langid
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"golang.org/x/exp/maps"
+)
+
+// Profile is the letter ngram profile of a language.
+type Profile struct {
+	Code alphabet.LanguageCode
+	Name string
+
+	counts map[string]int64
+	// totals is the total count of the ngrams of each size
+	totals map[int]int64
+	// ranked are the ngrams sorted by count (highest first)
+	ranked []string
+}
+
+// NewProfile creates the profile of the language from the letter ngram frequency tables. Usually the tables
+// of the ngram sizes 1 to 3 are used. The tables of different sizes are combined into a single profile.
+func NewProfile(code alphabet.LanguageCode, name string, tables ...*ngrams.FrequencyTable) *Profile {
+	p := &Profile{
+		Code:   code,
+		Name:   name,
+		counts: make(map[string]int64),
+		totals: make(map[int]int64),
+	}
+
+	for _, ft := range tables {
+		for _, freq := range ft.Entries() {
+			p.counts[freq.Token] += freq.Count
+			p.totals[utf8.RuneCountInString(freq.Token)] += freq.Count
+		}
+	}
+
+	p.ranked = maps.Keys(p.counts)
+	slices.SortFunc(p.ranked, func(a, b string) int {
+		if c := cmp.Compare(p.counts[b], p.counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+	return p
+}
+
+// LoadProfile creates the profile of the language from the letter ngram frequency tables saved at the paths.
+// The name of the language is looked up in the built-in languages and is the code otherwise.
+func LoadProfile(code alphabet.LanguageCode, paths ...string) (*Profile, error) {
+	tables := make([]*ngrams.FrequencyTable, 0, len(paths))
+	for _, path := range paths {
+		ft, err := ngrams.LoadFrequenciesFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the profile of %q. %w", code, err)
+		}
+		tables = append(tables, ft)
+	}
+	return NewProfile(code, languageName(code), tables...), nil
+}
+
+// profileFileRegex matches the names of the letter frequency tables created by the ngrams app. E.g. af-letters-3.csv.
+var profileFileRegex = regexp.MustCompile(`^(.+)-letters-\d+\.csv$`)
+
+// LoadProfilesFromDir creates the profiles of the languages from the letter ngram frequency tables in the
+// directory. The files are expected to be named <language-code>-letters-<size>.csv (e.g. af-letters-3.csv)
+// as created by the ngrams app. The profiles are sorted by language code.
+func LoadProfilesFromDir(dir string) ([]*Profile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the profiles directory %q. %w", dir, err)
+	}
+
+	paths := make(map[alphabet.LanguageCode][]string)
+	for _, entry := range entries {
+		matches := profileFileRegex.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+		code := alphabet.LanguageCode(matches[1])
+		paths[code] = append(paths[code], filepath.Join(dir, entry.Name()))
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no profiles (<language-code>-letters-<size>.csv) found in %q", dir)
+	}
+
+	codes := maps.Keys(paths)
+	slices.Sort(codes)
+
+	result := make([]*Profile, 0, len(codes))
+	for _, code := range codes {
+		p, err := LoadProfile(code, paths[code]...)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// Sizes returns the sorted ngram sizes found in the profile.
+func (p *Profile) Sizes() []int {
+	result := maps.Keys(p.totals)
+	slices.Sort(result)
+	return result
+}
+
+// Len returns the number of distinct ngrams in the profile.
+func (p *Profile) Len() int {
+	return len(p.ranked)
+}
+
+// languageName returns the name of the built-in language or the code if it is not a built-in language.
+func languageName(code alphabet.LanguageCode) string {
+	if lang, err := alphabet.BuiltinLanguages().Get(code); err == nil {
+		return lang.Name
+	}
+	return string(code)
+}
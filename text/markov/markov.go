@@ -0,0 +1,223 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package markov
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+)
+
+// Model is a Markov chain of letters or words where the next unit is chosen based on the previous
+// n-1 units (prefix) of the ngrams it was built from.
+type Model struct {
+	words       bool
+	transitions map[string][]choice
+	// starts are the prefixes weighted by how often they were observed and used to start a word or text
+	starts []choice
+}
+
+// choice is a unit (or prefix) with the cumulative weight of it and all the choices before it.
+type choice struct {
+	unit       string
+	cumulative float64
+}
+
+// NewModel creates a Markov model from the transition probabilities.
+func NewModel(tt *ngrams.TransitionTable) (*Model, error) {
+	m := &Model{
+		words:       tt.WordUnits(),
+		transitions: make(map[string][]choice),
+	}
+
+	var startTotal float64
+	for _, prefix := range tt.Prefixes() {
+		var count int64
+		var total float64
+		transitions := tt.Transitions(prefix)
+		choices := make([]choice, 0, len(transitions))
+		for _, tr := range transitions {
+			count += tr.Count
+			total += tr.Probability
+			choices = append(choices, choice{unit: tr.Next, cumulative: total})
+		}
+		m.transitions[prefix] = choices
+
+		startTotal += float64(count)
+		m.starts = append(m.starts, choice{unit: prefix, cumulative: startTotal})
+	}
+
+	if len(m.starts) == 0 {
+		return nil, fmt.Errorf("failed to create the markov model. no transitions")
+	}
+	return m, nil
+}
+
+// NewModelFromTable creates a Markov model from the ngrams (of size 2 or more) of the frequency table.
+// The options specify how the transition probabilities are derived. E.g. [ngrams.WithWordUnits] for word ngrams.
+func NewModelFromTable(ft *ngrams.FrequencyTable, opts ...ngrams.TransitionOption) (*Model, error) {
+	tt, err := ngrams.NewTransitionTable(ft, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewModel(tt)
+}
+
+// LoadModelFromFile creates a Markov model from the ngram frequency table saved at the path.
+// See [ngrams.LoadFrequenciesFromFile] and [NewModelFromTable].
+func LoadModelFromFile(path string, opts ...ngrams.TransitionOption) (*Model, error) {
+	ft, err := ngrams.LoadFrequenciesFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := NewModelFromTable(ft, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the markov model from %q. %w", path, err)
+	}
+	return m, nil
+}
+
+// WordUnits returns true if the model is a Markov chain of words instead of letters.
+func (m *Model) WordUnits() bool {
+	return m.words
+}
+
+// shift drops the first unit of the prefix and appends the next unit.
+func (m *Model) shift(prefix string, next string) string {
+	if m.words {
+		if i := strings.IndexByte(prefix, ' '); i >= 0 {
+			return prefix[i+1:] + " " + next
+		}
+		return next
+	}
+
+	_, size := utf8.DecodeRuneInString(prefix)
+	return prefix[size:] + next
+}
+
+//-----------------------------------------------------------------------------
+
+// GeneratorOption is used to configure the [Generator].
+type GeneratorOption func(g *Generator)
+
+// WithWordLength configures the number of letters (chosen at random between min and max) of the
+// words generated by a letter model. The default is 3 to 8 letters.
+func WithWordLength(minLength int, maxLength int) GeneratorOption {
+	return func(g *Generator) {
+		g.minLength = minLength
+		g.maxLength = maxLength
+	}
+}
+
+// Generator produces words and text from a Markov [Model].
+// The same seed produces the same output for the same model.
+type Generator struct {
+	model     *Model
+	rng       *rand.Rand
+	minLength int
+	maxLength int
+}
+
+// NewGenerator creates a new generator that uses a random number generator seeded with the seed.
+func NewGenerator(model *Model, seed int64, opts ...GeneratorOption) (*Generator, error) {
+	g := &Generator{
+		model:     model,
+		rng:       rand.New(rand.NewSource(seed)),
+		minLength: 3,
+		maxLength: 8,
+	}
+	for _, apply := range opts {
+		apply(g)
+	}
+
+	if g.minLength < 1 || g.maxLength < g.minLength {
+		return nil, fmt.Errorf("invalid word length %d-%d", g.minLength, g.maxLength)
+	}
+	return g, nil
+}
+
+// Word returns a pseudo-word of the given number of letters from a letter model. The word is shorter
+// when the chain reaches a prefix from which no transitions were observed.
+func (g *Generator) Word(length int) string {
+	var sb strings.Builder
+	prefix := g.pick(g.model.starts)
+	letters := 0
+	for _, r := range prefix {
+		if letters == length {
+			return sb.String()
+		}
+		sb.WriteRune(r)
+		letters++
+	}
+
+	for ; letters < length; letters++ {
+		choices, exists := g.model.transitions[prefix]
+		if !exists {
+			break
+		}
+		next := g.pick(choices)
+		sb.WriteString(next)
+		prefix = g.model.shift(prefix, next)
+	}
+	return sb.String()
+}
+
+// Words returns the given number of units separated by spaces. A letter model produces pseudo-words
+// (see [WithWordLength]) and a word model produces a sequence of words. The chain is restarted from a
+// random prefix when it reaches a prefix from which no transitions were observed.
+func (g *Generator) Words(count int) string {
+	if !g.model.words {
+		words := make([]string, 0, count)
+		for i := 0; i < count; i++ {
+			length := g.minLength + g.rng.Intn(g.maxLength-g.minLength+1)
+			words = append(words, g.Word(length))
+		}
+		return strings.Join(words, " ")
+	}
+
+	words := make([]string, 0, count)
+	var prefix string
+	for len(words) < count {
+		choices, exists := g.model.transitions[prefix]
+		if !exists {
+			prefix = g.pick(g.model.starts)
+			words = append(words, strings.Split(prefix, " ")...)
+			continue
+		}
+		next := g.pick(choices)
+		words = append(words, next)
+		prefix = g.model.shift(prefix, next)
+	}
+	return strings.Join(words[:count], " ")
+}
+
+// pick chooses one of the weighted choices at random.
+func (g *Generator) pick(choices []choice) string {
+	target := g.rng.Float64() * choices[len(choices)-1].cumulative
+	i := sort.Search(len(choices), func(i int) bool {
+		return choices[i].cumulative > target
+	})
+	return choices[min(i, len(choices)-1)].unit
+}
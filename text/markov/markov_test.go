@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package markov_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/markov"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratorWord(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("th", 1)
+	ft.Add("he", 1)
+
+	m, err := markov.NewModelFromTable(ft)
+	require.NoError(t, err)
+	assert.False(t, m.WordUnits())
+
+	g, err := markov.NewGenerator(m, 1)
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		word := g.Word(3)
+		assert.Contains(t, []string{"the", "he"}, word)
+	}
+	assert.Contains(t, []string{"t", "h"}, g.Word(1))
+}
+
+func TestGeneratorWords(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("the quick", 1)
+	ft.Add("quick fox", 1)
+
+	m, err := markov.NewModelFromTable(ft, ngrams.WithWordUnits())
+	require.NoError(t, err)
+	assert.True(t, m.WordUnits())
+
+	g, err := markov.NewGenerator(m, 42)
+	require.NoError(t, err)
+	text := g.Words(7)
+	assert.Len(t, strings.Split(text, " "), 7)
+	// The chain restarts from a random prefix after "fox"
+	for _, pair := range []string{"the fox", "quick the", "quick quick", "the the"} {
+		assert.NotContains(t, text, pair)
+	}
+}
+
+func TestGeneratorLetterModel(t *testing.T) {
+	ft, err := ngrams.LoadFrequenciesFromFile("../ngrams/testdata/freq-3-en-alice.csv")
+	require.NoError(t, err)
+
+	m, err := markov.LoadModelFromFile("../ngrams/testdata/freq-3-en-alice.csv")
+	require.NoError(t, err)
+
+	g, err := markov.NewGenerator(m, 7, markov.WithWordLength(4, 6))
+	require.NoError(t, err)
+	text := g.Words(50)
+
+	words := strings.Split(text, " ")
+	assert.Len(t, words, 50)
+	for _, word := range words {
+		assert.LessOrEqual(t, utf8.RuneCountInString(word), 6, word)
+		runes := []rune(word)
+		for i := 0; i+3 <= len(runes); i++ {
+			_, exists := ft.Get(string(runes[i : i+3]))
+			assert.True(t, exists, word)
+		}
+	}
+
+	// The same seed produces the same text
+	g, err = markov.NewGenerator(m, 7, markov.WithWordLength(4, 6))
+	require.NoError(t, err)
+	assert.Equal(t, text, g.Words(50))
+
+	g, err = markov.NewGenerator(m, 8, markov.WithWordLength(4, 6))
+	require.NoError(t, err)
+	assert.NotEqual(t, text, g.Words(50))
+}
+
+func TestGeneratorWordModel(t *testing.T) {
+	m, err := markov.LoadModelFromFile("../ngrams/testdata/freq-2w-en-alice.csv",
+		ngrams.WithWordUnits(), ngrams.WithKneserNey(ngrams.DefaultKneserNeyDiscount))
+	require.NoError(t, err)
+
+	g, err := markov.NewGenerator(m, 1)
+	require.NoError(t, err)
+	assert.Len(t, strings.Split(g.Words(100), " "), 100)
+}
+
+func TestModelErrors(t *testing.T) {
+	_, err := markov.NewModelFromTable(ngrams.NewFrequencyTable())
+	assert.ErrorContains(t, err, "no transitions")
+
+	_, err = markov.LoadModelFromFile("../ngrams/testdata/freq-1-en-alice.csv")
+	assert.ErrorContains(t, err, "failed to load the markov model from")
+
+	_, err = markov.LoadModelFromFile("missing.csv")
+	assert.Error(t, err)
+
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("th", 1)
+	m, err := markov.NewModelFromTable(ft)
+	require.NoError(t, err)
+	_, err = markov.NewGenerator(m, 1, markov.WithWordLength(5, 2))
+	assert.ErrorContains(t, err, "invalid word length 5-2")
+}
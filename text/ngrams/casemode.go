@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+)
+
+// CaseMode specifies how the case of letters and words are treated while parsing tokens.
+type CaseMode int
+
+const (
+	// CaseLower converts letters and words to lowercase using the default unicode rules.
+	CaseLower CaseMode = iota
+	// CasePreserve keeps the case of the letters and words as found in the input.
+	CasePreserve
+	// CaseUpper converts letters and words to uppercase using the case mapping rules of the language.
+	CaseUpper
+	// CaseFold converts letters and words to lowercase using the locale specific case mapping rules
+	// of the language (e.g. Turkish dotted and dotless i).
+	CaseFold
+)
+
+var caseModeNames = []string{"lower", "preserve", "upper", "fold"}
+
+// String returns the name of the case mode.
+func (c CaseMode) String() string {
+	if c < 0 || int(c) >= len(caseModeNames) {
+		return fmt.Sprintf("CaseMode(%d)", int(c))
+	}
+	return caseModeNames[c]
+}
+
+// ParseCaseMode returns the case mode for the given name (lower, preserve, upper or fold).
+func ParseCaseMode(name string) (CaseMode, error) {
+	for i, n := range caseModeNames {
+		if strings.EqualFold(n, name) {
+			return CaseMode(i), nil
+		}
+	}
+	return CaseLower, fmt.Errorf("invalid case mode %q", name)
+}
+
+// rune maps the rune to the case mode and reports if the rune is a letter of the language.
+func (c CaseMode) rune(language alphabet.Language, r rune) (rune, bool) {
+	switch c {
+	case CasePreserve:
+		return r, language.IsLetter(r)
+	case CaseUpper:
+		return language.ToUpper(r), language.IsLetter(r)
+	case CaseFold:
+		r = language.ToLower(r)
+		return r, language.ContainsRune(r)
+	default:
+		r = unicode.ToLower(r)
+		return r, language.ContainsRune(r)
+	}
+}
+
+// string maps the string to the case mode.
+func (c CaseMode) string(language alphabet.Language, s string) string {
+	switch c {
+	case CasePreserve:
+		return s
+	case CaseUpper:
+		return language.ToUpperString(s)
+	case CaseFold:
+		return language.ToLowerString(s)
+	default:
+		return strings.ToLower(s)
+	}
+}
+
+// stringFunc returns the function that maps strings to the case mode. This avoids passing the language
+// around for every word being mapped.
+func (c CaseMode) stringFunc(language alphabet.Language) func(s string) string {
+	switch c {
+	case CasePreserve:
+		return func(s string) string { return s }
+	case CaseUpper:
+		return language.ToUpperString
+	case CaseFold:
+		return language.ToLowerString
+	default:
+		return strings.ToLower
+	}
+}
+
+// lower maps the string to lowercase in the way the alphabet of the language is compared.
+func (c CaseMode) lower(language alphabet.Language, s string) string {
+	if c == CaseLower {
+		return strings.ToLower(s)
+	}
+	return language.ToLowerString(s)
+}
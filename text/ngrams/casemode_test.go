@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCaseMode(t *testing.T) {
+	for _, mode := range []ngrams.CaseMode{ngrams.CaseLower, ngrams.CasePreserve, ngrams.CaseUpper, ngrams.CaseFold} {
+		parsed, err := ngrams.ParseCaseMode(mode.String())
+		require.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+
+	parsed, err := ngrams.ParseCaseMode("UPPER")
+	require.NoError(t, err)
+	assert.Equal(t, ngrams.CaseUpper, parsed)
+
+	_, err = ngrams.ParseCaseMode("title")
+	assert.ErrorContains(t, err, "invalid case mode \"title\"")
+	assert.Equal(t, "CaseMode(42)", ngrams.CaseMode(42).String())
+}
+
+func TestParseTokensWithCase(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	tr := alphabet.Language{Name: "Turkish", Code: "tr", Letters: "abcçdefgğhıijklmnoöprsştuüvyz"}
+
+	testCases := []struct {
+		desc      string
+		input     string
+		language  alphabet.Language
+		caseMode  ngrams.CaseMode
+		words     bool
+		graphemes bool
+		tokenSize int
+		expected  []string
+	}{
+		{desc: "letters lower", input: "Hi Bob", language: en, caseMode: ngrams.CaseLower, tokenSize: 1,
+			expected: []string{"h", "i", "b", "o", "b"}},
+		{desc: "letters preserve", input: "Hi Bob", language: en, caseMode: ngrams.CasePreserve, tokenSize: 2,
+			expected: []string{"Hi", "Bo", "ob"}},
+		{desc: "letters upper", input: "Hi Bob", language: en, caseMode: ngrams.CaseUpper, tokenSize: 1,
+			expected: []string{"H", "I", "B", "O", "B"}},
+		{desc: "graphemes preserve", input: "Hi Bob", language: en, caseMode: ngrams.CasePreserve, tokenSize: 2,
+			graphemes: true, expected: []string{"Hi", "Bo", "ob"}},
+		{desc: "words preserve", input: "Hi Bob", language: en, caseMode: ngrams.CasePreserve, tokenSize: 1,
+			words: true, expected: []string{"Hi", "Bob"}},
+		{desc: "words upper", input: "Hi Bob", language: en, caseMode: ngrams.CaseUpper, tokenSize: 2,
+			words: true, expected: []string{"HI BOB"}},
+
+		// Turkish has a dotted and dotless i
+		{desc: "turkish lower", input: "KIŞ İyi", language: tr, caseMode: ngrams.CaseLower, tokenSize: 1,
+			words: true, expected: []string{"kiş", "iyi"}},
+		{desc: "turkish fold", input: "KIŞ İyi", language: tr, caseMode: ngrams.CaseFold, tokenSize: 1,
+			words: true, expected: []string{"kış", "iyi"}},
+		{desc: "turkish fold letters", input: "KIŞ", language: tr, caseMode: ngrams.CaseFold, tokenSize: 1,
+			expected: []string{"k", "ı", "ş"}},
+		{desc: "turkish upper letters", input: "kiş", language: tr, caseMode: ngrams.CaseUpper, tokenSize: 1,
+			expected: []string{"K", "İ", "Ş"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			recv := func(token string, err error) error {
+				require.NoError(t, err)
+				result = append(result, token)
+				return nil
+			}
+
+			opts := []ngrams.ParseOption{ngrams.WithCase(tC.caseMode)}
+			if tC.graphemes {
+				opts = append(opts, ngrams.WithGraphemes())
+			}
+
+			var err error
+			if tC.words {
+				err = ngrams.ParseWordTokens(context.Background(), strings.NewReader(tC.input), tC.language,
+					tC.tokenSize, recv, opts...)
+			} else {
+				err = ngrams.ParseLetterTokens(context.Background(), strings.NewReader(tC.input), tC.language,
+					tC.tokenSize, recv, opts...)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
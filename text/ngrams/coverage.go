@@ -0,0 +1,263 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+)
+
+// RuneCount is the number of times a rune was found.
+type RuneCount struct {
+	Rune  rune
+	Count int64
+}
+
+// CoverageStats are the number of non-whitespace runes read from an input source that are part of the
+// alphabet of the language (accepted) or not (rejected).
+type CoverageStats struct {
+	// Source is the input source or empty for the totals of all the input sources.
+	Source   string
+	Accepted int64
+	Rejected int64
+	rejected map[rune]int64
+}
+
+func newCoverageStats(source string) *CoverageStats {
+	return &CoverageStats{
+		Source:   source,
+		rejected: make(map[rune]int64),
+	}
+}
+
+// Total returns the number of non-whitespace runes read.
+func (s CoverageStats) Total() int64 {
+	return s.Accepted + s.Rejected
+}
+
+// Ratio returns the ratio (between 0 and 1) of the runes that are part of the alphabet.
+// A ratio of 1 is returned when no runes have been read.
+func (s CoverageStats) Ratio() float64 {
+	if s.Total() == 0 {
+		return 1
+	}
+	return float64(s.Accepted) / float64(s.Total())
+}
+
+// TopRejected returns the n most frequently rejected runes. All the rejected runes are returned if n is less than 1.
+func (s CoverageStats) TopRejected(n int) []RuneCount {
+	result := make([]RuneCount, 0, len(s.rejected))
+	for r, count := range s.rejected {
+		result = append(result, RuneCount{Rune: r, Count: count})
+	}
+	slices.SortFunc(result, func(a, b RuneCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Rune, b.Rune)
+	})
+	if n > 0 && n < len(result) {
+		result = result[:n]
+	}
+	return result
+}
+
+func (s *CoverageStats) clone() CoverageStats {
+	result := *s
+	result.rejected = make(map[rune]int64, len(s.rejected))
+	for r, count := range s.rejected {
+		result.rejected[r] = count
+	}
+	return result
+}
+
+// Coverage collects the alphabet coverage statistics of each input source processed and of all of them combined.
+// Use [WithCoverage] to collect the statistics while a [FrequencyProcessor] or [TokenStreamer] processes the input.
+// The statistics of an input source are added once the whole source has been processed.
+type Coverage struct {
+	mu      sync.Mutex
+	sources []*CoverageStats
+	total   *CoverageStats
+}
+
+// NewCoverage creates a new empty collection of coverage statistics.
+func NewCoverage() *Coverage {
+	return &Coverage{
+		total: newCoverageStats(""),
+	}
+}
+
+// Sources returns the statistics of each input source in the order they were processed.
+func (c *Coverage) Sources() []CoverageStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]CoverageStats, 0, len(c.sources))
+	for _, s := range c.sources {
+		result = append(result, s.clone())
+	}
+	return result
+}
+
+// Total returns the combined statistics of all the input sources.
+func (c *Coverage) Total() CoverageStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total.clone()
+}
+
+// WriteReport writes a human readable report of the statistics of each input source followed by the totals.
+// The top most frequently rejected runes are listed for each.
+func (c *Coverage) WriteReport(w io.Writer, top int) error {
+	for _, s := range c.Sources() {
+		if err := writeCoverageStats(w, s.Source, s, top); err != nil {
+			return err
+		}
+	}
+	return writeCoverageStats(w, "Total", c.Total(), top)
+}
+
+func writeCoverageStats(w io.Writer, name string, s CoverageStats, top int) error {
+	_, err := fmt.Fprintf(w, "%s: %.2f%% accepted (%d accepted, %d rejected)\n", name, s.Ratio()*100, s.Accepted, s.Rejected)
+	if err != nil {
+		return err
+	}
+	for _, rc := range s.TopRejected(top) {
+		if _, err := fmt.Fprintf(w, "  %q %U %d\n", rc.Rune, rc.Rune, rc.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merge adds the statistics counted while processing the input source to those of the source and the totals.
+func (c *Coverage) merge(source *CoverageStats, counted *CoverageStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range []*CoverageStats{source, c.total} {
+		s.Accepted += counted.Accepted
+		s.Rejected += counted.Rejected
+		for r, count := range counted.rejected {
+			s.rejected[r] += count
+		}
+	}
+}
+
+// newSource starts the statistics of a new input source.
+func (c *Coverage) newSource(source string) *CoverageStats {
+	s := newCoverageStats(source)
+	c.mu.Lock()
+	c.sources = append(c.sources, s)
+	c.mu.Unlock()
+	return s
+}
+
+//-----------------------------------------------------------------------------
+
+// coverageTokenizer records the alphabet coverage of the runes before passing them on to the tokenizer.
+// An input source is processed by a single goroutine and thus the runes are counted without locking and only
+// merged into the [Coverage] when the input ends.
+type coverageTokenizer struct {
+	Tokenizer
+	coverage *Coverage
+	stats    *CoverageStats
+	counted  *CoverageStats
+	language alphabet.Language
+	caseMode CaseMode
+	// Cache of the runes already seen to avoid mapping the case of every rune
+	ascii    [utf8.RuneSelf]coverageState
+	accepted map[rune]bool
+}
+
+// coverageState is whether an ASCII rune has been seen and is part of the language.
+type coverageState uint8
+
+const (
+	coverageUnknown coverageState = iota
+	coverageAccepted
+	coverageRejected
+)
+
+// withCoverage wraps the tokenizer to record the alphabet coverage of the input source if [WithCoverage]
+// was used.
+func (opt parseOptions) withCoverage(tok Tokenizer, language alphabet.Language, source string) Tokenizer {
+	if opt.coverage == nil {
+		return tok
+	}
+	return &coverageTokenizer{
+		Tokenizer: tok,
+		coverage:  opt.coverage,
+		stats:     opt.coverage.newSource(source),
+		counted:   newCoverageStats(source),
+		language:  language,
+		caseMode:  opt.caseMode,
+		accepted:  make(map[rune]bool),
+	}
+}
+
+func (t *coverageTokenizer) Next(r rune, emit EmitFunc) error {
+	if !isSpace(r) {
+		if t.isAccepted(r) {
+			t.counted.Accepted++
+		} else {
+			t.counted.Rejected++
+			t.counted.rejected[r]++
+		}
+	}
+	return t.Tokenizer.Next(r, emit)
+}
+
+func (t *coverageTokenizer) End(emit EmitFunc) error {
+	t.coverage.merge(t.stats, t.counted)
+	t.counted = newCoverageStats(t.counted.Source)
+	return t.Tokenizer.End(emit)
+}
+
+// isAccepted returns true if the rune is part of the alphabet of the language.
+func (t *coverageTokenizer) isAccepted(r rune) bool {
+	if r >= 0 && r < utf8.RuneSelf {
+		switch t.ascii[r] {
+		case coverageAccepted:
+			return true
+		case coverageRejected:
+			return false
+		}
+		_, ok := t.caseMode.rune(t.language, r)
+		t.ascii[r] = coverageRejected
+		if ok {
+			t.ascii[r] = coverageAccepted
+		}
+		return ok
+	}
+
+	ok, exists := t.accepted[r]
+	if !exists {
+		_, ok = t.caseMode.rune(t.language, r)
+		t.accepted[r] = ok
+	}
+	return ok
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverageStream(t *testing.T) {
+	coverage := ngrams.NewCoverage()
+	s := ngrams.NewTokenStreamer(alphabet.MustBuiltin("en"), []ngrams.ProcessorConfig{{Mode: ngrams.ProcessLetters, Sizes: []int{1}}},
+		ngrams.WithCoverage(coverage))
+
+	noop := func(token ngrams.StreamedToken) error { return nil }
+	require.NoError(t, s.Stream(context.Background(), strings.NewReader("The дом é\nдa"), "a", noop))
+	require.NoError(t, s.Stream(context.Background(), strings.NewReader("ok!"), "b", noop))
+
+	sources := coverage.Sources()
+	require.Len(t, sources, 2)
+
+	a := sources[0]
+	assert.Equal(t, "a", a.Source)
+	assert.Equal(t, int64(4), a.Accepted)
+	assert.Equal(t, int64(5), a.Rejected)
+	assert.Equal(t, int64(9), a.Total())
+	assert.InDelta(t, 4.0/9.0, a.Ratio(), 1e-9)
+	assert.Equal(t, []ngrams.RuneCount{{Rune: 'д', Count: 2}, {Rune: 'é', Count: 1}}, a.TopRejected(2))
+	assert.Len(t, a.TopRejected(0), 4)
+
+	total := coverage.Total()
+	assert.Equal(t, "", total.Source)
+	assert.Equal(t, int64(6), total.Accepted)
+	assert.Equal(t, int64(6), total.Rejected)
+	assert.Equal(t, []ngrams.RuneCount{{Rune: 'д', Count: 2}}, total.TopRejected(1))
+
+	var buf bytes.Buffer
+	require.NoError(t, coverage.WriteReport(&buf, 1))
+	assert.Equal(t, `a: 44.44% accepted (4 accepted, 5 rejected)
+  'д' U+0434 2
+b: 66.67% accepted (2 accepted, 1 rejected)
+  '!' U+0021 1
+Total: 50.00% accepted (6 accepted, 6 rejected)
+  'д' U+0434 2
+`, buf.String())
+}
+
+func TestCoverageProcessFiles(t *testing.T) {
+	coverage := ngrams.NewCoverage()
+	p := ngrams.NewFrequencyProcessorWithConfigs(alphabet.MustBuiltin("af"), []ngrams.ProcessorConfig{
+		{Mode: ngrams.ProcessLetters, Sizes: []int{1}},
+		{Mode: ngrams.ProcessWords, Sizes: []int{1}},
+	}, ngrams.WithCoverage(coverage))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/af-control.txt"}))
+
+	sources := coverage.Sources()
+	require.Len(t, sources, 1)
+	assert.Equal(t, "testdata/af-control.txt", sources[0].Source)
+
+	// Every rune is only counted once regardless of the number of tokenizers
+	var letters int64
+	for _, freq := range p.FrequencyTableFor(ngrams.ProcessLetters, 1).Entries() {
+		letters += freq.Count
+	}
+	assert.Equal(t, letters, sources[0].Accepted)
+	assert.Positive(t, sources[0].Rejected)
+	assert.Equal(t, sources[0].Accepted, coverage.Total().Accepted)
+	assert.Equal(t, sources[0].Rejected, coverage.Total().Rejected)
+}
+
+func TestCoverageEmpty(t *testing.T) {
+	coverage := ngrams.NewCoverage()
+	assert.Empty(t, coverage.Sources())
+	assert.Equal(t, 1.0, coverage.Total().Ratio())
+	assert.Empty(t, coverage.Total().TopRejected(5))
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Placeholders used by the built-in rewrite rules.
+const (
+	PlaceholderNumber = "<NUM>"
+	PlaceholderURL    = "<URL>"
+	PlaceholderEmail  = "<EMAIL>"
+)
+
+// Regular expressions used by the built-in rewrite rules.
+const (
+	PatternNumber = `\d+(?:[.,]\d+)*`
+	PatternURL    = `(?i)\b(?:https?://|www\.)\S+`
+	PatternEmail  = `(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b`
+)
+
+// TokenFilter is a pipeline of rules that rewrite or drop ngram tokens before they are added to a frequency table.
+// The rules are applied in the order they were added and the pipeline stops as soon as a token is dropped.
+// A TokenFilter can be used while parsing tokens (see [WithFilter]) or on an existing table (see [TokenFilter.ApplyToTable]).
+type TokenFilter struct {
+	rules []filterRule
+}
+
+type filterKind int
+
+const (
+	filterDrop filterKind = iota
+	filterRewrite
+	filterMinLength
+	filterMaxLength
+)
+
+type filterRule struct {
+	kind        filterKind
+	pattern     *regexp.Regexp
+	replacement string
+	length      int
+}
+
+// NewTokenFilter creates a new [TokenFilter] without any rules.
+func NewTokenFilter() *TokenFilter {
+	return &TokenFilter{}
+}
+
+// Len returns the number of rules in the pipeline.
+func (f *TokenFilter) Len() int {
+	return len(f.rules)
+}
+
+// Append adds the rules of the other filter to the end of the pipeline.
+func (f *TokenFilter) Append(other *TokenFilter) {
+	f.rules = append(f.rules, other.rules...)
+}
+
+// Drop adds a rule that drops the tokens matching the regular expression.
+func (f *TokenFilter) Drop(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid drop pattern %q. %w", pattern, err)
+	}
+	f.rules = append(f.rules, filterRule{kind: filterDrop, pattern: re})
+	return nil
+}
+
+// Rewrite adds a rule that replaces the matches of the regular expression with the replacement.
+// The replacement can refer to the submatches using $1 etc. See [regexp.Regexp.ReplaceAllString].
+func (f *TokenFilter) Rewrite(pattern string, replacement string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid rewrite pattern %q. %w", pattern, err)
+	}
+	f.rules = append(f.rules, filterRule{kind: filterRewrite, pattern: re, replacement: replacement})
+	return nil
+}
+
+// RewriteNumbers adds a rule that replaces numbers with the [PlaceholderNumber].
+func (f *TokenFilter) RewriteNumbers() {
+	_ = f.Rewrite(PatternNumber, PlaceholderNumber)
+}
+
+// RewriteURLs adds a rule that replaces URLs with the [PlaceholderURL].
+func (f *TokenFilter) RewriteURLs() {
+	_ = f.Rewrite(PatternURL, PlaceholderURL)
+}
+
+// RewriteEmails adds a rule that replaces email addresses with the [PlaceholderEmail].
+func (f *TokenFilter) RewriteEmails() {
+	_ = f.Rewrite(PatternEmail, PlaceholderEmail)
+}
+
+// MinLength adds a rule that drops the tokens that have less than length runes.
+func (f *TokenFilter) MinLength(length int) {
+	f.rules = append(f.rules, filterRule{kind: filterMinLength, length: length})
+}
+
+// MaxLength adds a rule that drops the tokens that have more than length runes.
+func (f *TokenFilter) MaxLength(length int) {
+	f.rules = append(f.rules, filterRule{kind: filterMaxLength, length: length})
+}
+
+// Apply runs the token through the pipeline and returns the (possibly rewritten) token and
+// false if the token should be dropped.
+func (f *TokenFilter) Apply(token string) (string, bool) {
+	for _, rule := range f.rules {
+		switch rule.kind {
+		case filterDrop:
+			if rule.pattern.MatchString(token) {
+				return "", false
+			}
+		case filterRewrite:
+			token = rule.pattern.ReplaceAllString(token, rule.replacement)
+		case filterMinLength:
+			if utf8.RuneCountInString(token) < rule.length {
+				return "", false
+			}
+		case filterMaxLength:
+			if utf8.RuneCountInString(token) > rule.length {
+				return "", false
+			}
+		}
+	}
+	return token, token != ""
+}
+
+// ApplyToTable runs each token of the frequency table through the pipeline and returns a new frequency table.
+// The counts of the tokens that are rewritten to the same token are combined and the metadata is copied.
+func (f *TokenFilter) ApplyToTable(ft *FrequencyTable) *FrequencyTable {
+	result := NewFrequencyTable()
+	for _, key := range ft.MetadataKeys() {
+		value, _ := ft.Metadata(key)
+		result.SetMetadata(key, value)
+	}
+
+	for _, freq := range ft.Entries() {
+		if token, keep := f.Apply(freq.Token); keep {
+			result.addCount(token, uint64(freq.Count))
+		}
+	}
+
+	result.Update()
+	return result
+}
+
+// LoadTokenFilter parses the rules of a token filter from an io.Reader.
+//
+// Expected format in UTF-8: one rule per line.
+//
+//	drop <regex>                   drop the tokens matching the regular expression
+//	rewrite <replacement> <regex>  replace the matches of the regular expression
+//	min <length>                   drop the tokens with less than length runes
+//	max <length>                   drop the tokens with more than length runes
+//	number | url | email           replace numbers, URLs or email addresses with <NUM>, <URL> or <EMAIL>
+//
+// Lines starting with a # is ignored.
+func LoadTokenFilter(r io.Reader) (*TokenFilter, error) {
+	f := NewTokenFilter()
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if err := f.parseRule(line); err != nil {
+			return nil, fmt.Errorf("failed to parse rule on line %d. %w", lineNo, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse the token filter rules. %w", err)
+	}
+	return f, nil
+}
+
+// LoadTokenFilterFromFile parses the rules of a token filter from a UTF-8 encoded text file.
+// See [LoadTokenFilter] for more details.
+func LoadTokenFilterFromFile(path string) (*TokenFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q. %w", path, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to close %q. %v", path, err)
+		}
+	}()
+
+	result, err := LoadTokenFilter(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the token filter from %q. %w", path, err)
+	}
+	return result, nil
+}
+
+func (f *TokenFilter) parseRule(line string) error {
+	name, args, _ := strings.Cut(line, " ")
+	args = strings.TrimSpace(args)
+
+	switch name {
+	case "drop":
+		if args == "" {
+			return fmt.Errorf("expected a regular expression")
+		}
+		return f.Drop(args)
+	case "rewrite":
+		replacement, pattern, _ := strings.Cut(args, " ")
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return fmt.Errorf("expected a replacement and a regular expression")
+		}
+		return f.Rewrite(pattern, replacement)
+	case "min", "max":
+		length, err := strconv.Atoi(args)
+		if err != nil || length < 0 {
+			return fmt.Errorf("invalid length %q", args)
+		}
+		if name == "min" {
+			f.MinLength(length)
+		} else {
+			f.MaxLength(length)
+		}
+	case "number":
+		f.RewriteNumbers()
+	case "url":
+		f.RewriteURLs()
+	case "email":
+		f.RewriteEmails()
+	default:
+		return fmt.Errorf("unknown rule %q", name)
+	}
+	return nil
+}
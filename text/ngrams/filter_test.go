@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFilterApply(t *testing.T) {
+	f := ngrams.NewTokenFilter()
+	f.RewriteEmails()
+	f.RewriteURLs()
+	f.RewriteNumbers()
+	require.NoError(t, f.Drop(`^\* \*$`))
+	require.NoError(t, f.Rewrite(`(\w+)'s`, "$1"))
+	f.MinLength(2)
+	f.MaxLength(12)
+	assert.Equal(t, 7, f.Len())
+
+	testCases := []struct {
+		token    string
+		expected string
+		keep     bool
+	}{
+		{token: "the king", expected: "the king", keep: true},
+		{token: "in 1865", expected: "in <NUM>", keep: true},
+		{token: "3.14,", expected: "<NUM>,", keep: true},
+		{token: "https://example.com/a?b=1", expected: "<URL>", keep: true},
+		{token: "see www.example.com", expected: "see <URL>", keep: true},
+		{token: "alice@example.com", expected: "<EMAIL>", keep: true},
+		{token: "* *", keep: false},
+		{token: "alice's", expected: "alice", keep: true},
+		{token: "a", keep: false},
+		{token: "the white rabbit", keep: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.token, func(t *testing.T) {
+			token, keep := f.Apply(tC.token)
+			assert.Equal(t, tC.keep, keep)
+			if tC.keep {
+				assert.Equal(t, tC.expected, token)
+			}
+		})
+	}
+}
+
+func TestLoadTokenFilter(t *testing.T) {
+	f, err := ngrams.LoadTokenFilter(strings.NewReader(`# rules
+email
+url
+rewrite <YEAR> \b1[89]\d\d\b
+number
+drop ^\* \*$
+min 2
+max 40
+`))
+	require.NoError(t, err)
+	assert.Equal(t, 7, f.Len())
+
+	token, keep := f.Apply("in 1865 and 2 mails to a@b.com")
+	assert.True(t, keep)
+	assert.Equal(t, "in <YEAR> and <NUM> mails to <EMAIL>", token)
+
+	_, keep = f.Apply("* *")
+	assert.False(t, keep)
+
+	testCases := []struct {
+		rules  string
+		errMsg string
+	}{
+		{rules: "drop", errMsg: "line 1. expected a regular expression"},
+		{rules: "# comment\nrewrite <X>", errMsg: "line 2. expected a replacement and a regular expression"},
+		{rules: "drop (", errMsg: "invalid drop pattern \"(\""},
+		{rules: "min a", errMsg: "invalid length \"a\""},
+		{rules: "max -1", errMsg: "invalid length \"-1\""},
+		{rules: "keep x", errMsg: "unknown rule \"keep\""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.rules, func(t *testing.T) {
+			_, err := ngrams.LoadTokenFilter(strings.NewReader(tC.rules))
+			assert.ErrorContains(t, err, tC.errMsg)
+		})
+	}
+}
+
+func TestTokenFilterApplyToTable(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("in 1865", 2)
+	ft.Add("in 1871", 3)
+	ft.Add("* *", 10)
+	ft.Add("the king", 5)
+	ft.SetMetadata(ngrams.MetadataSkip, "1")
+	ft.Update()
+
+	f := ngrams.NewTokenFilter()
+	f.RewriteNumbers()
+	require.NoError(t, f.Drop(`^\* \*$`))
+
+	result := f.ApplyToTable(ft)
+	assert.Equal(t, 2, result.Len())
+
+	freq, exists := result.Get("in <NUM>")
+	require.True(t, exists)
+	assert.Equal(t, int64(5), freq.Count)
+	assert.InDelta(t, 0.5, freq.Percentage, 0.0001)
+
+	skip, exists := result.Metadata(ngrams.MetadataSkip)
+	assert.True(t, exists)
+	assert.Equal(t, "1", skip)
+
+	// The original table is not modified
+	assert.Equal(t, 4, ft.Len())
+}
+
+func TestParseWordTokensWithFilter(t *testing.T) {
+	f := ngrams.NewTokenFilter()
+	f.RewriteNumbers()
+	f.MinLength(3)
+
+	result := make([]string, 0)
+	err := ngrams.ParseWordTokens(context.Background(), strings.NewReader("In 1865 a 12 b"), alphabet.MustBuiltin("en"), 1,
+		func(token string, err error) error {
+			require.NoError(t, err)
+			result = append(result, token)
+			return nil
+		}, ngrams.WithFilter(f))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"<NUM>", "<NUM>"}, result)
+}
+
+func TestProcessorWithFilter(t *testing.T) {
+	f := ngrams.NewTokenFilter()
+	require.NoError(t, f.Drop(`^the `))
+
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 2, ngrams.WithFilter(f))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	ft := p.FrequencyTable()
+	assert.Greater(t, ft.Len(), 0)
+	for _, token := range ft.Tokens() {
+		assert.False(t, strings.HasPrefix(token, "the "), token)
+	}
+}
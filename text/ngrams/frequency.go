@@ -31,24 +31,32 @@ import (
 	"sync"
 
 	"github.com/andrejacobs/go-analyse/text/alphabet"
-	"github.com/andrejacobs/go-collection/collection"
 	"golang.org/x/exp/maps"
 )
 
 type FrequencyTable struct {
-	frequencies tokenFrequencyMap
-	mu          sync.RWMutex
+	store tokenStore
+	// total is the count the percentages are relative to. It is reset when the counts change so that stale
+	// percentages are never returned. See [FrequencyTable.Update].
+	total    uint64
+	metadata map[string]string
+	mu       sync.RWMutex
+	spill    *tableSpill
 }
 
+// metadataPrefix is used to identify the rows in the CSV that contain metadata (#meta,key,value).
+const metadataPrefix = "#meta"
+
 // LoadFrequencies parses a frequency table from an io.Reader.
 //
 // Expected CSV format in UTF-8: token,count,percentage
-// Lines starting with a # is ignored.
+// Lines starting with a # is ignored, except for metadata lines in the format: #meta,key,value.
+// The percentage column is optional and is not used since the percentages are derived from the counts
+// (see [FrequencyTable.Update]). This ensures that saving and loading a table is lossless.
 func LoadFrequencies(r io.Reader) (*FrequencyTable, error) {
-	result := &FrequencyTable{
-		frequencies: make(tokenFrequencyMap),
-	}
+	result := NewFrequencyTable()
 	csvR := csv.NewReader(r)
+	csvR.FieldsPerRecord = -1
 
 	for {
 		record, err := csvR.Read()
@@ -59,7 +67,14 @@ func LoadFrequencies(r io.Reader) (*FrequencyTable, error) {
 			return nil, fmt.Errorf("failed to parse csv. %w", err)
 		}
 
-		if len(record) < 3 {
+		if len(record) < 2 {
+			continue
+		}
+
+		if record[0] == metadataPrefix {
+			if len(record) > 2 {
+				result.metadata[record[1]] = record[2]
+			}
 			continue
 		}
 
@@ -69,25 +84,21 @@ func LoadFrequencies(r io.Reader) (*FrequencyTable, error) {
 
 		token := record[0]
 
-		count, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		count, err := strconv.ParseUint(strings.TrimSpace(record[1]), 10, 64)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse the count field from the csv. %v. %w", record, err)
 		}
 
-		percentage, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 32)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse the percentage field from the csv. %v. %w", record, err)
-		}
-
-		freq := Frequency{
-			Token:      token,
-			Count:      count,
-			Percentage: float32(percentage),
+		if len(record) > 2 && strings.TrimSpace(record[2]) != "" {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64); err != nil {
+				return nil, fmt.Errorf("failed to parse the percentage field from the csv. %v. %w", record, err)
+			}
 		}
 
-		result.frequencies[token] = freq
+		result.store.set(token, count)
 	}
 
+	result.Update()
 	return result, nil
 }
 
@@ -115,7 +126,8 @@ func LoadFrequenciesFromFile(path string) (*FrequencyTable, error) {
 // NewFrequencyTable creates a new [FrequencyTable].
 func NewFrequencyTable() *FrequencyTable {
 	return &FrequencyTable{
-		frequencies: make(tokenFrequencyMap),
+		store:    newTokenStore(),
+		metadata: make(map[string]string),
 	}
 }
 
@@ -123,27 +135,32 @@ func NewFrequencyTable() *FrequencyTable {
 func (ft *FrequencyTable) Len() int {
 	ft.mu.RLock()
 	defer ft.mu.RUnlock()
-	return len(ft.frequencies)
+	return ft.store.len()
 }
 
-// Entries returns the token frequencies in the table.
+// Entries returns the token frequencies in the table, including the entries that have spilled to disk.
 // NOTE: The order can not be guaranteed since the underlying data structure uses a map.
+// If the spilled entries could not be read then the error is returned by [FrequencyTable.Save].
 func (ft *FrequencyTable) Entries() []Frequency {
+	if ft.Spilled() {
+		result, _ := ft.spilledEntries()
+		return result
+	}
+
 	ft.mu.RLock()
 	defer ft.mu.RUnlock()
-	return maps.Values(ft.frequencies)
+
+	result := make([]Frequency, 0, ft.store.len())
+	ft.store.each(func(token string, count uint64) {
+		result = append(result, ft.frequency(token, count))
+	})
+	return result
 }
 
 // EntriesSortedByCount returns the token frequencies in the table sorted by the count (descending) going from
 // the token that appears the most to the least (highest to lowest frequency).
 func (ft *FrequencyTable) EntriesSortedByCount() []Frequency {
-	ft.mu.RLock()
-	kvs := collection.MapSortedByValueFunc(ft.frequencies, func(l, r Frequency) bool {
-		return l.Count > r.Count
-	})
-	ft.mu.RUnlock()
-
-	values := collection.JustValues(kvs)
+	values := ft.Entries()
 	slices.SortFunc(values, func(a Frequency, b Frequency) int {
 		if a.Count > b.Count {
 			return -1
@@ -157,12 +174,26 @@ func (ft *FrequencyTable) EntriesSortedByCount() []Frequency {
 	return values
 }
 
-// Tokens returns the unique tokens present in the table.
+// Tokens returns the unique tokens present in the table, including the tokens that have spilled to disk.
 // NOTE: The order can not be guaranteed since the underlying data structure uses a map.
 func (ft *FrequencyTable) Tokens() []string {
+	if ft.Spilled() {
+		entries, _ := ft.spilledEntries()
+		result := make([]string, 0, len(entries))
+		for _, freq := range entries {
+			result = append(result, freq.Token)
+		}
+		return result
+	}
+
 	ft.mu.RLock()
 	defer ft.mu.RUnlock()
-	return maps.Keys(ft.frequencies)
+
+	result := make([]string, 0, ft.store.len())
+	ft.store.each(func(token string, _ uint64) {
+		result = append(result, token)
+	})
+	return result
 }
 
 // Get returns the frequency information for the given token.
@@ -170,36 +201,89 @@ func (ft *FrequencyTable) Tokens() []string {
 func (ft *FrequencyTable) Get(token string) (Frequency, bool) {
 	ft.mu.RLock()
 	defer ft.mu.RUnlock()
-	result, exists := ft.frequencies[token]
-	return result, exists
+	count, exists := ft.store.get(token)
+	if !exists {
+		return Frequency{}, false
+	}
+	return ft.frequency(token, count), true
+}
+
+// frequency returns the [Frequency] of the token. The caller must hold the lock.
+func (ft *FrequencyTable) frequency(token string, count uint64) Frequency {
+	result := Frequency{Token: token, Count: int64(count)}
+	if ft.total > 0 {
+		result.Percentage = float64(count) / float64(ft.total)
+	}
+	return result
 }
 
 // Add a token with the given frequency count.
 // If the token has already been added then it's count will be incremented.
+// Negative counts are ignored since the counts are stored unsigned.
+// The percentages are 0 until [FrequencyTable.Update] is called.
 func (ft *FrequencyTable) Add(token string, count int) {
+	if count < 0 {
+		return
+	}
+	ft.addCount(token, uint64(count))
+}
+
+// addCount adds the count of the token. See [FrequencyTable.Add].
+func (ft *FrequencyTable) addCount(token string, count uint64) {
 	ft.mu.Lock()
 	defer ft.mu.Unlock()
 
-	freq, exists := ft.frequencies[token]
-	if !exists {
-		ft.frequencies[token] = Frequency{Token: token, Count: count}
-	} else {
-		freq.Count += count
-		ft.frequencies[token] = freq
+	ft.total = 0
+
+	if isNew := ft.store.add(token, count); isNew && ft.spill != nil {
+		ft.spill.used += spillEntrySize(token)
+		ft.spillIfNeeded()
 	}
 }
 
+// Metadata returns the value of the metadata key and whether it exists.
+// Metadata is used to describe how the table was created (e.g. the skip used for skip-grams).
+func (ft *FrequencyTable) Metadata(key string) (string, bool) {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	value, exists := ft.metadata[key]
+	return value, exists
+}
+
+// MetadataKeys returns the sorted metadata keys.
+func (ft *FrequencyTable) MetadataKeys() []string {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	keys := maps.Keys(ft.metadata)
+	slices.Sort(keys)
+	return keys
+}
+
+// SetMetadata sets the value of the metadata key.
+func (ft *FrequencyTable) SetMetadata(key string, value string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.metadata[key] = value
+}
+
 // Save the frequency table to the io.Writer in the same CSV format used by the Load functions.
+// If the table has spilled to disk then the runs are merged first. See [FrequencyTable.SetMemoryBudget].
 func (ft *FrequencyTable) Save(w io.Writer) error {
+	if ft.Spilled() {
+		return ft.saveSpilled(w, nil)
+	}
+
 	csvW := csv.NewWriter(w)
-	err := csvW.Write([]string{"#token", "count", "percentage"})
+	ft.mu.RLock()
+	err := writeFrequencyHeader(csvW, ft.metadata)
+	ft.mu.RUnlock()
 	if err != nil {
-		return fmt.Errorf("failed to write the csv header. %w", err)
+		return err
 	}
 
 	freqs := ft.EntriesSortedByCount()
 	for _, freq := range freqs {
-		err := csvW.Write([]string{freq.Token, strconv.Itoa(freq.Count), strconv.FormatFloat(float64(freq.Percentage), 'f', 8, 32)})
+		err := csvW.Write([]string{freq.Token, strconv.FormatInt(freq.Count, 10), formatPercentage(freq.Percentage)})
 		if err != nil {
 			return fmt.Errorf("failed to write the token %q. %w", freq.Token, err)
 		}
@@ -212,20 +296,105 @@ func (ft *FrequencyTable) Save(w io.Writer) error {
 	return nil
 }
 
+// formatPercentage formats the percentage using the fewest digits needed to represent it exactly.
+func formatPercentage(percentage float64) string {
+	return strconv.FormatFloat(percentage, 'f', -1, 64)
+}
+
+// writeFrequencyHeader writes the CSV header and the sorted metadata rows.
+func writeFrequencyHeader(csvW *csv.Writer, metadata map[string]string) error {
+	err := csvW.Write([]string{"#token", "count", "percentage"})
+	if err != nil {
+		return fmt.Errorf("failed to write the csv header. %w", err)
+	}
+
+	keys := maps.Keys(metadata)
+	slices.Sort(keys)
+	for _, key := range keys {
+		if err := csvW.Write([]string{metadataPrefix, key, metadata[key]}); err != nil {
+			return fmt.Errorf("failed to write the metadata %q. %w", key, err)
+		}
+	}
+	return nil
+}
+
+// SaveToFile saves the frequency table to the given file path. See [FrequencyTable.Save] for more details.
+func (ft *FrequencyTable) SaveToFile(path string) error {
+	//AJ### TODO: Need to do "atomic" save and replace
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to close %s. %v", path, err)
+		}
+	}()
+
+	if err := ft.Save(f); err != nil {
+		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+	}
+	return nil
+}
+
+// Merge adds the token counts of the other frequency table, including the entries it has spilled to disk,
+// to this table. Metadata of the other table is copied when the key does not exist yet and the counts of the
+// pruned tokens are combined. An error is returned if the spilled entries of the other table could not be read.
+// NOTE: [FrequencyTable.Update] needs to be called to recalculate the frequencies. The percentages are 0 until then.
+func (ft *FrequencyTable) Merge(other *FrequencyTable) error {
+	var entries []Frequency
+	if other.Spilled() {
+		var err error
+		if entries, err = other.spilledEntries(); err != nil {
+			return err
+		}
+	} else {
+		entries = other.Entries()
+	}
+	for _, freq := range entries {
+		ft.addCount(freq.Token, uint64(freq.Count))
+	}
+
+	if _, exists := other.Metadata(MetadataPruned); exists {
+		pruned := ft.prunedCount() + other.prunedCount()
+		ft.SetMetadata(MetadataPruned, strconv.FormatInt(pruned, 10))
+
+		// The total includes the pruned counts
+		ft.mu.Lock()
+		ft.total = 0
+		ft.mu.Unlock()
+	}
+
+	for _, key := range other.MetadataKeys() {
+		if _, exists := ft.Metadata(key); !exists {
+			value, _ := other.Metadata(key)
+			ft.SetMetadata(key, value)
+		}
+	}
+	return nil
+}
+
 // Update will calculate and update the token frequencies.
+// The frequencies are derived from the counts and the total count, which is the sum of all the counts.
+// The frequencies of a pruned table are relative to the total count before pruning (see [Pruner]) and those
+// of a top-K table are relative to the total count of all the tokens seen (see [TopK]).
 func (ft *FrequencyTable) Update() {
 	ft.mu.Lock()
 	defer ft.mu.Unlock()
 
-	sum := 0
-	for _, freq := range ft.frequencies {
-		sum += freq.Count
-	}
+	sum := ft.store.sum()
 
-	for k, freq := range ft.frequencies {
-		freq.Percentage = float32(freq.Count) / float32(sum)
-		ft.frequencies[k] = freq
+	if _, exists := ft.metadata[MetadataPruned]; exists {
+		pruned := ft.prunedCountLocked()
+		sum += uint64(pruned)
+		ft.setPrunedLocked(int64(sum), pruned)
+	} else if value, exists := ft.metadata[MetadataTopKTotal]; exists {
+		if total, err := strconv.ParseUint(value, 10, 64); err == nil && total > sum {
+			sum = total
+		}
 	}
+
+	ft.total = sum
 }
 
 //-----------------------------------------------------------------------------
@@ -233,7 +402,7 @@ func (ft *FrequencyTable) Update() {
 // ParseLetterTokens is used to parse ngrams for letter combinations of the given tokenSize and language
 // from the io.Reader and then update the frequency table.
 func (ft *FrequencyTable) ParseLetterTokens(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int) error {
+	tokenSize int, opts ...ParseOption) error {
 
 	err := ParseLetterTokens(ctx, input, language, tokenSize,
 		func(token string, err error) error {
@@ -241,7 +410,7 @@ func (ft *FrequencyTable) ParseLetterTokens(ctx context.Context, input io.Reader
 				ft.Add(token, 1)
 			}
 			return nil
-		})
+		}, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to parse the letter tokens. %w", err)
 	}
@@ -251,7 +420,7 @@ func (ft *FrequencyTable) ParseLetterTokens(ctx context.Context, input io.Reader
 // ParseWordTokens is used to parse ngrams for word combinations of the given tokenSize and language
 // from the io.Reader and then update the frequency table.
 func (ft *FrequencyTable) ParseWordTokens(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int) error {
+	tokenSize int, opts ...ParseOption) error {
 
 	err := ParseWordTokens(ctx, input, language, tokenSize,
 		func(token string, err error) error {
@@ -259,19 +428,34 @@ func (ft *FrequencyTable) ParseWordTokens(ctx context.Context, input io.Reader,
 				ft.Add(token, 1)
 			}
 			return nil
-		})
+		}, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to parse the word tokens. %w", err)
 	}
 	return nil
 }
 
+// ParseTokens is used to parse the ngrams produced by the [Tokenizer] from the io.Reader and then
+// update the frequency table. All of the tokens produced are added regardless of their ngram size.
+func (ft *FrequencyTable) ParseTokens(ctx context.Context, input io.Reader, tok Tokenizer) error {
+	err := ParseTokens(ctx, input, tok,
+		func(token string, err error) error {
+			if err == nil {
+				ft.Add(token, 1)
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("failed to parse the tokens. %w", err)
+	}
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 
+// Frequency is the number of times a token appeared and its share of the total count of all the tokens.
 type Frequency struct {
 	Token      string
-	Count      int
-	Percentage float32
+	Count      int64
+	Percentage float64
 }
-
-type tokenFrequencyMap map[string]Frequency
@@ -20,11 +20,16 @@
 package ngrams_test
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
+	"github.com/andrejacobs/go-analyse/text/alphabet"
 	"github.com/andrejacobs/go-analyse/text/ngrams"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -81,6 +86,7 @@ func TestLoadFrequenciesParseErrors(t *testing.T) {
 		errMsg string
 	}{
 		{input: "the,nan,0.1", errMsg: "failed to parse the count field from the csv"},
+		{input: "the,-1,0.1", errMsg: "failed to parse the count field from the csv"},
 		{input: "the,42,abc", errMsg: "failed to parse the percentage field from the csv"},
 	}
 	for i, tC := range testCases {
@@ -98,6 +104,7 @@ func TestFrequenciesLoadAndSave(t *testing.T) {
 	freq.Add("he", 2)
 	freq.Add("she", 1)
 	freq.Add("the", 100)
+	freq.Update()
 
 	f, err := os.CreateTemp("", "unit-testing-ngrams")
 	require.NoError(t, err)
@@ -112,12 +119,149 @@ func TestFrequenciesLoadAndSave(t *testing.T) {
 	assert.Equal(t, freq.EntriesSortedByCount(), load.EntriesSortedByCount())
 }
 
+func TestFrequenciesSaveToFile(t *testing.T) {
+	freq := ngrams.NewFrequencyTable()
+	freq.Add("the", 3)
+	freq.Add("he", 1)
+	freq.Update()
+
+	path := filepath.Join(t.TempDir(), "freq.csv")
+	require.NoError(t, freq.SaveToFile(path))
+
+	load, err := ngrams.LoadFrequenciesFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, freq.EntriesSortedByCount(), load.EntriesSortedByCount())
+
+	assert.ErrorContains(t, freq.SaveToFile(filepath.Join(t.TempDir(), "missing", "freq.csv")),
+		"failed to save the frequency table to file")
+}
+
+func TestFrequenciesLosslessRoundTrip(t *testing.T) {
+	freq := ngrams.NewFrequencyTable()
+	freq.Add("the", 3_000_000_001)
+	freq.Add("he", 5_000_000_007)
+	freq.Add("she", 1)
+	freq.Update()
+
+	var sb strings.Builder
+	require.NoError(t, freq.Save(&sb))
+
+	load, err := ngrams.LoadFrequencies(strings.NewReader(sb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, freq.EntriesSortedByCount(), load.EntriesSortedByCount())
+
+	a, _ := load.Get("the")
+	assert.Equal(t, int64(3_000_000_001), a.Count)
+	assert.Equal(t, float64(3_000_000_001)/float64(8_000_000_009), a.Percentage)
+}
+
+func TestLoadFrequenciesDerivesPercentages(t *testing.T) {
+	r := strings.NewReader(`#token,count
+the,3
+fox,1
+she,4,
+`)
+
+	freq, err := ngrams.LoadFrequencies(r)
+	require.NoError(t, err)
+
+	expected := []ngrams.Frequency{
+		{Token: "she", Count: 4, Percentage: 0.5},
+		{Token: "the", Count: 3, Percentage: 0.375},
+		{Token: "fox", Count: 1, Percentage: 0.125},
+	}
+	assert.Equal(t, expected, freq.EntriesSortedByCount())
+}
+
+func TestFrequencyMerge(t *testing.T) {
+	a := ngrams.NewFrequencyTable()
+	a.Add("the", 3)
+	a.Add("he", 1)
+	a.SetMetadata("skip", "1")
+
+	b := ngrams.NewFrequencyTable()
+	b.Add("the", 2)
+	b.Add("she", 4)
+	b.SetMetadata("skip", "2")
+	b.SetMetadata("comment", "b")
+
+	require.NoError(t, a.Merge(b))
+	a.Update()
+
+	expected := []ngrams.Frequency{
+		{Token: "the", Count: 5, Percentage: 0.5},
+		{Token: "she", Count: 4, Percentage: 0.4},
+		{Token: "he", Count: 1, Percentage: 0.1},
+	}
+	assert.Equal(t, expected, a.EntriesSortedByCount())
+
+	value, _ := a.Metadata("skip")
+	assert.Equal(t, "1", value)
+	value, _ = a.Metadata("comment")
+	assert.Equal(t, "b", value)
+}
+
+func TestFrequencyPercentagesAfterChanges(t *testing.T) {
+	a := ngrams.NewFrequencyTable()
+	a.Add("the", 1)
+	a.Add("he", 1)
+	a.Update()
+	the, _ := a.Get("the")
+	assert.Equal(t, 0.5, the.Percentage)
+
+	// The percentages are not stale after adding or merging
+	b := ngrams.NewFrequencyTable()
+	b.Add("the", 6)
+	b.Add("she", 2)
+	b.Update()
+	require.NoError(t, a.Merge(b))
+	the, _ = a.Get("the")
+	assert.Equal(t, ngrams.Frequency{Token: "the", Count: 7}, the)
+	for _, freq := range a.Entries() {
+		assert.Zero(t, freq.Percentage, freq.Token)
+	}
+
+	a.Update()
+	the, _ = a.Get("the")
+	assert.Equal(t, 0.7, the.Percentage)
+
+	a.Add("he", 10)
+	he, _ := a.Get("he")
+	assert.Zero(t, he.Percentage)
+}
+
+func TestFrequenciesMetadata(t *testing.T) {
+	freq := ngrams.NewFrequencyTable()
+	freq.Add("the", 1)
+	freq.SetMetadata("skip", "2")
+	freq.SetMetadata("comment", "with, a comma")
+
+	value, exists := freq.Metadata("skip")
+	assert.True(t, exists)
+	assert.Equal(t, "2", value)
+	_, exists = freq.Metadata("naf")
+	assert.False(t, exists)
+	assert.Equal(t, []string{"comment", "skip"}, freq.MetadataKeys())
+
+	var sb strings.Builder
+	require.NoError(t, freq.Save(&sb))
+	assert.Equal(t, "#token,count,percentage\n#meta,comment,\"with, a comma\"\n#meta,skip,2\nthe,1,0\n", sb.String())
+
+	load, err := ngrams.LoadFrequencies(strings.NewReader(sb.String()))
+	require.NoError(t, err)
+	assert.Equal(t, 1, load.Len())
+	assert.Equal(t, []string{"comment", "skip"}, load.MetadataKeys())
+	value, _ = load.Metadata("comment")
+	assert.Equal(t, "with, a comma", value)
+}
+
 func TestFrequencyAdd(t *testing.T) {
 	freq := ngrams.NewFrequencyTable()
 	freq.Add("he", 1)
 	freq.Add("he", 2)
 	freq.Add("she", 1)
 	freq.Add("the", 100)
+	freq.Add("the", -1)
 
 	expected := []ngrams.Frequency{
 		{Token: "the", Count: 100},
@@ -166,3 +310,132 @@ func compareTwoFrequencyTables(t *testing.T, a *ngrams.FrequencyTable, b *ngrams
 			af.Token, af.Count, af.Percentage, bf.Token, bf.Count, bf.Percentage)
 	}
 }
+
+func TestFrequencyTableTokenStorage(t *testing.T) {
+	// Short tokens are packed and longer tokens are interned
+	tokens := []string{"a", "th", "the", "they", "ĳs", "😀😀😀", "😀😀😀😀", "\x00a", "a\x00", "\xffa", "", "the fox", "\U0010FFFF"}
+
+	ft := ngrams.NewFrequencyTable()
+	for i, token := range tokens {
+		ft.Add(token, i+1)
+		ft.Add(token, 1)
+	}
+	ft.Update()
+
+	assert.Equal(t, len(tokens), ft.Len())
+	assert.ElementsMatch(t, tokens, ft.Tokens())
+	for i, token := range tokens {
+		freq, exists := ft.Get(token)
+		require.True(t, exists, token)
+		assert.Equal(t, token, freq.Token)
+		assert.Equal(t, int64(i+2), freq.Count, token)
+		assert.Greater(t, freq.Percentage, float64(0), token)
+	}
+
+	_, exists := ft.Get("b")
+	assert.False(t, exists)
+	_, exists = ft.Get("a\x00\x00")
+	assert.False(t, exists)
+
+	entries := ft.EntriesSortedByCount()
+	assert.Equal(t, "\U0010FFFF", entries[0].Token)
+	assert.Equal(t, "a", entries[len(entries)-1].Token)
+}
+
+// mapFrequencyTable is the previous representation of a frequency table (the token is stored as both the
+// map key and in the Frequency) and is used as the baseline in the benchmarks.
+type mapFrequencyTable struct {
+	frequencies map[string]ngrams.Frequency
+	mu          sync.RWMutex
+}
+
+func (m *mapFrequencyTable) Add(token string, count int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	freq, exists := m.frequencies[token]
+	if !exists {
+		m.frequencies[token] = ngrams.Frequency{Token: token, Count: count}
+	} else {
+		freq.Count += count
+		m.frequencies[token] = freq
+	}
+}
+
+// benchmarkTokens returns the letter or word ngrams of the size parsed from the test data.
+func benchmarkTokens(b *testing.B, words bool, size int) []string {
+	data, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(b, err)
+
+	tokens := make([]string, 0)
+	recv := func(token string, err error) error {
+		tokens = append(tokens, token)
+		return nil
+	}
+
+	en := alphabet.MustBuiltin("en")
+	if words {
+		err = ngrams.ParseWordTokens(context.Background(), bytes.NewReader(data), en, size, recv)
+	} else {
+		err = ngrams.ParseLetterTokens(context.Background(), bytes.NewReader(data), en, size, recv)
+	}
+	require.NoError(b, err)
+	return tokens
+}
+
+func BenchmarkFrequencyTableAdd(b *testing.B) {
+	for _, words := range []bool{false, true} {
+		for size := 1; size <= 3; size++ {
+			tokens := benchmarkTokens(b, words, size)
+			name := fmt.Sprintf("%s-%d", ngrams.ProcessorMode(words), size)
+
+			b.Run(name+"/table", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					ft := ngrams.NewFrequencyTable()
+					for _, token := range tokens {
+						ft.Add(token, 1)
+					}
+				}
+			})
+
+			b.Run(name+"/map-baseline", func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					ft := &mapFrequencyTable{frequencies: make(map[string]ngrams.Frequency)}
+					for _, token := range tokens {
+						ft.Add(token, 1)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkFrequencyTableEntries(b *testing.B) {
+	tokens := benchmarkTokens(b, false, 3)
+	ft := ngrams.NewFrequencyTable()
+	for _, token := range tokens {
+		ft.Add(token, 1)
+	}
+	ft.Update()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = ft.EntriesSortedByCount()
+	}
+}
+
+func BenchmarkProcessorProcessFiles(b *testing.B) {
+	en := alphabet.MustBuiltin("en")
+	for _, mode := range []ngrams.ProcessorMode{ngrams.ProcessLetters, ngrams.ProcessWords} {
+		b.Run(mode.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				p := ngrams.NewFrequencyProcessorWithSizes(mode, en, []int{1, 2, 3})
+				require.NoError(b, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+			}
+		})
+	}
+}
@@ -21,23 +21,32 @@ package ngrams
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/andrejacobs/go-analyse/internal/processor"
 	"github.com/andrejacobs/go-analyse/text/alphabet"
 )
 
-// FrequencyProcessor is used to parse letter or word ngrams from input sources.
+// FrequencyProcessor is used to parse letter, word or custom [Tokenizer] ngrams from input sources.
 type FrequencyProcessor struct {
-	proc      *processor.Processor
-	ft        *FrequencyTable
-	language  alphabet.Language
-	tokenSize int
-	mode      ProcessorMode
+	proc     *processor.Processor
+	tables   map[tableKey]*FrequencyTable
+	language alphabet.Language
+	configs  []ProcessorConfig
+	opts     []ParseOption
+	topK     int
+	budget   uint64
+	spillDir string
 }
 
+// MetadataSkip is the frequency table metadata key used to record the skip used to create skip-grams.
+const MetadataSkip = "skip"
+
 // ProcessorMode specifies whether the processor works on letter or word ngrams.
 type ProcessorMode bool
 
@@ -46,14 +55,63 @@ const (
 	ProcessWords   ProcessorMode = true
 )
 
+// String returns either "letters" or "words".
+func (m ProcessorMode) String() string {
+	if m == ProcessWords {
+		return "words"
+	}
+	return "letters"
+}
+
+// ProcessorConfig specifies a tokenizer and the ngram sizes for which frequency tables are created.
+type ProcessorConfig struct {
+	// Mode selects the built-in letter or word tokenizer when Tokenizer is not specified.
+	Mode ProcessorMode
+	// Tokenizer is the name of a registered tokenizer. See [RegisterTokenizer].
+	Tokenizer string
+	Sizes     []int
+}
+
+// tableKey identifies the frequency table of a tokenizer and ngram size.
+type tableKey struct {
+	tokenizer string
+	size      int
+}
+
 // NewFrequencyProcessor creates a new frequency table and does not report progress.
-func NewFrequencyProcessor(mode ProcessorMode, language alphabet.Language, tokenSize int) *FrequencyProcessor {
+// The optional [ParseOption]s are passed along to the letter or word parser.
+func NewFrequencyProcessor(mode ProcessorMode, language alphabet.Language, tokenSize int,
+	opts ...ParseOption) *FrequencyProcessor {
+	return NewFrequencyProcessorWithSizes(mode, language, []int{tokenSize}, opts...)
+}
+
+// NewFrequencyProcessorWithSizes creates a new processor that fills a frequency table for each of the
+// ngram sizes while only reading and tokenizing the input sources once. Progress is not reported.
+// The optional [ParseOption]s are passed along to the letter or word parser.
+func NewFrequencyProcessorWithSizes(mode ProcessorMode, language alphabet.Language, sizes []int,
+	opts ...ParseOption) *FrequencyProcessor {
+	return NewFrequencyProcessorWithConfigs(language, []ProcessorConfig{{Mode: mode, Sizes: sizes}}, opts...)
+}
+
+// NewFrequencyProcessorWithConfigs creates a new processor that runs each of the tokenizer configurations
+// while only reading the input sources once. Every rune read is fanned out to all of the tokenizers and a
+// frequency table is filled for each tokenizer and ngram size. Progress is not reported.
+// Configurations with the same tokenizer are merged. The optional [ParseOption]s are passed along to all the tokenizers.
+func NewFrequencyProcessorWithConfigs(language alphabet.Language, configs []ProcessorConfig,
+	opts ...ParseOption) *FrequencyProcessor {
+
 	p := &FrequencyProcessor{
-		proc:      processor.NewProcessor(),
-		ft:        NewFrequencyTable(),
-		language:  language,
-		tokenSize: tokenSize,
-		mode:      mode,
+		proc:     processor.NewProcessor(),
+		tables:   make(map[tableKey]*FrequencyTable),
+		language: language,
+		configs:  mergeConfigs(configs),
+		opts:     opts,
+	}
+
+	for _, cfg := range p.configs {
+		for _, size := range cfg.Sizes {
+			p.tables[tableKey{tokenizer: cfg.Tokenizer, size: size}] = NewFrequencyTable()
+		}
 	}
 	return p
 }
@@ -63,58 +121,291 @@ func (p *FrequencyProcessor) SetProgressReporter(reporter processor.ProgressRepo
 	p.proc.SetProgressReporter(reporter)
 }
 
-// Table returns the frequency table.
+// SetTopK configures the processor to only keep the k most frequent tokens of each frequency table using
+// a bounded amount of memory instead of counting every distinct token exactly. The counts are approximate
+// and the error bounds are recorded in the metadata of the tables. See [TopK].
+// A k of 0 disables the top-K mode.
+func (p *FrequencyProcessor) SetTopK(k int) {
+	p.topK = k
+}
+
+// SetMemoryBudget configures the frequency tables to spill sorted runs to temporary files in dir (the default
+// temporary directory when empty) once the estimated memory used exceeds the budget in bytes. The budget is
+// shared equally by all of the frequency tables. The counts remain exact. See [FrequencyTable.SetMemoryBudget].
+// A budget of 0 disables spilling. Call [FrequencyProcessor.Close] to remove the temporary files.
+func (p *FrequencyProcessor) SetMemoryBudget(budget uint64, dir string) {
+	p.budget = budget
+	p.spillDir = dir
+}
+
+// Close removes any temporary files the frequency tables have spilled to disk.
+func (p *FrequencyProcessor) Close() error {
+	var result error
+	for _, ft := range p.tables {
+		result = errors.Join(result, ft.Close())
+	}
+	return result
+}
+
+// Configs returns the tokenizer configurations for which frequency tables are created.
+func (p *FrequencyProcessor) Configs() []ProcessorConfig {
+	return p.configs
+}
+
+// Sizes returns the ngram sizes (in ascending order) for which frequency tables are created
+// by the first tokenizer configuration.
+func (p *FrequencyProcessor) Sizes() []int {
+	return p.configs[0].Sizes
+}
+
+// FrequencyTable returns the frequency table (of the first configuration's smallest ngram size).
 func (p *FrequencyProcessor) FrequencyTable() *FrequencyTable {
-	return p.ft
+	return p.FrequencyTableForSize(p.configs[0].Sizes[0])
+}
+
+// FrequencyTableForSize returns the frequency table for the ngram size (of the first configuration)
+// or nil if the size is not being processed.
+func (p *FrequencyProcessor) FrequencyTableForSize(size int) *FrequencyTable {
+	return p.FrequencyTableForTokenizer(p.configs[0].Tokenizer, size)
+}
+
+// FrequencyTableFor returns the frequency table for the mode and ngram size or nil if it is not being processed.
+func (p *FrequencyProcessor) FrequencyTableFor(mode ProcessorMode, size int) *FrequencyTable {
+	return p.FrequencyTableForTokenizer(mode.String(), size)
+}
+
+// FrequencyTableForTokenizer returns the frequency table for the named tokenizer and ngram size or nil
+// if it is not being processed.
+func (p *FrequencyProcessor) FrequencyTableForTokenizer(name string, size int) *FrequencyTable {
+	return p.tables[tableKey{tokenizer: name, size: size}]
 }
 
-// LoadFrequenciesFromFile replaces the current frequency table by parsing frequencies from the given file path.
+// LoadFrequenciesFromFile replaces the current frequency table (of the first configuration's smallest
+// ngram size) by parsing frequencies from the given file path.
 func (p *FrequencyProcessor) LoadFrequenciesFromFile(path string) error {
+	return p.LoadFrequenciesForSizeFromFile(p.configs[0].Sizes[0], path)
+}
+
+// LoadFrequenciesForSizeFromFile replaces the current frequency table for the ngram size (of the first
+// configuration) by parsing frequencies from the given file path.
+func (p *FrequencyProcessor) LoadFrequenciesForSizeFromFile(size int, path string) error {
+	return p.LoadFrequenciesForTokenizerFromFile(p.configs[0].Tokenizer, size, path)
+}
+
+// LoadFrequenciesForFromFile replaces the current frequency table for the mode and ngram size by parsing
+// frequencies from the given file path.
+func (p *FrequencyProcessor) LoadFrequenciesForFromFile(mode ProcessorMode, size int, path string) error {
+	return p.LoadFrequenciesForTokenizerFromFile(mode.String(), size, path)
+}
+
+// LoadFrequenciesForTokenizerFromFile replaces the current frequency table for the named tokenizer and
+// ngram size by parsing frequencies from the given file path.
+func (p *FrequencyProcessor) LoadFrequenciesForTokenizerFromFile(name string, size int, path string) error {
+	key := tableKey{tokenizer: name, size: size}
+	if _, exists := p.tables[key]; !exists {
+		return fmt.Errorf("%s ngram size %d is not being processed", name, size)
+	}
+
 	ft, err := LoadFrequenciesFromFile(path)
 	if err != nil {
 		return err
 	}
 
-	p.ft = ft
+	p.tables[key] = ft
 	return nil
 }
 
-// Save the frequency table to the given file path.
+// Save the frequency table (of the first configuration's smallest ngram size) to the given file path.
 func (p *FrequencyProcessor) Save(path string) error {
-	//AJ### TODO: Need to do "atomic" save and replace
-	f, err := os.Create(path)
+	return p.SaveForSize(p.configs[0].Sizes[0], path)
+}
+
+// SaveForSize saves the frequency table for the ngram size (of the first configuration) to the given file path.
+func (p *FrequencyProcessor) SaveForSize(size int, path string) error {
+	return p.SaveForTokenizer(p.configs[0].Tokenizer, size, path)
+}
+
+// SaveFor saves the frequency table for the mode and ngram size to the given file path.
+func (p *FrequencyProcessor) SaveFor(mode ProcessorMode, size int, path string) error {
+	return p.SaveForTokenizer(mode.String(), size, path)
+}
+
+// SaveForTokenizer saves the frequency table for the named tokenizer and ngram size to the given file path.
+func (p *FrequencyProcessor) SaveForTokenizer(name string, size int, path string) error {
+	ft, exists := p.tables[tableKey{tokenizer: name, size: size}]
+	if !exists {
+		return fmt.Errorf("%s ngram size %d is not being processed", name, size)
+	}
+	return ft.SaveToFile(path)
+}
+
+// ProcessFiles updates the frequency tables by parsing the ngrams produced by the tokenizers from the given input paths.
+func (p *FrequencyProcessor) ProcessFiles(ctx context.Context, paths []string) error {
+	opt := applyParseOptions(p.opts)
+
+	factories, err := lookupFactories(p.configs)
 	if err != nil {
-		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+		return err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "ERROR: failed to close %s. %v", path, err)
+
+	if p.budget > 0 && p.topK < 1 {
+		budget := max(p.budget/uint64(len(p.tables)), 1)
+		for _, ft := range p.tables {
+			ft.SetMemoryBudget(budget, p.spillDir)
+		}
+	}
+
+	// The tokens are either counted exactly by the frequency tables or approximately by top-K counters
+	// that are seeded with the existing frequencies.
+	counters := make(map[tableKey]tokenCounter, len(p.tables))
+	topKs := make(map[tableKey]*TopK)
+	for key, ft := range p.tables {
+		if p.topK < 1 {
+			counters[key] = ft
+			continue
 		}
-	}()
 
-	if err := p.ft.Save(f); err != nil {
-		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+		topK := NewTopK(p.topK)
+		if err := topK.resume(ft); err != nil {
+			return err
+		}
+		topKs[key] = topK
+		counters[key] = topK
 	}
-	return nil
-}
 
-// ProcessFiles updates the frequency table by parsing letter or word ngrams from the given input paths.
-func (p *FrequencyProcessor) ProcessFiles(ctx context.Context, paths []string) error {
-	var fn processor.ProcessFunc
-	if p.mode == ProcessWords {
-		fn = func(ctx context.Context, r io.Reader) error {
-			return p.ft.ParseWordTokens(ctx, r, p.language, p.tokenSize)
+	fn := func(ctx context.Context, r io.Reader) error {
+		emitters := make([]EmitFunc, 0, len(p.configs))
+		for _, cfg := range p.configs {
+			// Indexed by the ngram size since this is looked up for every token
+			tables := make([]tokenCounter, slices.Max(cfg.Sizes)+1)
+			for _, size := range cfg.Sizes {
+				tables[size] = counters[tableKey{tokenizer: cfg.Tokenizer, size: size}]
+			}
+
+			if opt.filter == nil {
+				emitters = append(emitters, func(size int, token string) error {
+					if size < len(tables) && tables[size] != nil {
+						tables[size].Add(token, 1)
+					}
+					return nil
+				})
+				continue
+			}
+
+			emitters = append(emitters, func(size int, token string) error {
+				if size >= len(tables) || tables[size] == nil {
+					return nil
+				}
+				token, keep := opt.filter.Apply(token)
+				if !keep {
+					return nil
+				}
+				tables[size].Add(token, 1)
+				return nil
+			})
 		}
-	} else {
-		fn = func(ctx context.Context, r io.Reader) error {
-			return p.ft.ParseLetterTokens(ctx, r, p.language, p.tokenSize)
+
+		tok := newConfigTokenizer(p.language, p.configs, factories, emitters, p.opts)
+		tok = opt.withCoverage(tok, p.language, processor.Source(ctx))
+		if err := tokenize(ctx, opt.filterInput(ctx, r), tok, emitters[0]); err != nil {
+			return fmt.Errorf("failed to parse the %s tokens. %w", p.description(), err)
 		}
+		return nil
 	}
 
 	if err := p.proc.ProcessFiles(ctx, paths, fn); err != nil {
 		return err
 	}
 
-	p.ft.Update()
+	for key, ft := range p.tables {
+		if topK, exists := topKs[key]; exists {
+			// The percentages of the top-K table are relative to the total of all the tokens seen
+			result := topK.FrequencyTable()
+			for _, name := range ft.MetadataKeys() {
+				if _, exists := result.Metadata(name); !exists {
+					value, _ := ft.Metadata(name)
+					result.SetMetadata(name, value)
+				}
+			}
+			p.tables[key] = result
+			ft = result
+		}
+
+		if opt.skip > 0 {
+			ft.SetMetadata(MetadataSkip, strconv.Itoa(opt.skip))
+		}
+		if p.topK < 1 {
+			ft.Update()
+		}
+	}
 	return nil
 }
+
+// mergeConfigs resolves the tokenizer name of each config and merges the configs that use the same tokenizer.
+func mergeConfigs(configs []ProcessorConfig) []ProcessorConfig {
+	result := make([]ProcessorConfig, 0, len(configs))
+	for _, cfg := range configs {
+		if cfg.Tokenizer == "" {
+			cfg.Tokenizer = cfg.Mode.String()
+		}
+
+		idx := slices.IndexFunc(result, func(c ProcessorConfig) bool { return c.Tokenizer == cfg.Tokenizer })
+		if idx < 0 {
+			result = append(result, ProcessorConfig{Mode: cfg.Mode, Tokenizer: cfg.Tokenizer})
+			idx = len(result) - 1
+		}
+
+		sizes := append(slices.Clone(result[idx].Sizes), cfg.Sizes...)
+		slices.Sort(sizes)
+		result[idx].Sizes = slices.Compact(sizes)
+	}
+	return result
+}
+
+// lookupFactories returns the factory of the tokenizer used by each of the configs.
+func lookupFactories(configs []ProcessorConfig) ([]TokenizerFactory, error) {
+	factories := make([]TokenizerFactory, 0, len(configs))
+	for _, cfg := range configs {
+		factory, err := LookupTokenizer(cfg.Tokenizer)
+		if err != nil {
+			return nil, err
+		}
+		factories = append(factories, factory)
+	}
+	return factories, nil
+}
+
+// newConfigTokenizer creates the tokenizers of the configs. The tokens produced by the tokenizer of a config
+// are passed to the emit function at the same index.
+func newConfigTokenizer(language alphabet.Language, configs []ProcessorConfig, factories []TokenizerFactory,
+	emitters []EmitFunc, opts []ParseOption) Tokenizer {
+
+	if len(configs) == 1 {
+		return factories[0](language, configs[0].Sizes, opts...)
+	}
+
+	tokenizers := make([]Tokenizer, 0, len(configs))
+	for i, cfg := range configs {
+		tokenizers = append(tokenizers, factories[i](language, cfg.Sizes, opts...))
+	}
+	return newMultiTokenizer(tokenizers, emitters)
+}
+
+// tokenCounter counts the tokens produced by a tokenizer. E.g. [FrequencyTable] or [TopK].
+type tokenCounter interface {
+	Add(token string, count int)
+}
+
+// description returns the tokenizers being used as shown in error messages. E.g. "letter" or "letter and word".
+func (p *FrequencyProcessor) description() string {
+	return configsDescription(p.configs)
+}
+
+// configsDescription returns the tokenizers of the configs as shown in error messages. E.g. "letter and word".
+func configsDescription(configs []ProcessorConfig) string {
+	names := make([]string, 0, len(configs))
+	for _, cfg := range configs {
+		names = append(names, strings.TrimSuffix(cfg.Tokenizer, "s"))
+	}
+	return strings.Join(names, " and ")
+}
@@ -23,8 +23,11 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/andrejacobs/go-analyse/text/alphabet"
@@ -260,3 +263,184 @@ func loadWordFrequenciesFromFiles(paths []string, language alphabet.Language, to
 	ft.Update()
 	return ft, nil
 }
+
+func TestProcessorProcessFilesMultipleSizes(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		path     string
+		lang     alphabet.Language
+		words    bool
+		expFreqs map[int]string
+	}{
+		{desc: "en-alice-partial letters 1-3",
+			path: "testdata/en-alice-partial.txt",
+			lang: alphabet.MustBuiltin("en"),
+			expFreqs: map[int]string{
+				1: "testdata/freq-1-en-alice.csv",
+				2: "testdata/freq-2-en-alice.csv",
+				3: "testdata/freq-3-en-alice.csv",
+			},
+		},
+		{desc: "fr-alice-partial words 1-3",
+			path: "testdata/fr-alice-partial.txt",
+			lang: alphabet.MustBuiltin("fr"), words: true,
+			expFreqs: map[int]string{
+				1: "testdata/freq-1w-fr-alice.csv",
+				2: "testdata/freq-2w-fr-alice.csv",
+				3: "testdata/freq-3w-fr-alice.csv",
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := ngrams.NewFrequencyProcessorWithSizes(ngrams.ProcessorMode(tC.words), tC.lang, []int{3, 1, 2, 1})
+			assert.Equal(t, []int{1, 2, 3}, p.Sizes())
+			require.NoError(t, p.ProcessFiles(context.Background(), []string{tC.path}))
+
+			for size, path := range tC.expFreqs {
+				expected, err := ngrams.LoadFrequenciesFromFile(path)
+				require.NoError(t, err)
+				compareTwoFrequencyTables(t, expected, p.FrequencyTableForSize(size))
+			}
+			assert.Nil(t, p.FrequencyTableForSize(4))
+		})
+	}
+}
+
+func TestProcessorProcessFilesLettersAndWords(t *testing.T) {
+	p := ngrams.NewFrequencyProcessorWithConfigs(alphabet.MustBuiltin("en"), []ngrams.ProcessorConfig{
+		{Mode: ngrams.ProcessLetters, Sizes: []int{2}},
+		{Mode: ngrams.ProcessWords, Sizes: []int{1, 2}},
+		{Mode: ngrams.ProcessLetters, Sizes: []int{3}},
+	})
+	assert.Equal(t, []ngrams.ProcessorConfig{
+		{Mode: ngrams.ProcessLetters, Tokenizer: ngrams.TokenizerLetters, Sizes: []int{2, 3}},
+		{Mode: ngrams.ProcessWords, Tokenizer: ngrams.TokenizerWords, Sizes: []int{1, 2}},
+	}, p.Configs())
+
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	expFreqs := []struct {
+		mode ngrams.ProcessorMode
+		size int
+		path string
+	}{
+		{mode: ngrams.ProcessLetters, size: 2, path: "testdata/freq-2-en-alice.csv"},
+		{mode: ngrams.ProcessLetters, size: 3, path: "testdata/freq-3-en-alice.csv"},
+		{mode: ngrams.ProcessWords, size: 1, path: "testdata/freq-1w-en-alice.csv"},
+		{mode: ngrams.ProcessWords, size: 2, path: "testdata/freq-2w-en-alice.csv"},
+	}
+	for _, exp := range expFreqs {
+		expected, err := ngrams.LoadFrequenciesFromFile(exp.path)
+		require.NoError(t, err)
+		compareTwoFrequencyTables(t, expected, p.FrequencyTableFor(exp.mode, exp.size))
+	}
+
+	assert.Nil(t, p.FrequencyTableFor(ngrams.ProcessWords, 3))
+	assert.Same(t, p.FrequencyTableFor(ngrams.ProcessLetters, 2), p.FrequencyTable())
+}
+
+func TestProcessorProcessFilesTopK(t *testing.T) {
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 1)
+	p.SetTopK(20)
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	exact, err := ngrams.LoadFrequenciesFromFile("testdata/freq-1w-en-alice.csv")
+	require.NoError(t, err)
+	var total int64
+	for _, freq := range exact.Entries() {
+		total += freq.Count
+	}
+
+	ft := p.FrequencyTable()
+	assert.Equal(t, 20, ft.Len())
+
+	value, _ := ft.Metadata(ngrams.MetadataTopK)
+	assert.Equal(t, "20", value)
+	value, _ = ft.Metadata(ngrams.MetadataTopKTotal)
+	assert.Equal(t, strconv.FormatInt(total, 10), value)
+	value, _ = ft.Metadata(ngrams.MetadataTopKMaxError)
+	maxError, err := strconv.ParseInt(value, 10, 64)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, maxError, total/20)
+
+	for _, freq := range ft.Entries() {
+		expected, _ := exact.Get(freq.Token)
+		assert.GreaterOrEqual(t, freq.Count, expected.Count, freq.Token)
+		assert.LessOrEqual(t, freq.Count, expected.Count+maxError, freq.Token)
+	}
+
+	// The most frequent tokens are kept
+	for _, freq := range exact.EntriesSortedByCount()[:3] {
+		_, exists := ft.Get(freq.Token)
+		assert.True(t, exists, freq.Token)
+	}
+}
+
+func TestProcessorProcessFilesTopKUpdate(t *testing.T) {
+	inputs := []string{"testdata/en-alice-partial.txt"}
+
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 1)
+	p.SetTopK(20)
+	require.NoError(t, p.ProcessFiles(context.Background(), inputs))
+	total, _ := p.FrequencyTable().Metadata(ngrams.MetadataTopKTotal)
+	maxError, _ := p.FrequencyTable().Metadata(ngrams.MetadataTopKMaxError)
+
+	temp := filepath.Join(t.TempDir(), "topk.csv")
+	require.NoError(t, p.Save(temp))
+
+	p2 := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 1)
+	p2.SetTopK(20)
+	require.NoError(t, p2.LoadFrequenciesFromFile(temp))
+	require.NoError(t, p2.ProcessFiles(context.Background(), inputs))
+
+	// The tokens that were not kept by the first run still count towards the total
+	expected, err := strconv.ParseInt(total, 10, 64)
+	require.NoError(t, err)
+	value, _ := p2.FrequencyTable().Metadata(ngrams.MetadataTopKTotal)
+	assert.Equal(t, strconv.FormatInt(2*expected, 10), value)
+
+	value, _ = p2.FrequencyTable().Metadata(ngrams.MetadataTopKMaxError)
+	updatedError, err := strconv.ParseInt(value, 10, 64)
+	require.NoError(t, err)
+	firstError, err := strconv.ParseInt(maxError, 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, updatedError, firstError)
+
+	exact, err := ngrams.LoadFrequenciesFromFile("testdata/freq-1w-en-alice.csv")
+	require.NoError(t, err)
+	for _, freq := range exact.EntriesSortedByCount()[:3] {
+		updated, exists := p2.FrequencyTable().Get(freq.Token)
+		require.True(t, exists, freq.Token)
+		assert.GreaterOrEqual(t, updated.Count, 2*freq.Count, freq.Token)
+		assert.LessOrEqual(t, updated.Count, 2*freq.Count+updatedError, freq.Token)
+		assert.InDelta(t, freq.Percentage, updated.Percentage, float64(updatedError)/float64(2*expected), freq.Token)
+	}
+}
+
+func TestProcessorProcessFilesInputFilter(t *testing.T) {
+	// Only the first line of each input is kept
+	firstLine := func(ctx context.Context, r io.Reader) io.Reader {
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		return strings.NewReader(line)
+	}
+
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("af"), 1,
+		ngrams.WithInputFilter(firstLine))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/af-control.txt"}))
+
+	ft := p.FrequencyTable()
+	assert.ElementsMatch(t, []string{"jan", "pierewiet,"}, ft.Tokens())
+	freq, _ := ft.Get("jan")
+	assert.Equal(t, int64(2), freq.Count)
+
+	s := ngrams.NewTokenStreamer(alphabet.MustBuiltin("af"), []ngrams.ProcessorConfig{{Mode: ngrams.ProcessWords, Sizes: []int{1}}},
+		ngrams.WithInputFilter(firstLine))
+	var tokens []string
+	err := s.ProcessFiles(context.Background(), []string{"testdata/af-control.txt"}, func(token ngrams.StreamedToken) error {
+		tokens = append(tokens, token.Token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jan", "pierewiet,", "jan", "pierewiet,"}, tokens)
+}
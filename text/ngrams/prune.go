@@ -0,0 +1,175 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Frequency table metadata keys used to record the pruning of a table.
+const (
+	// MetadataTotal records the total count of all the tokens, including those that have been pruned.
+	MetadataTotal = "total"
+	// MetadataPruned records the total count of the tokens that have been pruned.
+	MetadataPruned = "pruned"
+)
+
+// Pruner removes the long tail of infrequent tokens from a frequency table. A token is only kept if it meets
+// all of the configured criteria. The percentages of the kept tokens remain relative to the total count before
+// pruning, which is recorded in the metadata (see [MetadataTotal] and [MetadataPruned]).
+// A Pruner can be used when saving a table (see [Pruner.SaveToFile]) or on an existing table (see [Pruner.ApplyToTable]).
+type Pruner struct {
+	minCount      int64
+	topN          int
+	coverage      float64
+	minPercentage float64
+}
+
+// NewPruner creates a new [Pruner] that keeps all of the tokens.
+func NewPruner() *Pruner {
+	return &Pruner{}
+}
+
+// IsEmpty returns true if no pruning criteria have been configured.
+func (p *Pruner) IsEmpty() bool {
+	return p.minCount == 0 && p.topN == 0 && p.coverage == 0 && p.minPercentage == 0
+}
+
+// MinCount only keeps the tokens that have a count of at least n.
+func (p *Pruner) MinCount(n int64) {
+	p.minCount = n
+}
+
+// TopN only keeps the n most frequent tokens. Tokens with the same count are ordered by token.
+func (p *Pruner) TopN(n int) {
+	p.topN = n
+}
+
+// Coverage only keeps the most frequent tokens that together cover the fraction (e.g. 0.95) of the total count.
+func (p *Pruner) Coverage(fraction float64) {
+	p.coverage = fraction
+}
+
+// MinPercentage only keeps the tokens whose share of the total count is at least the fraction (e.g. 0.0001).
+func (p *Pruner) MinPercentage(fraction float64) {
+	p.minPercentage = fraction
+}
+
+// keep returns true if the token at the rank (0 being the most frequent) with the count is kept.
+// cumulative is the sum of the counts of the tokens ranked before it.
+func (p *Pruner) keep(rank int, count int64, cumulative int64, total int64) bool {
+	if p.minCount > 0 && count < p.minCount {
+		return false
+	}
+	if p.topN > 0 && rank >= p.topN {
+		return false
+	}
+	if total > 0 {
+		if p.minPercentage > 0 && float64(count)/float64(total) < p.minPercentage {
+			return false
+		}
+		if p.coverage > 0 && float64(cumulative) >= p.coverage*float64(total) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyToTable returns a new frequency table that only contains the tokens that are kept.
+// The metadata is copied and the total and pruned counts are recorded.
+// NOTE: Only the in-memory entries of a table that has spilled to disk are pruned. Use [Pruner.SaveToFile] instead.
+func (p *Pruner) ApplyToTable(ft *FrequencyTable) *FrequencyTable {
+	result := NewFrequencyTable()
+	for _, key := range ft.MetadataKeys() {
+		value, _ := ft.Metadata(key)
+		result.SetMetadata(key, value)
+	}
+
+	pruned := ft.prunedCount()
+	total := pruned
+	entries := ft.EntriesSortedByCount()
+	for _, freq := range entries {
+		total += freq.Count
+	}
+
+	var cumulative int64
+	for rank, freq := range entries {
+		if !p.keep(rank, freq.Count, cumulative, total) {
+			break
+		}
+		result.addCount(freq.Token, uint64(freq.Count))
+		cumulative += freq.Count
+	}
+
+	result.setPruned(total, total-cumulative)
+	result.Update()
+	return result
+}
+
+// SaveToFile prunes the frequency table while saving it to the file path. Unlike [Pruner.ApplyToTable], this
+// also works on a table that has spilled to disk without loading all of the entries into memory.
+func (p *Pruner) SaveToFile(ft *FrequencyTable, path string) error {
+	if !ft.Spilled() {
+		return p.ApplyToTable(ft).SaveToFile(path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to close %s. %v", path, err)
+		}
+	}()
+
+	if err := ft.saveSpilled(f, p); err != nil {
+		return fmt.Errorf("failed to save the frequency table to file %q. %w", path, err)
+	}
+	return nil
+}
+
+// prunedCount returns the total count of the tokens that have been pruned from the table.
+func (ft *FrequencyTable) prunedCount() int64 {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	return ft.prunedCountLocked()
+}
+
+// prunedCountLocked returns the total count of the pruned tokens. The caller must hold the lock.
+func (ft *FrequencyTable) prunedCountLocked() int64 {
+	pruned, _ := strconv.ParseInt(ft.metadata[MetadataPruned], 10, 64)
+	return pruned
+}
+
+// setPruned records the total count before pruning and the count of the pruned tokens.
+func (ft *FrequencyTable) setPruned(total int64, pruned int64) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	ft.setPrunedLocked(total, pruned)
+}
+
+// setPrunedLocked records the total and pruned counts. The caller must hold the lock.
+func (ft *FrequencyTable) setPrunedLocked(total int64, pruned int64) {
+	ft.metadata[MetadataTotal] = strconv.FormatInt(total, 10)
+	ft.metadata[MetadataPruned] = strconv.FormatInt(pruned, 10)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pruneTestTable() *ngrams.FrequencyTable {
+	ft := ngrams.NewFrequencyTable()
+	for token, count := range map[string]int{"a": 50, "b": 30, "c": 10, "d": 5, "e": 3, "f": 1, "g": 1} {
+		ft.Add(token, count)
+	}
+	ft.Update()
+	return ft
+}
+
+func TestPrunerApplyToTable(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		setup    func(p *ngrams.Pruner)
+		expected []string
+		pruned   string
+	}{
+		{desc: "no criteria", setup: func(p *ngrams.Pruner) {},
+			expected: []string{"a", "b", "c", "d", "e", "f", "g"}, pruned: "0"},
+		{desc: "min count", setup: func(p *ngrams.Pruner) { p.MinCount(5) },
+			expected: []string{"a", "b", "c", "d"}, pruned: "5"},
+		{desc: "top n", setup: func(p *ngrams.Pruner) { p.TopN(2) },
+			expected: []string{"a", "b"}, pruned: "20"},
+		{desc: "coverage", setup: func(p *ngrams.Pruner) { p.Coverage(0.9) },
+			expected: []string{"a", "b", "c"}, pruned: "10"},
+		{desc: "min percentage", setup: func(p *ngrams.Pruner) { p.MinPercentage(0.04) },
+			expected: []string{"a", "b", "c", "d"}, pruned: "5"},
+		{desc: "combined", setup: func(p *ngrams.Pruner) { p.MinCount(2); p.TopN(6) },
+			expected: []string{"a", "b", "c", "d", "e"}, pruned: "2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p := ngrams.NewPruner()
+			tC.setup(p)
+
+			result := p.ApplyToTable(pruneTestTable())
+			assert.ElementsMatch(t, tC.expected, result.Tokens())
+
+			total, _ := result.Metadata(ngrams.MetadataTotal)
+			assert.Equal(t, "100", total)
+			pruned, _ := result.Metadata(ngrams.MetadataPruned)
+			assert.Equal(t, tC.pruned, pruned)
+
+			// Percentages remain relative to the total before pruning
+			a, _ := result.Get("a")
+			assert.Equal(t, 0.5, a.Percentage)
+		})
+	}
+}
+
+func TestPrunerHonestPercentages(t *testing.T) {
+	p := ngrams.NewPruner()
+	p.MinCount(5)
+	pruned := p.ApplyToTable(pruneTestTable())
+
+	// Pruning again keeps the original total
+	p = ngrams.NewPruner()
+	p.TopN(2)
+	pruned = p.ApplyToTable(pruned)
+	value, _ := pruned.Metadata(ngrams.MetadataTotal)
+	assert.Equal(t, "100", value)
+	value, _ = pruned.Metadata(ngrams.MetadataPruned)
+	assert.Equal(t, "20", value)
+
+	// Round trip
+	path := filepath.Join(t.TempDir(), "pruned.csv")
+	require.NoError(t, pruned.SaveToFile(path))
+	loaded, err := ngrams.LoadFrequenciesFromFile(path)
+	require.NoError(t, err)
+	compareTwoFrequencyTables(t, pruned, loaded)
+
+	// Adding counts updates the total
+	loaded.Add("a", 10)
+	loaded.Update()
+	a, _ := loaded.Get("a")
+	assert.Equal(t, float64(60)/float64(110), a.Percentage)
+	value, _ = loaded.Metadata(ngrams.MetadataTotal)
+	assert.Equal(t, "110", value)
+
+	// Merging combines the pruned counts
+	merged := ngrams.NewFrequencyTable()
+	require.NoError(t, merged.Merge(pruned))
+	require.NoError(t, merged.Merge(pruned))
+	merged.Update()
+	value, _ = merged.Metadata(ngrams.MetadataPruned)
+	assert.Equal(t, "40", value)
+	value, _ = merged.Metadata(ngrams.MetadataTotal)
+	assert.Equal(t, "200", value)
+}
+
+func TestPrunerSaveToFileSpilled(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	input, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(t, err)
+
+	p := ngrams.NewPruner()
+	p.MinCount(2)
+	p.Coverage(0.5)
+
+	expected := ngrams.NewFrequencyTable()
+	require.NoError(t, expected.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 1))
+	expected.Update()
+	expectedPath := filepath.Join(t.TempDir(), "expected.csv")
+	require.NoError(t, p.SaveToFile(expected, expectedPath))
+
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMemoryBudget(2048, t.TempDir())
+	defer ft.Close()
+	require.NoError(t, ft.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 1))
+	require.True(t, ft.Spilled())
+	path := filepath.Join(t.TempDir(), "spilled.csv")
+	require.NoError(t, p.SaveToFile(ft, path))
+
+	expectedData, err := os.ReadFile(expectedPath)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedData), string(data))
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSkipgrams(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+
+	testCases := []struct {
+		desc      string
+		input     string
+		tokenSize int
+		skip      int
+		words     bool
+		graphemes bool
+		expected  []string
+	}{
+		{desc: "letters 1-skip-2", input: "the", tokenSize: 2, skip: 1,
+			expected: []string{"th", "he", "t_e"}},
+		{desc: "letters 2-skip-2", input: "then", tokenSize: 2, skip: 2,
+			expected: []string{"th", "he", "t_e", "en", "h_n", "t__n"}},
+		{desc: "letters 1-skip-3", input: "then", tokenSize: 3, skip: 1,
+			expected: []string{"the", "th_n", "t_en", "hen"}},
+		{desc: "letters reset on whitespace", input: "ab cd", tokenSize: 2, skip: 1,
+			expected: []string{"ab", "cd"}},
+		{desc: "letters monograms ignore skip", input: "ab", tokenSize: 1, skip: 2,
+			expected: []string{"a", "b"}},
+		{desc: "letters no skip", input: "the", tokenSize: 2, skip: 0,
+			expected: []string{"th", "he"}},
+		{desc: "graphemes 1-skip-2", input: "the", tokenSize: 2, skip: 1, graphemes: true,
+			expected: []string{"th", "he", "t_e"}},
+		{desc: "words 2-skip-2", input: "of all the\nthings", tokenSize: 2, skip: 2, words: true,
+			expected: []string{"of all", "all the", "of _ the", "the things", "all _ things", "of _ _ things"}},
+		{desc: "words 1-skip-3", input: "A b c d", tokenSize: 3, skip: 1, words: true,
+			expected: []string{"a b c", "b c d", "a b _ d", "a _ c d"}},
+		{desc: "words escape underscores", input: "_ snake_case a\\b c", tokenSize: 2, skip: 1, words: true,
+			expected: []string{`\_ snake\_case`, `snake\_case a\\b`, `\_ _ a\\b`, `a\\b c`, `snake\_case _ c`}},
+		{desc: "words no escape without skip", input: "_ snake_case", tokenSize: 2, skip: 0, words: true,
+			expected: []string{"_ snake_case"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			recv := func(token string, err error) error {
+				require.NoError(t, err)
+				result = append(result, token)
+				return nil
+			}
+
+			opts := []ngrams.ParseOption{ngrams.WithSkip(tC.skip)}
+			if tC.graphemes {
+				opts = append(opts, ngrams.WithGraphemes())
+			}
+
+			var err error
+			if tC.words {
+				err = ngrams.ParseWordTokens(context.Background(), strings.NewReader(tC.input), en,
+					tC.tokenSize, recv, opts...)
+			} else {
+				err = ngrams.ParseLetterTokens(context.Background(), strings.NewReader(tC.input), en,
+					tC.tokenSize, recv, opts...)
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tC.expected, result)
+		})
+	}
+}
+
+func TestParseSkipgramsContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	recv := func(token string, err error) error {
+		return nil
+	}
+
+	err := ngrams.ParseLetterTokens(ctx, strings.NewReader("the quick brown fox"), alphabet.MustBuiltin("en"),
+		2, recv, ngrams.WithSkip(1))
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = ngrams.ParseWordTokens(ctx, strings.NewReader("the quick brown fox"), alphabet.MustBuiltin("en"),
+		2, recv, ngrams.WithSkip(1))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestProcessorRecordsSkip(t *testing.T) {
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 2, ngrams.WithSkip(2))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-control.txt"}))
+
+	skip, exists := p.FrequencyTable().Metadata(ngrams.MetadataSkip)
+	assert.True(t, exists)
+	assert.Equal(t, "2", skip)
+
+	_, exists = p.FrequencyTable().Get("cats _ bats?")
+	assert.True(t, exists)
+}
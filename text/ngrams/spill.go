@@ -0,0 +1,674 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+)
+
+// maxMergeRuns is the maximum number of runs that are merged at the same time (and thus files that are open).
+// More runs are merged in several passes.
+const maxMergeRuns = 64
+
+// spillEntryOverhead is the estimated number of bytes used by an entry in the frequency table
+// (map bucket, string header and [Frequency]) excluding the bytes of the token itself.
+const spillEntryOverhead = 96
+
+// tableSpill keeps track of the sorted runs that a frequency table has spilled to disk.
+type tableSpill struct {
+	budget uint64
+	dir    string
+	tmpDir string
+	used   uint64
+	runs   []string
+	err    error
+}
+
+// runEntry is a token and count stored in a run file.
+type runEntry struct {
+	token string
+	count int64
+}
+
+// SetMemoryBudget configures the frequency table to spill its entries as sorted runs to temporary files in dir
+// (the default temporary directory when empty) once the estimated memory used exceeds the budget in bytes.
+// The runs are merged when the table is saved (external merge sort) and the result is exactly the same as
+// that of a table kept in memory. A budget of 0 disables spilling.
+//
+// NOTE: Once the table has spilled to disk, only [FrequencyTable.Save], [FrequencyTable.Entries],
+// [FrequencyTable.Tokens] and [FrequencyTable.Merge] reflect all of the entries (by merging the runs) and the
+// percentages are calculated while saving. [FrequencyTable.Len] and [FrequencyTable.Get] only reflect the
+// entries still in memory.
+// Call [FrequencyTable.Close] to remove the temporary files.
+func (ft *FrequencyTable) SetMemoryBudget(budget uint64, dir string) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if budget == 0 {
+		if ft.spill != nil && len(ft.spill.runs) > 0 {
+			// Keep the runs that have already been spilled
+			ft.spill.budget = 0
+			return
+		}
+		ft.spill = nil
+		return
+	}
+
+	if ft.spill == nil {
+		ft.spill = &tableSpill{}
+	}
+	ft.spill.budget = budget
+	ft.spill.dir = dir
+	ft.spill.used = 0
+	ft.store.each(func(token string, _ uint64) {
+		ft.spill.used += spillEntrySize(token)
+	})
+	ft.spillIfNeeded()
+}
+
+// Spilled returns true if the frequency table has spilled entries to disk.
+func (ft *FrequencyTable) Spilled() bool {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	return ft.isSpilled()
+}
+
+// Close removes the temporary files the frequency table has spilled to disk.
+func (ft *FrequencyTable) Close() error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if ft.spill == nil || ft.spill.tmpDir == "" {
+		return nil
+	}
+
+	err := os.RemoveAll(ft.spill.tmpDir)
+	ft.spill.tmpDir = ""
+	ft.spill.runs = nil
+	if err != nil {
+		return fmt.Errorf("failed to remove the spilled frequency table files. %w", err)
+	}
+	return nil
+}
+
+// isSpilled returns true if runs have been spilled to disk. The caller must hold the lock.
+func (ft *FrequencyTable) isSpilled() bool {
+	return ft.spill != nil && len(ft.spill.runs) > 0
+}
+
+// spillEntrySize returns the estimated number of bytes used by the token's entry.
+func spillEntrySize(token string) uint64 {
+	return uint64(len(token)) + spillEntryOverhead
+}
+
+// spillIfNeeded writes the entries to a sorted run when the memory budget has been exceeded.
+// The caller must hold the lock.
+func (ft *FrequencyTable) spillIfNeeded() {
+	s := ft.spill
+	if s == nil || s.budget == 0 || s.err != nil || s.used <= s.budget {
+		return
+	}
+	if err := ft.spillEntries(); err != nil {
+		s.err = err
+	}
+}
+
+// spillEntries writes the in-memory entries sorted by token to a new run and clears them from memory.
+// The caller must hold the lock.
+func (ft *FrequencyTable) spillEntries() error {
+	if ft.store.len() == 0 {
+		return nil
+	}
+
+	entries := make([]runEntry, 0, ft.store.len())
+	ft.store.each(func(token string, count uint64) {
+		entries = append(entries, runEntry{token: token, count: int64(count)})
+	})
+	slices.SortFunc(entries, compareRunTokens)
+
+	path, err := ft.spill.writeRun(entries)
+	if err != nil {
+		return err
+	}
+
+	ft.spill.runs = append(ft.spill.runs, path)
+	ft.store = newTokenStore()
+	ft.spill.used = 0
+	return nil
+}
+
+// spillBudget returns the memory budget and directory used for spilling and whether the table has spilled to disk.
+func (ft *FrequencyTable) spillBudget() (uint64, string, bool) {
+	ft.mu.RLock()
+	defer ft.mu.RUnlock()
+	if !ft.isSpilled() {
+		return 0, "", false
+	}
+	return ft.spill.budget, ft.spill.dir, true
+}
+
+// eachEntry calls fn for every token and its count, including the entries that have spilled to disk.
+// The runs are merged first and thus the tokens are passed in sorted order when the table has spilled.
+func (ft *FrequencyTable) eachEntry(fn func(token string, count int64)) error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if !ft.isSpilled() {
+		ft.store.each(func(token string, count uint64) {
+			fn(token, int64(count))
+		})
+		return nil
+	}
+
+	s := ft.spill
+	if s.err != nil {
+		return fmt.Errorf("failed to spill the frequency table to disk. %w", s.err)
+	}
+	if err := ft.spillEntries(); err != nil {
+		return err
+	}
+	if _, err := s.mergeRuns(); err != nil {
+		return err
+	}
+
+	return mergeRunFiles(s.runs, compareRunTokens, func(entry runEntry) error {
+		fn(entry.token, entry.count)
+		return nil
+	})
+}
+
+// spilledEntries returns all of the entries, including those spilled to disk, with the percentages calculated
+// the same way as when the table is saved. The error is also recorded so that it is returned by
+// [FrequencyTable.Save].
+func (ft *FrequencyTable) spilledEntries() ([]Frequency, error) {
+	var result []Frequency
+	var total int64
+	err := ft.eachEntry(func(token string, count int64) {
+		result = append(result, Frequency{Token: token, Count: count})
+		total += count
+	})
+
+	ft.mu.Lock()
+	if err != nil && ft.spill.err == nil {
+		ft.spill.err = err
+	}
+	total += ft.prunedCountLocked()
+	ft.mu.Unlock()
+
+	if total > 0 {
+		for i := range result {
+			result[i].Percentage = float64(result[i].Count) / float64(total)
+		}
+	}
+	return result, err
+}
+
+// errStopMerge is used to stop merging the runs early.
+var errStopMerge = errors.New("stop merging the runs")
+
+// saveSpilled merges the runs and writes the frequency table in the same CSV format as [FrequencyTable.Save].
+// The optional pruner is applied while the sorted entries are being written.
+func (ft *FrequencyTable) saveSpilled(w io.Writer, pruner *Pruner) error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	s := ft.spill
+	if s.err != nil {
+		return fmt.Errorf("failed to spill the frequency table to disk. %w", s.err)
+	}
+
+	// Combine the counts of the same tokens into a single run sorted by token
+	if err := ft.spillEntries(); err != nil {
+		return err
+	}
+	total, err := s.mergeRuns()
+	if err != nil {
+		return err
+	}
+	_, isPruned := ft.metadata[MetadataPruned]
+	pruned := ft.prunedCountLocked()
+	total += pruned
+
+	// Sort the combined run by count (descending) in chunks that fit in the memory budget
+	byCount, err := s.sortRunByCount(s.runs[0])
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, path := range byCount {
+			_ = os.Remove(path)
+		}
+	}()
+	if byCount, err = s.reduceRuns(byCount, compareRunCounts); err != nil {
+		return err
+	}
+
+	// Find how many of the most frequent tokens are kept
+	kept := -1
+	metadata := maps.Clone(ft.metadata)
+	if pruner != nil {
+		kept = 0
+		var cumulative int64
+		err = mergeRunFiles(byCount, compareRunCounts, func(entry runEntry) error {
+			if !pruner.keep(kept, entry.count, cumulative, total) {
+				return errStopMerge
+			}
+			kept++
+			cumulative += entry.count
+			return nil
+		})
+		if err != nil && err != errStopMerge {
+			return err
+		}
+		pruned = total - cumulative
+		isPruned = true
+	}
+	if isPruned {
+		metadata[MetadataTotal] = strconv.FormatInt(total, 10)
+		metadata[MetadataPruned] = strconv.FormatInt(pruned, 10)
+	}
+
+	csvW := csv.NewWriter(w)
+	if err := writeFrequencyHeader(csvW, metadata); err != nil {
+		return err
+	}
+
+	written := 0
+	err = mergeRunFiles(byCount, compareRunCounts, func(entry runEntry) error {
+		if kept >= 0 && written >= kept {
+			return errStopMerge
+		}
+		written++
+
+		percentage := float64(entry.count) / float64(total)
+		err := csvW.Write([]string{entry.token, strconv.FormatInt(entry.count, 10), formatPercentage(percentage)})
+		if err != nil {
+			return fmt.Errorf("failed to write the token %q. %w", entry.token, err)
+		}
+		return nil
+	})
+	if err != nil && err != errStopMerge {
+		return err
+	}
+
+	csvW.Flush()
+	if err := csvW.Error(); err != nil {
+		return fmt.Errorf("failed to write the frequency table. %w", err)
+	}
+	return nil
+}
+
+// mergeRuns combines all of the runs into a single run sorted by token and returns the total count.
+func (s *tableSpill) mergeRuns() (int64, error) {
+	var err error
+	if s.runs, err = s.reduceRuns(s.runs, compareRunTokens); err != nil {
+		return 0, err
+	}
+
+	f, err := s.createRun()
+	if err != nil {
+		return 0, err
+	}
+	rw := newRunWriter(f)
+
+	var total int64
+	var current runEntry
+	hasCurrent := false
+
+	err = mergeRunFiles(s.runs, compareRunTokens, func(entry runEntry) error {
+		total += entry.count
+		if hasCurrent && current.token == entry.token {
+			current.count += entry.count
+			return nil
+		}
+		if hasCurrent {
+			if err := rw.write(current); err != nil {
+				return err
+			}
+		}
+		current = entry
+		hasCurrent = true
+		return nil
+	})
+	if err == nil && hasCurrent {
+		err = rw.write(current)
+	}
+	if err == nil {
+		err = rw.close()
+	} else {
+		_ = f.Close()
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return 0, err
+	}
+
+	for _, path := range s.runs {
+		_ = os.Remove(path)
+	}
+	s.runs = []string{f.Name()}
+	return total, nil
+}
+
+// reduceRuns merges the runs in groups of [maxMergeRuns] into new runs until there are no more than
+// [maxMergeRuns] runs left, so that they can be merged without running out of file descriptors.
+// The runs that have been merged are removed. On failure the runs that are left are returned along with
+// the error so that the caller can still remove them.
+func (s *tableSpill) reduceRuns(paths []string, cmp func(a runEntry, b runEntry) int) ([]string, error) {
+	for len(paths) > maxMergeRuns {
+		var next []string
+		for len(paths) > 0 {
+			group := paths[:min(maxMergeRuns, len(paths))]
+			path, err := s.mergeRunGroup(group, cmp)
+			if err != nil {
+				return append(next, paths...), err
+			}
+			for _, p := range group {
+				_ = os.Remove(p)
+			}
+			next = append(next, path)
+			paths = paths[len(group):]
+		}
+		paths = next
+	}
+	return paths, nil
+}
+
+// mergeRunGroup merges the runs into a new run in the order defined by cmp and returns its path.
+func (s *tableSpill) mergeRunGroup(paths []string, cmp func(a runEntry, b runEntry) int) (string, error) {
+	f, err := s.createRun()
+	if err != nil {
+		return "", err
+	}
+	rw := newRunWriter(f)
+
+	err = mergeRunFiles(paths, cmp, rw.write)
+	if err == nil {
+		err = rw.close()
+	} else {
+		_ = f.Close()
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// sortRunByCount splits the run into chunks that fit in the memory budget, sorts each chunk by count
+// (descending) and returns the paths of the sorted chunks.
+func (s *tableSpill) sortRunByCount(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the run %q. %w", path, err)
+	}
+	defer f.Close()
+
+	var result []string
+	fail := func(err error) ([]string, error) {
+		for _, path := range result {
+			_ = os.Remove(path)
+		}
+		return nil, err
+	}
+
+	rr := newRunReader(f)
+	chunk := make([]runEntry, 0)
+	var used uint64
+	for {
+		entry, err := rr.read()
+		if err != nil && err != io.EOF {
+			return fail(err)
+		}
+		if err == nil {
+			chunk = append(chunk, entry)
+			used += spillEntrySize(entry.token)
+		}
+
+		if len(chunk) > 0 && (err == io.EOF || (s.budget > 0 && used > s.budget)) {
+			slices.SortFunc(chunk, compareRunCounts)
+			path, werr := s.writeRun(chunk)
+			if werr != nil {
+				return fail(werr)
+			}
+			result = append(result, path)
+			chunk = chunk[:0]
+			used = 0
+		}
+
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+// createRun creates a new run file in the temporary directory and creates the directory if needed.
+func (s *tableSpill) createRun() (*os.File, error) {
+	if s.tmpDir == "" {
+		dir, err := os.MkdirTemp(s.dir, "ngrams-spill-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the directory for spilling the frequency table. %w", err)
+		}
+		s.tmpDir = dir
+	}
+
+	f, err := os.CreateTemp(s.tmpDir, "run-*.csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the run file in %q. %w", s.tmpDir, err)
+	}
+	return f, nil
+}
+
+// writeRun writes the entries to a new run file and returns the path.
+func (s *tableSpill) writeRun(entries []runEntry) (string, error) {
+	f, err := s.createRun()
+	if err != nil {
+		return "", err
+	}
+
+	rw := newRunWriter(f)
+	for _, entry := range entries {
+		if err := rw.write(entry); err != nil {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+			return "", err
+		}
+	}
+	if err := rw.close(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// compareRunTokens orders the entries by token.
+func compareRunTokens(a runEntry, b runEntry) int {
+	return strings.Compare(a.token, b.token)
+}
+
+// compareRunCounts orders the entries by count (descending) and then by token, the same order used by
+// [FrequencyTable.EntriesSortedByCount].
+func compareRunCounts(a runEntry, b runEntry) int {
+	if a.count > b.count {
+		return -1
+	} else if a.count < b.count {
+		return 1
+	}
+	return strings.Compare(a.token, b.token)
+}
+
+//-----------------------------------------------------------------------------
+
+// runWriter writes the entries of a run as CSV rows: token,count.
+type runWriter struct {
+	f    *os.File
+	bufW *bufio.Writer
+	csvW *csv.Writer
+}
+
+func newRunWriter(f *os.File) *runWriter {
+	bufW := bufio.NewWriter(f)
+	return &runWriter{f: f, bufW: bufW, csvW: csv.NewWriter(bufW)}
+}
+
+func (rw *runWriter) write(entry runEntry) error {
+	if err := rw.csvW.Write([]string{entry.token, strconv.FormatInt(entry.count, 10)}); err != nil {
+		return fmt.Errorf("failed to write the run %q. %w", rw.f.Name(), err)
+	}
+	return nil
+}
+
+func (rw *runWriter) close() error {
+	rw.csvW.Flush()
+	err := errors.Join(rw.csvW.Error(), rw.bufW.Flush(), rw.f.Close())
+	if err != nil {
+		return fmt.Errorf("failed to write the run %q. %w", rw.f.Name(), err)
+	}
+	return nil
+}
+
+// runReader reads the entries of a run written by [runWriter].
+type runReader struct {
+	csvR *csv.Reader
+}
+
+func newRunReader(r io.Reader) *runReader {
+	csvR := csv.NewReader(bufio.NewReader(r))
+	csvR.FieldsPerRecord = 2
+	return &runReader{csvR: csvR}
+}
+
+func (rr *runReader) read() (runEntry, error) {
+	record, err := rr.csvR.Read()
+	if err != nil {
+		if err == io.EOF {
+			return runEntry{}, err
+		}
+		return runEntry{}, fmt.Errorf("failed to read the run. %w", err)
+	}
+
+	count, err := strconv.ParseInt(record[1], 10, 64)
+	if err != nil {
+		return runEntry{}, fmt.Errorf("failed to parse the count of %q in the run. %w", record[0], err)
+	}
+	return runEntry{token: record[0], count: count}, nil
+}
+
+// mergeRunFiles merges the sorted runs (k-way merge) and calls fn with every entry in the order defined by cmp.
+// Each run is closed as soon as all of its entries have been merged. See [tableSpill.reduceRuns] to limit the
+// number of runs that are open at the same time.
+func mergeRunFiles(paths []string, cmp func(a runEntry, b runEntry) int, fn func(entry runEntry) error) error {
+	h := &runHeap{cmp: cmp}
+	defer func() {
+		for _, src := range h.sources {
+			_ = src.f.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open the run %q. %w", path, err)
+		}
+
+		src := &runSource{f: f, reader: newRunReader(f)}
+		ok, err := src.next()
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+		if ok {
+			h.sources = append(h.sources, src)
+		} else {
+			_ = f.Close()
+		}
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		src := h.sources[0]
+		if err := fn(src.entry); err != nil {
+			return err
+		}
+
+		ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+			_ = src.f.Close()
+		}
+	}
+	return nil
+}
+
+// runSource is the current entry of a run being merged.
+type runSource struct {
+	f      *os.File
+	reader *runReader
+	entry  runEntry
+}
+
+func (src *runSource) next() (bool, error) {
+	entry, err := src.reader.read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	src.entry = entry
+	return true, nil
+}
+
+// runHeap is a min-heap of the runs being merged ordered by their current entry.
+type runHeap struct {
+	sources []*runSource
+	cmp     func(a runEntry, b runEntry) int
+}
+
+func (h *runHeap) Len() int           { return len(h.sources) }
+func (h *runHeap) Less(i, j int) bool { return h.cmp(h.sources[i].entry, h.sources[j].entry) < 0 }
+func (h *runHeap) Swap(i, j int)      { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+
+func (h *runHeap) Push(x any) {
+	h.sources = append(h.sources, x.(*runSource))
+}
+
+func (h *runHeap) Pop() any {
+	n := len(h.sources)
+	src := h.sources[n-1]
+	h.sources = h.sources[:n-1]
+	return src
+}
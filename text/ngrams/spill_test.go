@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrequencyTableSpill(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	input, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(t, err)
+
+	expected := ngrams.NewFrequencyTable()
+	expected.SetMetadata("key", "value")
+	require.NoError(t, expected.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	expected.Update()
+	var expectedOut bytes.Buffer
+	require.NoError(t, expected.Save(&expectedOut))
+
+	dir := t.TempDir()
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMetadata("key", "value")
+	ft.SetMemoryBudget(2048, dir)
+	require.NoError(t, ft.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	assert.True(t, ft.Spilled())
+	assert.Less(t, ft.Len(), expected.Len())
+
+	var out bytes.Buffer
+	require.NoError(t, ft.Save(&out))
+	assert.Equal(t, expectedOut.String(), out.String())
+
+	// Saving again and adding after the runs have been merged
+	out.Reset()
+	require.NoError(t, ft.Save(&out))
+	assert.Equal(t, expectedOut.String(), out.String())
+
+	expected.Add("the end", 2)
+	expected.Update()
+	expectedOut.Reset()
+	require.NoError(t, expected.Save(&expectedOut))
+
+	ft.Add("the end", 2)
+	out.Reset()
+	require.NoError(t, ft.Save(&out))
+	assert.Equal(t, expectedOut.String(), out.String())
+
+	// The temporary files are removed
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, entries)
+	require.NoError(t, ft.Close())
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestFrequencyTableSpillManyRuns(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	input, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(t, err)
+
+	expected := ngrams.NewFrequencyTable()
+	require.NoError(t, expected.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	expected.Update()
+	var expectedOut bytes.Buffer
+	require.NoError(t, expected.Save(&expectedOut))
+
+	// Every entry is spilled to a run of its own and thus the runs are merged in several passes
+	dir := t.TempDir()
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMemoryBudget(1, dir)
+	require.NoError(t, ft.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	require.Greater(t, expected.Len(), 64)
+
+	var out bytes.Buffer
+	require.NoError(t, ft.Save(&out))
+	assert.Equal(t, expectedOut.String(), out.String())
+
+	// Only the combined run is left
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	runs, err := os.ReadDir(filepath.Join(dir, entries[0].Name()))
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+	require.NoError(t, ft.Close())
+}
+
+func TestFrequencyTableSpillEntriesAndMerge(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	input, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(t, err)
+
+	expected := ngrams.NewFrequencyTable()
+	require.NoError(t, expected.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	expected.Update()
+
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMemoryBudget(2048, t.TempDir())
+	require.NoError(t, ft.ParseWordTokens(context.Background(), bytes.NewReader(input), en, 2))
+	require.True(t, ft.Spilled())
+	defer ft.Close()
+
+	assert.Equal(t, expected.EntriesSortedByCount(), ft.EntriesSortedByCount())
+	assert.ElementsMatch(t, expected.Tokens(), ft.Tokens())
+
+	merged := ngrams.NewFrequencyTable()
+	require.NoError(t, merged.Merge(ft))
+	merged.Update()
+	assert.Equal(t, expected.EntriesSortedByCount(), merged.EntriesSortedByCount())
+}
+
+func TestFrequencyTableSpillNotNeeded(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMemoryBudget(1024*1024, t.TempDir())
+	ft.Add("a", 1)
+	assert.False(t, ft.Spilled())
+	assert.NoError(t, ft.Close())
+}
+
+func TestProcessorSetMemoryBudget(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	configs := []ngrams.ProcessorConfig{
+		{Mode: ngrams.ProcessLetters, Sizes: []int{3}},
+		{Mode: ngrams.ProcessWords, Sizes: []int{1, 2}},
+	}
+
+	expected := ngrams.NewFrequencyProcessorWithConfigs(en, configs)
+	require.NoError(t, expected.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	p := ngrams.NewFrequencyProcessorWithConfigs(en, configs)
+	p.SetMemoryBudget(8192, t.TempDir())
+	defer p.Close()
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	for _, cfg := range p.Configs() {
+		for _, size := range cfg.Sizes {
+			ft := p.FrequencyTableForTokenizer(cfg.Tokenizer, size)
+			assert.True(t, ft.Spilled())
+
+			var expectedOut, out bytes.Buffer
+			require.NoError(t, expected.FrequencyTableForTokenizer(cfg.Tokenizer, size).Save(&expectedOut))
+			require.NoError(t, ft.Save(&out))
+			assert.Equal(t, expectedOut.String(), out.String())
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/blevesearch/snowballstem"
+	"github.com/blevesearch/snowballstem/arabic"
+	"github.com/blevesearch/snowballstem/danish"
+	"github.com/blevesearch/snowballstem/dutch"
+	"github.com/blevesearch/snowballstem/english"
+	"github.com/blevesearch/snowballstem/finnish"
+	"github.com/blevesearch/snowballstem/french"
+	"github.com/blevesearch/snowballstem/german"
+	"github.com/blevesearch/snowballstem/spanish"
+	"github.com/blevesearch/snowballstem/swedish"
+)
+
+// MetadataStemmer is the frequency table metadata key used to record the language of the stemmer used.
+const MetadataStemmer = "stemmer"
+
+// Stemmer reduces a lowercase word to its stem. E.g. walks, walked and walking become walk.
+type Stemmer interface {
+	Stem(word string) string
+}
+
+// StemmerFunc is an adapter to allow the use of ordinary functions as a [Stemmer].
+type StemmerFunc func(word string) string
+
+// Stem calls f(word).
+func (f StemmerFunc) Stem(word string) string {
+	return f(word)
+}
+
+// snowballStemmers are the Snowball stemmers of the languages that have one.
+var snowballStemmers = map[alphabet.LanguageCode]func(env *snowballstem.Env) bool{
+	"ar": arabic.Stem,
+	"da": danish.Stem,
+	"de": german.Stem,
+	"en": english.Stem,
+	"es": spanish.Stem,
+	"fi": finnish.Stem,
+	"fr": french.Stem,
+	"nl": dutch.Stem,
+	"sv": swedish.Stem,
+}
+
+// BuiltinStemmer returns the built-in stemmer of the language.
+// Snowball stemmers are used for ar, da, de, en, es, fi, fr, nl and sv and a light suffix stripping
+// stemmer is used for af.
+func BuiltinStemmer(code alphabet.LanguageCode) (Stemmer, error) {
+	if code == "af" {
+		return StemmerFunc(stemAfrikaans), nil
+	}
+
+	stem, exists := snowballStemmers[code]
+	if !exists {
+		return nil, fmt.Errorf("no built-in stemmer for the language %q", code)
+	}
+
+	return StemmerFunc(func(word string) string {
+		env := snowballstem.NewEnv(word)
+		stem(env)
+		return env.Current()
+	}), nil
+}
+
+// afrikaansSuffixes are the inflectional suffixes removed by the Afrikaans stemmer. Longest first.
+var afrikaansSuffixes = []string{"etjies", "tjies", "etjie", "tjie", "jies", "jie", "ste", "ers", "er", "e", "s"}
+
+// stemAfrikaans is a light stemmer for Afrikaans that removes the past participle prefix ge- and
+// the most common plural, diminutive, comparative and superlative suffixes.
+func stemAfrikaans(word string) string {
+	const minStem = 3
+
+	if rest, found := strings.CutPrefix(word, "ge"); found && utf8.RuneCountInString(rest) > minStem {
+		word = rest
+	}
+
+	for _, suffix := range afrikaansSuffixes {
+		stem, found := strings.CutSuffix(word, suffix)
+		if !found || utf8.RuneCountInString(stem) < minStem {
+			continue
+		}
+
+		// Undouble the final consonant. E.g. katte -> katt -> kat
+		last, size := utf8.DecodeLastRuneInString(stem)
+		prev, _ := utf8.DecodeLastRuneInString(stem[:len(stem)-size])
+		if last == prev && !strings.ContainsRune("aeiouy", last) {
+			stem = stem[:len(stem)-size]
+		}
+		return stem
+	}
+	return word
+}
+
+// splitWord splits the word into the leading punctuation, the core of the word and the trailing punctuation.
+func splitWord(word string) (string, string, string) {
+	isPunct := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}
+
+	start := strings.IndexFunc(word, func(r rune) bool { return !isPunct(r) })
+	if start < 0 {
+		return word, "", ""
+	}
+	end := strings.LastIndexFunc(word, func(r rune) bool { return !isPunct(r) })
+	_, size := utf8.DecodeRuneInString(word[end:])
+	end += size
+
+	return word[:start], word[start:end], word[end:]
+}
+
+//-----------------------------------------------------------------------------
+
+// StemForms records the surface forms of the words that were reduced to each stem so that the
+// stems can be replaced by the most frequent surface form. It is safe for concurrent use.
+type StemForms struct {
+	mu    sync.Mutex
+	forms map[string]map[string]int
+}
+
+// NewStemForms creates a new [StemForms].
+func NewStemForms() *StemForms {
+	return &StemForms{
+		forms: make(map[string]map[string]int),
+	}
+}
+
+// Add records that the surface form was reduced to the stem.
+func (s *StemForms) Add(stem string, surface string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forms, exists := s.forms[stem]
+	if !exists {
+		forms = make(map[string]int)
+		s.forms[stem] = forms
+	}
+	forms[surface]++
+}
+
+// SurfaceForm returns the most frequent surface form of the stem and whether the stem is known.
+// When surface forms are equally frequent then the first in sorted order is returned.
+func (s *StemForms) SurfaceForm(stem string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	forms, exists := s.forms[stem]
+	if !exists {
+		return "", false
+	}
+
+	result := ""
+	count := 0
+	for surface, c := range forms {
+		if c > count || (c == count && surface < result) {
+			result = surface
+			count = c
+		}
+	}
+	return result, true
+}
+
+// ApplyToTable returns a new frequency table in which the stems of the word ngram tokens have been replaced
+// by their most frequent surface form. The counts of tokens that are replaced by the same token are combined
+// and the metadata is copied.
+//
+// When the table has spilled to disk (see [FrequencyTable.SetMemoryBudget]) then all of the entries are
+// rewritten and the new table spills within the same memory budget. Call [FrequencyTable.Close] on the new
+// table to remove its temporary files.
+func (s *StemForms) ApplyToTable(ft *FrequencyTable) (*FrequencyTable, error) {
+	result := NewFrequencyTable()
+	for _, key := range ft.MetadataKeys() {
+		value, _ := ft.Metadata(key)
+		result.SetMetadata(key, value)
+	}
+
+	if budget, dir, spilled := ft.spillBudget(); spilled {
+		result.SetMemoryBudget(budget, dir)
+	}
+
+	err := ft.eachEntry(func(token string, count int64) {
+		result.addCount(s.replaceStems(token), uint64(count))
+	})
+	if err != nil {
+		_ = result.Close()
+		return nil, fmt.Errorf("failed to replace the stems of the frequency table. %w", err)
+	}
+
+	result.Update()
+	return result, nil
+}
+
+// replaceStems replaces each word (separated by a space) of the token with the surface form of the stem.
+func (s *StemForms) replaceStems(token string) string {
+	words := strings.Split(token, " ")
+	for i, word := range words {
+		prefix, core, suffix := splitWord(word)
+		if surface, exists := s.SurfaceForm(core); exists {
+			words[i] = prefix + surface + suffix
+		}
+	}
+	return strings.Join(words, " ")
+}
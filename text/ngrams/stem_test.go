@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinStemmer(t *testing.T) {
+	testCases := []struct {
+		code     alphabet.LanguageCode
+		word     string
+		expected string
+	}{
+		{code: "en", word: "walking", expected: "walk"},
+		{code: "en", word: "walked", expected: "walk"},
+		{code: "de", word: "häuser", expected: "haus"},
+		{code: "nl", word: "huizen", expected: "huiz"},
+		{code: "fr", word: "continuellement", expected: "continuel"},
+		{code: "es", word: "corriendo", expected: "corr"},
+		{code: "sv", word: "flickorna", expected: "flick"},
+		{code: "da", word: "pigerne", expected: "pig"},
+		{code: "fi", word: "taloissa", expected: "talo"},
+		{code: "af", word: "katte", expected: "kat"},
+		{code: "af", word: "mannetjie", expected: "man"},
+		{code: "af", word: "gewerk", expected: "werk"},
+		{code: "af", word: "mooiste", expected: "mooi"},
+		{code: "af", word: "kat", expected: "kat"},
+	}
+	for _, tC := range testCases {
+		t.Run(string(tC.code)+" "+tC.word, func(t *testing.T) {
+			stemmer, err := ngrams.BuiltinStemmer(tC.code)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, stemmer.Stem(tC.word))
+		})
+	}
+
+	_, err := ngrams.BuiltinStemmer("xx")
+	assert.ErrorContains(t, err, `no built-in stemmer for the language "xx"`)
+}
+
+func TestParseWordTokensStemmer(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	stemmer, err := ngrams.BuiltinStemmer("en")
+	require.NoError(t, err)
+	input := "Walking home, he walked and walks (walking)."
+
+	testCases := []struct {
+		desc     string
+		size     int
+		opts     []ngrams.ParseOption
+		expected []string
+	}{
+		{desc: "stems", size: 1,
+			opts:     []ngrams.ParseOption{ngrams.WithStemmer(stemmer)},
+			expected: []string{"walk", "home,", "he", "walk", "and", "walk", "(walk)."}},
+		{desc: "bigrams", size: 2,
+			opts:     []ngrams.ParseOption{ngrams.WithStemmer(stemmer)},
+			expected: []string{"walk home,", "home, he", "he walk", "walk and", "and walk", "walk (walk)."}},
+		{desc: "stop words matched before stemming", size: 1,
+			opts: []ngrams.ParseOption{ngrams.WithStemmer(stemmer),
+				ngrams.WithStopWords(ngrams.NewWordList("he", "and"), ngrams.StopWordsRemove)},
+			expected: []string{"walk", "home,", "walk", "walk", "(walk)."}},
+		{desc: "drop any stop words", size: 2,
+			opts: []ngrams.ParseOption{ngrams.WithStemmer(stemmer),
+				ngrams.WithStopWords(ngrams.NewWordList("walking"), ngrams.StopWordsDropAny)},
+			expected: []string{"home, he", "he walk", "walk and", "and walk"}},
+		{desc: "drop all stop words", size: 2,
+			opts: []ngrams.ParseOption{ngrams.WithStemmer(stemmer),
+				ngrams.WithStopWords(ngrams.NewWordList("walking", "home"), ngrams.StopWordsDropAll)},
+			expected: []string{"home, he", "he walk", "walk and", "and walk", "walk (walk)."}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			err := ngrams.ParseWordTokens(context.Background(), strings.NewReader(input), en, tC.size,
+				func(token string, err error) error {
+					require.NoError(t, err)
+					result = append(result, token)
+					return nil
+				}, tC.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestStemFormsApplyToTable(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	stemmer, err := ngrams.BuiltinStemmer("en")
+	require.NoError(t, err)
+
+	forms := ngrams.NewStemForms()
+	ft := ngrams.NewFrequencyTable()
+	ft.SetMetadata("key", "value")
+	err = ft.ParseWordTokens(context.Background(), strings.NewReader("walked walking walked home. Walked"), en, 1,
+		ngrams.WithStemmer(stemmer), ngrams.WithStemForms(forms))
+	require.NoError(t, err)
+
+	walk, exists := ft.Get("walk")
+	require.True(t, exists)
+	assert.Equal(t, int64(4), walk.Count)
+
+	surface, exists := forms.SurfaceForm("walk")
+	require.True(t, exists)
+	assert.Equal(t, "walked", surface)
+	_, exists = forms.SurfaceForm("run")
+	assert.False(t, exists)
+
+	result, err := forms.ApplyToTable(ft)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"home.", "walked"}, result.Tokens())
+	walked, _ := result.Get("walked")
+	assert.Equal(t, int64(4), walked.Count)
+	value, _ := result.Metadata("key")
+	assert.Equal(t, "value", value)
+}
+
+func TestStemFormsApplyToSpilledTable(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	stemmer, err := ngrams.BuiltinStemmer("en")
+	require.NoError(t, err)
+
+	parse := func(budget uint64) (*ngrams.FrequencyTable, *ngrams.StemForms) {
+		forms := ngrams.NewStemForms()
+		ft := ngrams.NewFrequencyTable()
+		ft.SetMemoryBudget(budget, t.TempDir())
+		f, err := os.Open("testdata/en-alice-partial.txt")
+		require.NoError(t, err)
+		defer f.Close()
+		require.NoError(t, ft.ParseWordTokens(context.Background(), f, en, 2,
+			ngrams.WithStemmer(stemmer), ngrams.WithStemForms(forms)))
+		return ft, forms
+	}
+
+	ft, forms := parse(0)
+	expected, err := forms.ApplyToTable(ft)
+	require.NoError(t, err)
+
+	ft, forms = parse(2048)
+	defer ft.Close()
+	require.True(t, ft.Spilled())
+	result, err := forms.ApplyToTable(ft)
+	require.NoError(t, err)
+	defer result.Close()
+	assert.True(t, result.Spilled())
+
+	var expectedBuf, resultBuf bytes.Buffer
+	require.NoError(t, expected.Save(&expectedBuf))
+	require.NoError(t, result.Save(&resultBuf))
+	assert.Equal(t, expectedBuf.String(), resultBuf.String())
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"bufio"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+)
+
+// WordList is a set of words used to filter the words before word ngrams are formed.
+// E.g. stop words, allow or deny lists.
+type WordList map[string]struct{}
+
+// NewWordList creates a new word list containing the lowercase versions of the words.
+func NewWordList(words ...string) WordList {
+	l := make(WordList, len(words))
+	l.Add(words...)
+	return l
+}
+
+// Add the lowercase versions of the words to the list.
+func (l WordList) Add(words ...string) {
+	for _, word := range words {
+		l[strings.ToLower(word)] = struct{}{}
+	}
+}
+
+// Contains returns true if the (lowercase) word is part of the list.
+func (l WordList) Contains(word string) bool {
+	_, exists := l[word]
+	return exists
+}
+
+// Len returns the number of words in the list.
+func (l WordList) Len() int {
+	return len(l)
+}
+
+// LoadWordList parses a list of words from an io.Reader.
+//
+// Expected format in UTF-8: one or more words per line separated by whitespace.
+// Lines starting with a # is ignored.
+func LoadWordList(r io.Reader) (WordList, error) {
+	result := NewWordList()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result.Add(strings.Fields(line)...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse the word list. %w", err)
+	}
+	return result, nil
+}
+
+// LoadWordListFromFile parses a list of words from a UTF-8 encoded text file.
+// See [LoadWordList] for more details.
+func LoadWordListFromFile(path string) (WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q. %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to close %q. %v", path, err)
+		}
+	}()
+
+	result, err := LoadWordList(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the word list from %q. %w", path, err)
+	}
+	return result, nil
+}
+
+//go:embed stopwords/*.txt
+var builtinStopWords embed.FS
+
+// BuiltinStopWords returns the built-in stop words of the language. Stop words are available for
+// all of the languages in [alphabet.BuiltinLanguages].
+func BuiltinStopWords(code alphabet.LanguageCode) (WordList, error) {
+	f, err := builtinStopWords.Open(fmt.Sprintf("stopwords/%s.txt", code))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("no built-in stop words for the language %q", code)
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadWordList(f)
+}
+
+//-----------------------------------------------------------------------------
+
+// StopWordFilter specifies how the word tokenizer treats stop words.
+type StopWordFilter int
+
+const (
+	// StopWordsRemove removes the stop words before the word ngrams are formed.
+	StopWordsRemove StopWordFilter = iota
+	// StopWordsDropAny drops the word ngrams that contain any stop word.
+	StopWordsDropAny
+	// StopWordsDropAll drops the word ngrams that only consist of stop words.
+	StopWordsDropAll
+)
+
+var stopWordFilterNames = []string{"remove", "any", "all"}
+
+// String returns the name of the stop word filter.
+func (f StopWordFilter) String() string {
+	if f < 0 || int(f) >= len(stopWordFilterNames) {
+		return fmt.Sprintf("StopWordFilter(%d)", int(f))
+	}
+	return stopWordFilterNames[f]
+}
+
+// ParseStopWordFilter returns the stop word filter for the given name (remove, any or all).
+func ParseStopWordFilter(name string) (StopWordFilter, error) {
+	for i, n := range stopWordFilterNames {
+		if strings.EqualFold(n, name) {
+			return StopWordFilter(i), nil
+		}
+	}
+	return StopWordsRemove, fmt.Errorf("invalid stop word filter %q", name)
+}
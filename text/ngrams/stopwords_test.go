@@ -0,0 +1,153 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWordList(t *testing.T) {
+	list, err := ngrams.LoadWordList(strings.NewReader("# comment\nThe a\n\n  of  \n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, list.Len())
+	assert.True(t, list.Contains("the"))
+	assert.True(t, list.Contains("a"))
+	assert.True(t, list.Contains("of"))
+	assert.False(t, list.Contains("# comment"))
+}
+
+func TestLoadWordListFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	require.NoError(t, os.WriteFile(path, []byte("alice\nrabbit\n"), 0644))
+
+	list, err := ngrams.LoadWordListFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, ngrams.NewWordList("rabbit", "Alice"), list)
+
+	_, err = ngrams.LoadWordListFromFile(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.ErrorContains(t, err, "failed to open")
+}
+
+func TestBuiltinStopWords(t *testing.T) {
+	for code := range alphabet.BuiltinLanguages() {
+		list, err := ngrams.BuiltinStopWords(code)
+		require.NoError(t, err, code)
+		assert.Greater(t, list.Len(), 10, code)
+	}
+
+	en, err := ngrams.BuiltinStopWords("en")
+	require.NoError(t, err)
+	assert.True(t, en.Contains("the"))
+
+	_, err = ngrams.BuiltinStopWords("golang")
+	assert.ErrorContains(t, err, "no built-in stop words for the language \"golang\"")
+}
+
+func TestParseStopWordFilter(t *testing.T) {
+	for _, filter := range []ngrams.StopWordFilter{ngrams.StopWordsRemove, ngrams.StopWordsDropAny, ngrams.StopWordsDropAll} {
+		parsed, err := ngrams.ParseStopWordFilter(strings.ToUpper(filter.String()))
+		require.NoError(t, err)
+		assert.Equal(t, filter, parsed)
+	}
+
+	_, err := ngrams.ParseStopWordFilter("some")
+	assert.ErrorContains(t, err, "invalid stop word filter \"some\"")
+	assert.Equal(t, "StopWordFilter(5)", ngrams.StopWordFilter(5).String())
+}
+
+func TestParseWordTokensWordLists(t *testing.T) {
+	en := alphabet.MustBuiltin("en")
+	stopWords := ngrams.NewWordList("of", "the", "in")
+	input := "The king of the hill, in the end."
+
+	testCases := []struct {
+		desc     string
+		size     int
+		opts     []ngrams.ParseOption
+		expected []string
+	}{
+		{desc: "no filter", size: 2,
+			expected: []string{"the king", "king of", "of the", "the hill,", "hill, in", "in the", "the end."}},
+		{desc: "remove stop words", size: 2,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsRemove)},
+			expected: []string{"king hill,", "hill, end."}},
+		{desc: "drop any", size: 2,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsDropAny)},
+			expected: []string{}},
+		{desc: "drop any monograms", size: 1,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsDropAny)},
+			expected: []string{"king", "hill,", "end."}},
+		{desc: "drop all", size: 2,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsDropAll)},
+			expected: []string{"the king", "king of", "the hill,", "hill, in", "the end."}},
+		{desc: "drop all skip-grams", size: 2,
+			opts: []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsDropAll), ngrams.WithSkip(1)},
+			expected: []string{"the king", "king of", "king _ the", "the hill,", "of _ hill,",
+				"hill, in", "hill, _ the", "the end.", "in _ end."}},
+		{desc: "deny list", size: 1,
+			opts:     []ngrams.ParseOption{ngrams.WithDenyList(ngrams.NewWordList("king", "hill"))},
+			expected: []string{"the", "of", "the", "in", "the", "end."}},
+		{desc: "allow list", size: 2,
+			opts:     []ngrams.ParseOption{ngrams.WithAllowList(ngrams.NewWordList("king", "hill", "end"))},
+			expected: []string{"king hill,", "hill, end."}},
+		{desc: "case preserved", size: 1,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(stopWords, ngrams.StopWordsRemove), ngrams.WithCase(ngrams.CasePreserve)},
+			expected: []string{"king", "hill,", "end."}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			err := ngrams.ParseWordTokens(context.Background(), strings.NewReader(input), en, tC.size,
+				func(token string, err error) error {
+					require.NoError(t, err)
+					result = append(result, token)
+					return nil
+				}, tC.opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestProcessorStopWords(t *testing.T) {
+	stopWords, err := ngrams.BuiltinStopWords("en")
+	require.NoError(t, err)
+
+	p := ngrams.NewFrequencyProcessor(ngrams.ProcessWords, alphabet.MustBuiltin("en"), 2,
+		ngrams.WithStopWords(stopWords, ngrams.StopWordsDropAny))
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}))
+
+	ft := p.FrequencyTable()
+	assert.Greater(t, ft.Len(), 0)
+	_, exists := ft.Get("of the")
+	assert.False(t, exists)
+	_, exists = ft.Get("in the")
+	assert.False(t, exists)
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenStore stores the counts of the tokens in a frequency table.
+//
+// Short tokens of up to 3 runes (e.g. letter monograms, bigrams and trigrams) are packed into integer keys so
+// that no string needs to be kept for them. All other tokens (e.g. words) are interned, i.e. the map key is the
+// only copy of the token that is kept. Counts are stored as uint64 and the percentages are derived from the
+// counts when needed.
+type tokenStore struct {
+	packed map[uint64]uint64
+	strs   map[string]uint64
+}
+
+func newTokenStore() tokenStore {
+	return tokenStore{
+		packed: make(map[uint64]uint64),
+		strs:   make(map[string]uint64),
+	}
+}
+
+const (
+	// packedRunes is the maximum number of runes that can be packed into a key.
+	packedRunes = 3
+	// packedBits is the number of bits used per rune. Runes are stored as r+1 so that 0 marks an unused slot.
+	packedBits = 21
+	packedMask = 1<<packedBits - 1
+)
+
+// packToken packs the runes of the token into an integer key and returns false if the token can not be packed.
+func packToken(token string) (uint64, bool) {
+	if token == "" || len(token) > packedRunes*utf8.UTFMax {
+		return 0, false
+	}
+
+	var key uint64
+	n := 0
+	for i := 0; i < len(token); {
+		r, size := rune(token[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(token[i:])
+			if r == utf8.RuneError && size == 1 {
+				// Invalid UTF-8 can not be restored from the rune
+				return 0, false
+			}
+		}
+		if n == packedRunes {
+			return 0, false
+		}
+		key = key<<packedBits | uint64(r+1)
+		n++
+		i += size
+	}
+	return key, true
+}
+
+// unpackToken restores the token from the packed key.
+func unpackToken(key uint64) string {
+	var runes [packedRunes]rune
+	n := 0
+	for ; key != 0; key >>= packedBits {
+		runes[n] = rune(key&packedMask) - 1
+		n++
+	}
+
+	var sb strings.Builder
+	sb.Grow(n * utf8.UTFMax)
+	for i := n - 1; i >= 0; i-- {
+		sb.WriteRune(runes[i])
+	}
+	return sb.String()
+}
+
+// len returns the number of tokens in the store.
+func (s *tokenStore) len() int {
+	return len(s.packed) + len(s.strs)
+}
+
+// get returns the count of the token and whether it exists.
+func (s *tokenStore) get(token string) (uint64, bool) {
+	if key, ok := packToken(token); ok {
+		count, exists := s.packed[key]
+		return count, exists
+	}
+	count, exists := s.strs[token]
+	return count, exists
+}
+
+// add increments the count of the token and returns true if the token is new.
+func (s *tokenStore) add(token string, count uint64) bool {
+	if key, ok := packToken(token); ok {
+		current, exists := s.packed[key]
+		s.packed[key] = current + count
+		return !exists
+	}
+
+	current, exists := s.strs[token]
+	s.strs[token] = current + count
+	return !exists
+}
+
+// set replaces the count of the token.
+func (s *tokenStore) set(token string, count uint64) {
+	if key, ok := packToken(token); ok {
+		s.packed[key] = count
+		return
+	}
+	s.strs[token] = count
+}
+
+// each calls fn for every token in the store. The order is not guaranteed.
+func (s *tokenStore) each(fn func(token string, count uint64)) {
+	for key, count := range s.packed {
+		fn(unpackToken(key), count)
+	}
+	for token, count := range s.strs {
+		fn(token, count)
+	}
+}
+
+// sum returns the sum of all the counts.
+func (s *tokenStore) sum() uint64 {
+	var result uint64
+	for _, count := range s.packed {
+		result += count
+	}
+	for _, count := range s.strs {
+		result += count
+	}
+	return result
+}
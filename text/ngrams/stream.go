@@ -0,0 +1,138 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/andrejacobs/go-analyse/internal/processor"
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+)
+
+// StreamedToken is an ngram token produced by a [TokenStreamer].
+type StreamedToken struct {
+	// Tokenizer is the name of the tokenizer that produced the ngram. E.g. letters or words.
+	Tokenizer string
+	// Size is the number of letters or words in the ngram.
+	Size  int
+	Token string
+	// Source is the path of the input file (or the file inside of a zip file) the ngram was read from.
+	Source string
+	// Offset is the byte offset in the input source of the rune that completed the ngram. For letter ngrams
+	// this is the last letter of the ngram and for word ngrams the whitespace following the last word.
+	// Ngrams completed by the end of the input have the size of the input as offset.
+	Offset int64
+}
+
+// StreamTokenFunc is called by the [TokenStreamer] for every ngram token produced, in the order they were
+// produced. If an error is returned then processing stops and the error is returned.
+type StreamTokenFunc func(token StreamedToken) error
+
+// TokenStreamer is used to stream the ngram tokens produced by letter, word or custom [Tokenizer]s from input
+// sources instead of counting them in frequency tables. E.g. for feeding a language model trainer.
+type TokenStreamer struct {
+	proc     *processor.Processor
+	language alphabet.Language
+	configs  []ProcessorConfig
+	opts     []ParseOption
+}
+
+// NewTokenStreamer creates a new streamer that runs each of the tokenizer configurations while only reading
+// the input sources once. Progress is not reported.
+// Configurations with the same tokenizer are merged. The optional [ParseOption]s are passed along to all the tokenizers.
+func NewTokenStreamer(language alphabet.Language, configs []ProcessorConfig, opts ...ParseOption) *TokenStreamer {
+	return &TokenStreamer{
+		proc:     processor.NewProcessor(),
+		language: language,
+		configs:  mergeConfigs(configs),
+		opts:     opts,
+	}
+}
+
+// SetProgressReporter sets the progress reporter to use.
+func (s *TokenStreamer) SetProgressReporter(reporter processor.ProgressReporter) {
+	s.proc.SetProgressReporter(reporter)
+}
+
+// Configs returns the tokenizer configurations used by the streamer.
+func (s *TokenStreamer) Configs() []ProcessorConfig {
+	return s.configs
+}
+
+// ProcessFiles parses the ngrams produced by the tokenizers from the given input paths and passes each of them
+// to recv. Any [TokenFilter] (see [WithFilter]) is applied before the tokens are passed on and any
+// [InputFilter] (see [WithInputFilter]) is applied to each input source.
+func (s *TokenStreamer) ProcessFiles(ctx context.Context, paths []string, recv StreamTokenFunc) error {
+	factories, err := lookupFactories(s.configs)
+	if err != nil {
+		return err
+	}
+
+	opt := applyParseOptions(s.opts)
+	return s.proc.ProcessFiles(ctx, paths, func(ctx context.Context, r io.Reader) error {
+		return s.stream(ctx, opt.filterInput(ctx, r), processor.Source(ctx), factories, recv)
+	})
+}
+
+// Stream parses the ngrams produced by the tokenizers from the io.Reader and passes each of them to recv.
+// The source is used to identify the input in the [StreamedToken]s.
+func (s *TokenStreamer) Stream(ctx context.Context, input io.Reader, source string, recv StreamTokenFunc) error {
+	factories, err := lookupFactories(s.configs)
+	if err != nil {
+		return err
+	}
+	return s.stream(ctx, input, source, factories, recv)
+}
+
+func (s *TokenStreamer) stream(ctx context.Context, input io.Reader, source string,
+	factories []TokenizerFactory, recv StreamTokenFunc) error {
+
+	opt := applyParseOptions(s.opts)
+	filter := opt.filter
+	var offset int64
+
+	emitters := make([]EmitFunc, 0, len(s.configs))
+	for _, cfg := range s.configs {
+		name := cfg.Tokenizer
+		emitters = append(emitters, func(size int, token string) error {
+			if filter != nil {
+				var keep bool
+				if token, keep = filter.Apply(token); !keep {
+					return nil
+				}
+			}
+			return recv(StreamedToken{Tokenizer: name, Size: size, Token: token, Source: source, Offset: offset})
+		})
+	}
+
+	tok := newConfigTokenizer(s.language, s.configs, factories, emitters, s.opts)
+	tok = opt.withCoverage(tok, s.language, source)
+	if err := tokenizeWithOffset(ctx, input, tok, emitters[0], &offset); err != nil {
+		var consumerErr *consumerError
+		if errors.As(err, &consumerErr) {
+			return consumerErr.err
+		}
+		return fmt.Errorf("failed to parse the %s tokens. %w", configsDescription(s.configs), err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStreamerStream(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		configs  []ngrams.ProcessorConfig
+		input    string
+		expected []ngrams.StreamedToken
+	}{
+		{
+			desc:    "letter bigrams",
+			configs: []ngrams.ProcessorConfig{{Mode: ngrams.ProcessLetters, Sizes: []int{2}}},
+			input:   "The fox",
+			expected: []ngrams.StreamedToken{
+				{Tokenizer: "letters", Size: 2, Token: "th", Source: "input", Offset: 1},
+				{Tokenizer: "letters", Size: 2, Token: "he", Source: "input", Offset: 2},
+				{Tokenizer: "letters", Size: 2, Token: "fo", Source: "input", Offset: 5},
+				{Tokenizer: "letters", Size: 2, Token: "ox", Source: "input", Offset: 6},
+			},
+		},
+		{
+			desc:    "words",
+			configs: []ngrams.ProcessorConfig{{Mode: ngrams.ProcessWords, Sizes: []int{1, 2}}},
+			input:   "the fox\njumps",
+			expected: []ngrams.StreamedToken{
+				{Tokenizer: "words", Size: 1, Token: "the", Source: "input", Offset: 3},
+				{Tokenizer: "words", Size: 1, Token: "fox", Source: "input", Offset: 7},
+				{Tokenizer: "words", Size: 2, Token: "the fox", Source: "input", Offset: 7},
+				{Tokenizer: "words", Size: 1, Token: "jumps", Source: "input", Offset: 13},
+				{Tokenizer: "words", Size: 2, Token: "fox jumps", Source: "input", Offset: 13},
+			},
+		},
+		{
+			desc: "letters and words",
+			configs: []ngrams.ProcessorConfig{
+				{Mode: ngrams.ProcessLetters, Sizes: []int{1}},
+				{Mode: ngrams.ProcessWords, Sizes: []int{1}},
+			},
+			input: "añ b",
+			expected: []ngrams.StreamedToken{
+				{Tokenizer: "letters", Size: 1, Token: "a", Source: "input", Offset: 0},
+				{Tokenizer: "words", Size: 1, Token: "añ", Source: "input", Offset: 3},
+				{Tokenizer: "letters", Size: 1, Token: "b", Source: "input", Offset: 4},
+				{Tokenizer: "words", Size: 1, Token: "b", Source: "input", Offset: 5},
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := ngrams.NewTokenStreamer(alphabet.MustBuiltin("en"), tC.configs)
+
+			result := make([]ngrams.StreamedToken, 0, len(tC.expected))
+			err := s.Stream(context.Background(), strings.NewReader(tC.input), "input", func(token ngrams.StreamedToken) error {
+				result = append(result, token)
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestTokenStreamerProcessFiles(t *testing.T) {
+	configs := []ngrams.ProcessorConfig{{Mode: ngrams.ProcessWords, Sizes: []int{1}}}
+	s := ngrams.NewTokenStreamer(alphabet.MustBuiltin("en"), configs)
+
+	ft := ngrams.NewFrequencyTable()
+	err := s.ProcessFiles(context.Background(), []string{"testdata/en-alice-partial.txt"}, func(token ngrams.StreamedToken) error {
+		assert.Equal(t, "testdata/en-alice-partial.txt", token.Source)
+		ft.Add(token.Token, 1)
+		return nil
+	})
+	require.NoError(t, err)
+	ft.Update()
+
+	expected, err := ngrams.LoadFrequenciesFromFile("testdata/freq-1w-en-alice.csv")
+	require.NoError(t, err)
+	compareTwoFrequencyTables(t, expected, ft)
+}
+
+func TestTokenStreamerFilterAndErrors(t *testing.T) {
+	filter := ngrams.NewTokenFilter()
+	filter.RewriteNumbers()
+	s := ngrams.NewTokenStreamer(alphabet.MustBuiltin("en"),
+		[]ngrams.ProcessorConfig{{Mode: ngrams.ProcessWords, Sizes: []int{1}}}, ngrams.WithFilter(filter))
+
+	tokens := make([]string, 0, 2)
+	err := s.Stream(context.Background(), strings.NewReader("in 1865"), "", func(token ngrams.StreamedToken) error {
+		tokens = append(tokens, token.Token)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"in", "<NUM>"}, tokens)
+
+	errStop := errors.New("stop")
+	err = s.Stream(context.Background(), strings.NewReader("in 1865"), "", func(token ngrams.StreamedToken) error {
+		return errStop
+	})
+	assert.ErrorIs(t, err, errStop)
+
+	s = ngrams.NewTokenStreamer(alphabet.MustBuiltin("en"), []ngrams.ProcessorConfig{{Tokenizer: "missing", Sizes: []int{1}}})
+	err = s.Stream(context.Background(), strings.NewReader("in"), "", func(token ngrams.StreamedToken) error {
+		return nil
+	})
+	assert.Error(t, err)
+}
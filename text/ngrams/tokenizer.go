@@ -0,0 +1,273 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+)
+
+// ParseTokens is used to parse the ngram tokens produced by the [Tokenizer] from the io.Reader.
+func ParseTokens(ctx context.Context, input io.Reader, tok Tokenizer, recv RecvTokenFunc) error {
+	err := tokenize(ctx, input, tok, func(size int, token string) error {
+		return recv(token, nil)
+	})
+	if err != nil {
+		var consumerErr *consumerError
+		if errors.As(err, &consumerErr) {
+			return consumerErr.err
+		}
+		// Inform consumer of error
+		_ = recv("", err)
+		return err
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// EmitFunc is called by a [Tokenizer] for every ngram token of the given size that was produced.
+// If an error is returned then the tokenizer should stop and return the error.
+type EmitFunc func(size int, token string) error
+
+// Tokenizer produces ngram tokens from a stream of runes.
+// Tokenizers are fed one rune at a time so that the input only needs to be read and decoded once
+// even when ngrams of multiple sizes or multiple tokenizers are used.
+// A new tokenizer is created for every input source and thus does not need to be safe for concurrent use.
+type Tokenizer interface {
+	// Next consumes the next rune read from the input.
+	Next(r rune, emit EmitFunc) error
+	// End is called when the end of the input has been reached and resets the tokenizer.
+	End(emit EmitFunc) error
+}
+
+// consumerError wraps an error returned by the consumer of the tokens so that it can be
+// distinguished from errors encountered while reading the input.
+type consumerError struct {
+	err error
+}
+
+func (e *consumerError) Error() string {
+	return e.err.Error()
+}
+
+func (e *consumerError) Unwrap() error {
+	return e.err
+}
+
+// tokenize reads the runes from the input and feeds them to the tokenizer.
+func tokenize(ctx context.Context, input io.Reader, tok Tokenizer, emit EmitFunc) error {
+	return tokenizeWithOffset(ctx, input, tok, emit, nil)
+}
+
+// tokenizeWithOffset reads the runes from the input and feeds them to the tokenizer. If offset is not nil
+// then it is set to the byte offset in the input of the rune being fed to the tokenizer and to the size of
+// the input when the end has been reached.
+func tokenizeWithOffset(ctx context.Context, input io.Reader, tok Tokenizer, emit EmitFunc, offset *int64) error {
+	consumer := func(size int, token string) error {
+		if err := emit(size, token); err != nil {
+			return &consumerError{err: err}
+		}
+		return nil
+	}
+
+	if words, ok := tok.(wordConsumer); ok && offset == nil {
+		return tokenizeWords(ctx, input, words, tok, consumer)
+	}
+
+	rd := bufio.NewReader(input)
+	var pos int64
+
+	for i := 0; ; i++ {
+		// Checking the context for every rune is relatively expensive
+		if i%contextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		r, size, err := rd.ReadRune()
+		if offset != nil {
+			*offset = pos
+		}
+		if err != nil {
+			if err == io.EOF {
+				// Done reading
+				return tok.End(consumer)
+			}
+			return err
+		}
+
+		if err := tok.Next(r, consumer); err != nil {
+			return err
+		}
+		pos += int64(size)
+	}
+}
+
+// contextCheckInterval is the number of runes (or words) read between checking if the context was cancelled.
+const contextCheckInterval = 1024
+
+// wordConsumer is implemented by tokenizers that only need the words (separated by whitespace) of the input.
+// Splitting the input into words with a bufio.Scanner is a lot faster than feeding the tokenizer one rune at a time.
+type wordConsumer interface {
+	// nextWord consumes the next word read from the input.
+	nextWord(word string, emit EmitFunc) error
+}
+
+// tokenizeWords reads the words from the input and feeds them to the tokenizer.
+func tokenizeWords(ctx context.Context, input io.Reader, words wordConsumer, tok Tokenizer, emit EmitFunc) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Split(bufio.ScanWords)
+	// The words are not limited in length when fed one rune at a time
+	scanner.Buffer(nil, math.MaxInt)
+
+	for i := 0; scanner.Scan(); i++ {
+		if i%contextCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+
+		if err := words.nextWord(scanner.Text(), emit); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return tok.End(emit)
+}
+
+// multiTokenizer fans every rune out to multiple tokenizers so that the input only needs to be read once.
+// The tokens produced by a tokenizer are passed to its own emit function, at the same index, instead
+// of the emit function given to Next and End.
+type multiTokenizer struct {
+	tokenizers []Tokenizer
+	emitters   []EmitFunc
+}
+
+func newMultiTokenizer(tokenizers []Tokenizer, emitters []EmitFunc) *multiTokenizer {
+	t := &multiTokenizer{
+		tokenizers: tokenizers,
+		emitters:   make([]EmitFunc, len(emitters)),
+	}
+
+	for i, emit := range emitters {
+		t.emitters[i] = func(size int, token string) error {
+			if err := emit(size, token); err != nil {
+				return &consumerError{err: err}
+			}
+			return nil
+		}
+	}
+	return t
+}
+
+func (t *multiTokenizer) Next(r rune, _ EmitFunc) error {
+	for i, tok := range t.tokenizers {
+		if err := tok.Next(r, t.emitters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *multiTokenizer) End(_ EmitFunc) error {
+	for i, tok := range t.tokenizers {
+		if err := tok.End(t.emitters[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// Registry
+
+// TokenizerFactory creates a new [Tokenizer] that produces ngrams of the given sizes for the language.
+type TokenizerFactory func(language alphabet.Language, sizes []int, opts ...ParseOption) Tokenizer
+
+// Names of the built-in tokenizers.
+const (
+	TokenizerLetters = "letters"
+	TokenizerWords   = "words"
+)
+
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = map[string]TokenizerFactory{
+		TokenizerLetters: NewLetterTokenizer,
+		TokenizerWords:   NewWordTokenizer,
+	}
+)
+
+// RegisterTokenizer makes a tokenizer available by name. E.g. to be selected from the ngrams CLI.
+// An error is returned if the name is empty or a tokenizer with the name has already been registered.
+func RegisterTokenizer(name string, factory TokenizerFactory) error {
+	if name == "" {
+		return fmt.Errorf("invalid tokenizer name %q", name)
+	}
+	if factory == nil {
+		return fmt.Errorf("tokenizer %q has no factory", name)
+	}
+
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+
+	if _, exists := tokenizers[name]; exists {
+		return fmt.Errorf("tokenizer %q has already been registered", name)
+	}
+	tokenizers[name] = factory
+	return nil
+}
+
+// LookupTokenizer returns the factory of the tokenizer registered with the name.
+func LookupTokenizer(name string) (TokenizerFactory, error) {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+
+	factory, exists := tokenizers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown tokenizer %q", name)
+	}
+	return factory, nil
+}
+
+// TokenizerNames returns the sorted names of all the registered tokenizers.
+func TokenizerNames() []string {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+
+	names := make([]string, 0, len(tokenizers))
+	for name := range tokenizers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizerRegistry(t *testing.T) {
+	names := ngrams.TokenizerNames()
+	assert.Contains(t, names, ngrams.TokenizerLetters)
+	assert.Contains(t, names, ngrams.TokenizerWords)
+
+	_, err := ngrams.LookupTokenizer("unit-test-missing")
+	assert.ErrorContains(t, err, "unknown tokenizer \"unit-test-missing\"")
+
+	assert.ErrorContains(t, ngrams.RegisterTokenizer("", newNumberTokenizer), "invalid tokenizer name")
+	assert.ErrorContains(t, ngrams.RegisterTokenizer("unit-test-nil", nil), "has no factory")
+	assert.ErrorContains(t, ngrams.RegisterTokenizer(ngrams.TokenizerWords, newNumberTokenizer),
+		"tokenizer \"words\" has already been registered")
+
+	registerNumberTokenizer(t)
+	factory, err := ngrams.LookupTokenizer(numberTokenizerName)
+	require.NoError(t, err)
+	assert.NotNil(t, factory)
+	assert.Contains(t, ngrams.TokenizerNames(), numberTokenizerName)
+}
+
+func TestFrequencyTableParseTokens(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	tok := newNumberTokenizer(alphabet.MustBuiltin("en"), []int{1})
+	require.NoError(t, ft.ParseTokens(context.Background(), strings.NewReader("In 1865 and 1871, 42 books"), tok))
+	ft.Update()
+
+	assert.Equal(t, 3, ft.Len())
+	_, exists := ft.Get("1865")
+	assert.True(t, exists)
+	_, exists = ft.Get("42")
+	assert.True(t, exists)
+}
+
+func TestProcessorCustomTokenizer(t *testing.T) {
+	registerNumberTokenizer(t)
+
+	temp := filepath.Join(t.TempDir(), "in.txt")
+	require.NoError(t, os.WriteFile(temp, []byte("the 1st and 2nd, 1 and 2 then 1"), 0644))
+
+	p := ngrams.NewFrequencyProcessorWithConfigs(alphabet.MustBuiltin("en"), []ngrams.ProcessorConfig{
+		{Tokenizer: numberTokenizerName, Sizes: []int{1}},
+		{Mode: ngrams.ProcessWords, Sizes: []int{1}},
+	})
+	require.NoError(t, p.ProcessFiles(context.Background(), []string{temp}))
+
+	numbers := p.FrequencyTableForTokenizer(numberTokenizerName, 1)
+	require.NotNil(t, numbers)
+	freq, exists := numbers.Get("1")
+	assert.True(t, exists)
+	assert.Equal(t, int64(3), freq.Count)
+	freq, exists = numbers.Get("2")
+	assert.True(t, exists)
+	assert.Equal(t, int64(2), freq.Count)
+
+	words := p.FrequencyTableFor(ngrams.ProcessWords, 1)
+	freq, exists = words.Get("and")
+	assert.True(t, exists)
+	assert.Equal(t, int64(2), freq.Count)
+
+	p = ngrams.NewFrequencyProcessorWithConfigs(alphabet.MustBuiltin("en"), []ngrams.ProcessorConfig{
+		{Tokenizer: "unit-test-missing", Sizes: []int{1}},
+	})
+	assert.ErrorContains(t, p.ProcessFiles(context.Background(), []string{temp}), "unknown tokenizer")
+}
+
+//-----------------------------------------------------------------------------
+
+const numberTokenizerName = "unit-test-numbers"
+
+func registerNumberTokenizer(t *testing.T) {
+	if _, err := ngrams.LookupTokenizer(numberTokenizerName); err == nil {
+		return
+	}
+	require.NoError(t, ngrams.RegisterTokenizer(numberTokenizerName, newNumberTokenizer))
+}
+
+// numberTokenizer produces monograms of the numbers (sequences of digits) found in the input.
+type numberTokenizer struct {
+	number strings.Builder
+}
+
+func newNumberTokenizer(_ alphabet.Language, _ []int, _ ...ngrams.ParseOption) ngrams.Tokenizer {
+	return &numberTokenizer{}
+}
+
+func (t *numberTokenizer) Next(r rune, emit ngrams.EmitFunc) error {
+	if unicode.IsDigit(r) {
+		t.number.WriteRune(r)
+		return nil
+	}
+	return t.End(emit)
+}
+
+func (t *numberTokenizer) End(emit ngrams.EmitFunc) error {
+	if t.number.Len() == 0 {
+		return nil
+	}
+	token := t.number.String()
+	t.number.Reset()
+	return emit(1, token)
+}
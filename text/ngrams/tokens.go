@@ -20,13 +20,14 @@
 package ngrams
 
 import (
-	"bufio"
 	"context"
 	"io"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/andrejacobs/go-analyse/text/alphabet"
+	"github.com/rivo/uniseg"
 )
 
 // RecvTokenFunc will be called when a new token has been parsed from the input stream.
@@ -35,193 +36,577 @@ import (
 // If this function returns an error then it will indicate to the parser to stop the parsing process.
 type RecvTokenFunc func(token string, err error) error
 
-// ParseLetterTokens is used to parse ngrams for letter combinations of the given tokenSize and language from the io.Reader.
-func ParseLetterTokens(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int, recv RecvTokenFunc) error {
+// ParseOption is used to configure optional parsing behaviour.
+type ParseOption func(opt *parseOptions)
+
+type parseOptions struct {
+	graphemes      bool
+	caseMode       CaseMode
+	skip           int
+	stopWords      WordList
+	stopWordFilter StopWordFilter
+	allowList      WordList
+	denyList       WordList
+	filter         *TokenFilter
+	stemmer        Stemmer
+	stemForms      *StemForms
+	inputFilter    InputFilter
+	coverage       *Coverage
+	wordBoundaries bool
+}
+
+// WithGraphemes configures letters to be parsed as extended grapheme clusters (user-perceived characters)
+// instead of individual runes. The multi-rune letters of the language (e.g. Dutch ij) are also
+// matched and counted as a single letter.
+func WithGraphemes() ParseOption {
+	return func(opt *parseOptions) {
+		opt.graphemes = true
+	}
+}
+
+// WithCase configures how the case of letters and words are treated. The default is [CaseLower].
+func WithCase(mode CaseMode) ParseOption {
+	return func(opt *parseOptions) {
+		opt.caseMode = mode
+	}
+}
+
+// WithSkip configures the parser to produce k-skip-n-grams where up to skip letters or words may be skipped
+// in total between the ones that form a token. Each skipped letter or word is marked with the [SkipMarker].
+// For example the letter bigrams produced for "the" with a skip of 1 are: th, he and t_e.
+func WithSkip(skip int) ParseOption {
+	return func(opt *parseOptions) {
+		opt.skip = max(0, skip)
+	}
+}
+
+// WithStopWords configures the word parser to filter the stop words. The filter specifies whether the
+// stop words are removed before the word ngrams are formed or if the ngrams that contain any or
+// only stop words are dropped. See [BuiltinStopWords] and [LoadWordList].
+func WithStopWords(stopWords WordList, filter StopWordFilter) ParseOption {
+	return func(opt *parseOptions) {
+		opt.stopWords = stopWords
+		opt.stopWordFilter = filter
+	}
+}
+
+// WithAllowList configures the word parser to only keep the words in the list before the word ngrams are formed.
+func WithAllowList(words WordList) ParseOption {
+	return func(opt *parseOptions) {
+		opt.allowList = words
+	}
+}
+
+// WithDenyList configures the word parser to remove the words in the list before the word ngrams are formed.
+func WithDenyList(words WordList) ParseOption {
+	return func(opt *parseOptions) {
+		opt.denyList = words
+	}
+}
+
+// WithFilter configures the [TokenFilter] used to rewrite or drop the tokens before they are received
+// or added to a frequency table.
+func WithFilter(filter *TokenFilter) ParseOption {
+	return func(opt *parseOptions) {
+		opt.filter = filter
+	}
+}
 
-	if tokenSize == 1 {
-		return parseLetterMonograms(ctx, input, language, recv)
+// WithStemmer configures the words to be reduced to their stem before word ngrams are created.
+// Word lists are matched against the word before it is stemmed.
+func WithStemmer(stemmer Stemmer) ParseOption {
+	return func(opt *parseOptions) {
+		opt.stemmer = stemmer
 	}
+}
 
-	return parseLetterNgrams(ctx, input, language, tokenSize, recv)
+// WithStemForms configures the surface forms of the stemmed words to be recorded in forms.
+// This can be used with [StemForms.ApplyToTable] to output the most frequent surface form of each stem.
+func WithStemForms(forms *StemForms) ParseOption {
+	return func(opt *parseOptions) {
+		opt.stemForms = forms
+	}
+}
+
+// WithWordBoundaries configures the word parser to split the words on punctuation instead of only on whitespace.
+// Only the letters and numbers of a word are kept along with the apostrophes and hyphens of the language (see
+// [alphabet.Language.Apostrophes] and [alphabet.Language.Hyphens]) that are found between them. E.g. "Don’t,"
+// becomes don't and "well-known." becomes well-known. The apostrophes and hyphens are replaced with the first
+// one listed by the language so that the variants (e.g. don’t and don't) are counted as the same word.
+func WithWordBoundaries() ParseOption {
+	return func(opt *parseOptions) {
+		opt.wordBoundaries = true
+	}
+}
+
+// InputFilter wraps the io.Reader of an input source before it is tokenized. E.g. to drop the lines written in
+// another language. The context is the one of the input source being processed.
+type InputFilter func(ctx context.Context, r io.Reader) io.Reader
+
+// WithInputFilter configures the [InputFilter] applied to each input source processed by a [FrequencyProcessor]
+// or [TokenStreamer].
+func WithInputFilter(filter InputFilter) ParseOption {
+	return func(opt *parseOptions) {
+		opt.inputFilter = filter
+	}
+}
+
+// filterInput applies the input filter (if any) to the io.Reader.
+func (opt parseOptions) filterInput(ctx context.Context, r io.Reader) io.Reader {
+	if opt.inputFilter == nil {
+		return r
+	}
+	return opt.inputFilter(ctx, r)
+}
+
+// WithCoverage configures the statistics of the runes that are part of the alphabet of the language (and
+// those that are not) to be collected for each input source processed by a [FrequencyProcessor] or
+// [TokenStreamer]. Whitespace is not counted.
+func WithCoverage(coverage *Coverage) ParseOption {
+	return func(opt *parseOptions) {
+		opt.coverage = coverage
+	}
+}
+
+func applyParseOptions(opts []ParseOption) parseOptions {
+	var opt parseOptions
+	for _, apply := range opts {
+		apply(&opt)
+	}
+	return opt
+}
+
+// ParseLetterTokens is used to parse ngrams for letter combinations of the given tokenSize and language from the io.Reader.
+func ParseLetterTokens(ctx context.Context, input io.Reader, language alphabet.Language,
+	tokenSize int, recv RecvTokenFunc, opts ...ParseOption) error {
+
+	return ParseTokens(ctx, input, NewLetterTokenizer(language, []int{tokenSize}, opts...),
+		filterRecv(applyParseOptions(opts).filter, recv))
 }
 
 // ParseWordTokens is used to parse ngrams for word combinations of the given tokenSize and language from the io.Reader.
 func ParseWordTokens(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int, recv RecvTokenFunc) error {
-	return parseWordNgrams(ctx, input, language, tokenSize, recv)
+	tokenSize int, recv RecvTokenFunc, opts ...ParseOption) error {
+
+	return ParseTokens(ctx, input, NewWordTokenizer(language, []int{tokenSize}, opts...),
+		filterRecv(applyParseOptions(opts).filter, recv))
 }
 
-func parseLetterNgrams(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int, recv RecvTokenFunc) error {
+// filterRecv runs the received tokens through the filter (if any) before passing them on to recv.
+func filterRecv(filter *TokenFilter, recv RecvTokenFunc) RecvTokenFunc {
+	if filter == nil {
+		return recv
+	}
+	return func(token string, err error) error {
+		if err != nil {
+			return recv(token, err)
+		}
+		token, keep := filter.Apply(token)
+		if !keep {
+			return nil
+		}
+		return recv(token, nil)
+	}
+}
 
-	buf := make([]rune, tokenSize)
-	pos := 0
-	count := 0
+// NewLetterTokenizer creates the built-in [Tokenizer] that produces ngrams of letters (of each of the sizes)
+// that are part of the language. Whitespace ends an ngram and any runes not part of the language are ignored.
+func NewLetterTokenizer(language alphabet.Language, sizes []int, opts ...ParseOption) Tokenizer {
+	return newLetterTokenizer(language, sizes, applyParseOptions(opts))
+}
 
-	rd := bufio.NewReader(input)
+// NewWordTokenizer creates the built-in [Tokenizer] that produces ngrams of words (of each of the sizes).
+// Words are separated by whitespace.
+func NewWordTokenizer(language alphabet.Language, sizes []int, opts ...ParseOption) Tokenizer {
+	return newWordTokenizer(language, sizes, applyParseOptions(opts))
+}
 
-loop:
-	for {
-		select {
-		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				// Inform consumer of error
-				_ = recv("", err)
-				return err
-			}
-			break loop
-		default:
-			r, _, err := rd.ReadRune()
-			if err != nil {
-				if err == io.EOF {
-					// Done reading
-					break loop
-				}
-				// Inform consumer of error
-				_ = recv("", err)
-				return err
-			}
+// isSpace is the same as unicode.IsSpace but is cheap enough to be inlined for the ASCII runes.
+func isSpace(r rune) bool {
+	if r < utf8.RuneSelf {
+		return r == ' ' || ('\t' <= r && r <= '\r')
+	}
+	return unicode.IsSpace(r)
+}
 
-			// Ignore white space
-			if unicode.IsSpace(r) {
-				pos = 0
-				count = 0
-				continue
-			}
+//-----------------------------------------------------------------------------
+
+// letterTokenizer produces ngrams of letters. Whitespace ends an ngram and any runes not part
+// of the language are ignored.
+type letterTokenizer struct {
+	language alphabet.Language
+	caseMode CaseMode
+	window   *ngramWindow
+	// Cache of the runes already seen to avoid mapping the case and allocating a new string for every rune
+	ascii   [utf8.RuneSelf]cachedLetter
+	letters map[rune]cachedLetter
+
+	// Only used when parsing grapheme clusters.
+	// Runes are collected until whitespace is found and then split into letters
+	matcher *letterMatcher
+	word    strings.Builder
+}
 
-			r = unicode.ToLower(r)
+func newLetterTokenizer(language alphabet.Language, sizes []int, opt parseOptions) *letterTokenizer {
+	t := &letterTokenizer{
+		language: language,
+		caseMode: opt.caseMode,
+		window:   newNgramWindow(sizes, opt.skip, ""),
+		letters:  make(map[rune]cachedLetter),
+	}
+	if opt.graphemes {
+		t.matcher = newLetterMatcher(language, opt.caseMode)
+	}
+	return t
+}
 
-			// Ignore any runes not part of the language
-			if !language.ContainsRune(r) {
-				continue
-			}
+func (t *letterTokenizer) Next(r rune, emit EmitFunc) error {
+	// White space ends the ngram
+	if isSpace(r) {
+		return t.End(emit)
+	}
 
-			buf[pos+count] = r
-			count++
+	if t.matcher != nil {
+		t.word.WriteRune(r)
+		return nil
+	}
 
-			// Did we parse enough runes for a full token?
-			if count == tokenSize {
-				token := string(buf[pos:])
+	// Ignore any runes not part of the language
+	letter, ok := t.letter(r)
+	if !ok {
+		return nil
+	}
 
-				copy(buf[pos:], buf[pos+1:])
-				pos = 0
-				count = tokenSize - 1
+	return t.window.push(letter, emit)
+}
 
-				// Inform the consumer of a new token
-				err := recv(token, nil)
-				if err != nil {
-					return err
-				}
+// cachedLetter is the letter a rune was mapped to and whether the rune is part of the language.
+type cachedLetter struct {
+	letter string
+	ok     bool
+	cached bool
+}
+
+// letter returns the rune mapped to the case mode and reports if the rune is a letter of the language.
+func (t *letterTokenizer) letter(r rune) (string, bool) {
+	var cached cachedLetter
+	if r >= 0 && r < utf8.RuneSelf {
+		cached = t.ascii[r]
+	} else {
+		cached = t.letters[r]
+	}
+	if cached.cached {
+		return cached.letter, cached.ok
+	}
+
+	mapped, ok := t.caseMode.rune(t.language, r)
+	cached = cachedLetter{letter: string(mapped), ok: ok, cached: true}
+	if r >= 0 && r < utf8.RuneSelf {
+		t.ascii[r] = cached
+	} else {
+		t.letters[r] = cached
+	}
+	return cached.letter, cached.ok
+}
+
+func (t *letterTokenizer) End(emit EmitFunc) error {
+	if t.matcher != nil && t.word.Len() > 0 {
+		letters := t.matcher.letters(t.word.String())
+		t.word.Reset()
+
+		for _, letter := range letters {
+			if err := t.window.push(letter, emit); err != nil {
+				return err
 			}
 		}
 	}
 
+	t.window.reset()
 	return nil
 }
 
-func parseLetterMonograms(ctx context.Context, input io.Reader,
-	language alphabet.Language, recv RecvTokenFunc) error {
+//-----------------------------------------------------------------------------
+
+// wordTokenizer produces ngrams of words. Words are separated by whitespace and an ngram can span
+// across multiple lines.
+type wordTokenizer struct {
+	language alphabet.Language
+	mapCase  func(s string) string
+	window   *ngramWindow
+	// The runes of the current word. Reused between words to avoid growing a new buffer for every word
+	word []byte
+
+	stopWords      WordList
+	stopWordFilter StopWordFilter
+	allowList      WordList
+	denyList       WordList
+	// Whether each of the words in the window (oldest first) is a stop word. Stop words are matched against
+	// the word before it is stemmed and thus can not be matched against the words of the ngrams.
+	stops []bool
+
+	stemmer   Stemmer
+	stemForms *StemForms
+
+	// Split the words on punctuation (see WithWordBoundaries). The apostrophes and hyphens of the language
+	// are replaced by the first one of each. Zero if the language does not have any.
+	boundaries bool
+	apostrophe rune
+	hyphen     rune
+	// The runes of the current part of a word. Reused between words
+	part []byte
+}
 
-	rd := bufio.NewReader(input)
+func newWordTokenizer(language alphabet.Language, sizes []int, opt parseOptions) *wordTokenizer {
+	t := &wordTokenizer{
+		language:       language,
+		mapCase:        opt.caseMode.stringFunc(language),
+		window:         newNgramWindow(sizes, opt.skip, " "),
+		stopWords:      opt.stopWords,
+		stopWordFilter: opt.stopWordFilter,
+		allowList:      opt.allowList,
+		denyList:       opt.denyList,
+		stemmer:        opt.stemmer,
+		stemForms:      opt.stemForms,
+	}
+	if t.stopWords != nil && t.stopWordFilter != StopWordsRemove {
+		t.stops = make([]bool, 0, cap(t.window.units))
+	}
+	if opt.wordBoundaries {
+		t.boundaries = true
+		if language.Apostrophes != "" {
+			t.apostrophe, _ = utf8.DecodeRuneInString(language.Apostrophes)
+		}
+		if language.Hyphens != "" {
+			t.hyphen, _ = utf8.DecodeRuneInString(language.Hyphens)
+		}
+	}
+	return t
+}
 
-loop:
-	for {
-		select {
-		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				// Inform consumer of error
-				_ = recv("", err)
-				return err
+func (t *wordTokenizer) Next(r rune, emit EmitFunc) error {
+	if !isSpace(r) {
+		if r < utf8.RuneSelf {
+			t.word = append(t.word, byte(r))
+		} else {
+			t.word = utf8.AppendRune(t.word, r)
+		}
+		return nil
+	}
+	return t.endWord(emit)
+}
+
+func (t *wordTokenizer) endWord(emit EmitFunc) error {
+	if len(t.word) < 1 {
+		return nil
+	}
+
+	word := string(t.word)
+	t.word = t.word[:0]
+	return t.nextWord(word, emit)
+}
+
+func (t *wordTokenizer) nextWord(word string, emit EmitFunc) error {
+	if t.boundaries {
+		return t.splitBoundaries(word, emit)
+	}
+	return t.addWord(word, emit)
+}
+
+// splitBoundaries adds each of the parts of the word that are separated by punctuation. See [WithWordBoundaries].
+func (t *wordTokenizer) splitBoundaries(word string, emit EmitFunc) error {
+	t.part = t.part[:0]
+	// Apostrophe or hyphen found after the last letter of the part
+	var joiner rune
+
+	for _, r := range word {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			if joiner != 0 {
+				t.part = utf8.AppendRune(t.part, joiner)
+				joiner = 0
 			}
-			break loop
+			t.part = utf8.AppendRune(t.part, r)
+		case len(t.part) > 0 && joiner == 0 && t.language.IsApostrophe(r):
+			joiner = t.apostrophe
+		case len(t.part) > 0 && joiner == 0 && t.language.IsHyphen(r):
+			joiner = t.hyphen
 		default:
-			r, _, err := rd.ReadRune()
-			if err != nil {
-				if err == io.EOF {
-					// Done reading
-					break loop
-				}
-				// Inform consumer of error
-				_ = recv("", err)
+			// Any other punctuation (or more than one apostrophe or hyphen in a row) ends the part
+			joiner = 0
+			if err := t.addPart(emit); err != nil {
 				return err
 			}
+		}
+	}
+	return t.addPart(emit)
+}
 
-			// Ignore white space
-			if unicode.IsSpace(r) {
-				continue
-			}
+// addPart adds the current part of a word (if any).
+func (t *wordTokenizer) addPart(emit EmitFunc) error {
+	if len(t.part) < 1 {
+		return nil
+	}
+	part := string(t.part)
+	t.part = t.part[:0]
+	return t.addWord(part, emit)
+}
 
-			r = unicode.ToLower(r)
+// addWord adds the word to the window after it has been filtered and stemmed.
+func (t *wordTokenizer) addWord(word string, emit EmitFunc) error {
+	word = t.mapCase(word)
 
-			// Ignore any runes not part of the language
-			if !language.ContainsRune(r) {
-				continue
-			}
+	if t.allowList != nil || t.denyList != nil || t.stopWords != nil {
+		key := t.listKey(word)
+		if t.allowList != nil && !t.allowList.Contains(key) {
+			return nil
+		}
+		if t.denyList.Contains(key) {
+			return nil
+		}
 
-			// Inform the consumer of a new token
-			err = recv(string(r), nil)
-			if err != nil {
-				return err
+		if t.stops != nil {
+			if len(t.stops) == cap(t.stops) {
+				copy(t.stops, t.stops[1:])
+				t.stops = t.stops[:len(t.stops)-1]
 			}
+			t.stops = append(t.stops, t.stopWords.Contains(key))
+			return t.window.push(t.stem(word), t.filterStopWords(emit))
+		}
+		if t.stopWords.Contains(key) {
+			return nil
 		}
 	}
 
-	return nil
+	return t.window.push(t.stem(word), emit)
 }
 
-func parseWordNgrams(ctx context.Context, input io.Reader, language alphabet.Language,
-	tokenSize int, recv RecvTokenFunc) error {
+// stem replaces the core of the word with its stem while keeping any leading or trailing punctuation.
+func (t *wordTokenizer) stem(word string) string {
+	if t.stemmer == nil {
+		return word
+	}
 
-	buf := make([]string, tokenSize)
-	pos := 0
-	count := 0
+	prefix, core, suffix := splitWord(word)
+	if core == "" {
+		return word
+	}
 
-	scanner := bufio.NewScanner(bufio.NewReader(input))
-	scanner.Split(bufio.ScanWords)
+	stem := t.stemmer.Stem(t.language.ToLowerString(core))
+	if t.stemForms != nil {
+		t.stemForms.Add(stem, core)
+	}
+	return prefix + stem + suffix
+}
 
-loop:
-	for {
-		select {
-		case <-ctx.Done():
-			if err := ctx.Err(); err != nil {
-				// Inform consumer of error
-				_ = recv("", err)
-				return err
+// listKey returns the version of the word used to look it up in a word list.
+// Any leading or trailing punctuation is ignored and the word is lowercased.
+func (t *wordTokenizer) listKey(word string) string {
+	word = strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return t.language.ToLowerString(word)
+}
+
+// filterStopWords drops the word ngrams that contain any or only stop words before passing them on to emit.
+func (t *wordTokenizer) filterStopWords(emit EmitFunc) EmitFunc {
+	return func(size int, token string) error {
+		// The ngram ends on the most recent word and every word in between that was skipped is marked.
+		// Thus the words of the ngram are the last words in the window.
+		units := strings.Split(token, " ")
+		first := len(t.stops) - len(units)
+
+		words := 0
+		stopWords := 0
+		for i, word := range units {
+			if word == SkipMarker {
+				continue
 			}
-			break loop
-		default:
-			if !scanner.Scan() {
-				if err := scanner.Err(); err != nil {
-					// Inform consumer of error
-					_ = recv("", err)
-					return err
-				}
-				break loop
+			words++
+			if t.stops[first+i] {
+				stopWords++
 			}
+		}
 
-			word := strings.ToLower(scanner.Text())
+		if (t.stopWordFilter == StopWordsDropAny && stopWords > 0) ||
+			(t.stopWordFilter == StopWordsDropAll && stopWords == words) {
+			return nil
+		}
+		return emit(size, token)
+	}
+}
+
+func (t *wordTokenizer) End(emit EmitFunc) error {
+	err := t.endWord(emit)
+	t.window.reset()
+	if t.stops != nil {
+		t.stops = t.stops[:0]
+	}
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// letterMatcher splits text into the letters of a language by using extended grapheme clusters
+// and the multi-rune letters of the language.
+type letterMatcher struct {
+	language alphabet.Language
+	caseMode CaseMode
+	// Length in bytes of the longest multi-rune letter
+	maxMulti int
+}
 
-			buf[pos+count] = word
-			count++
+func newLetterMatcher(language alphabet.Language, caseMode CaseMode) *letterMatcher {
+	m := &letterMatcher{
+		language: language,
+		caseMode: caseMode,
+	}
+	for _, multi := range language.MultiLetters {
+		m.maxMulti = max(m.maxMulti, len(multi))
+	}
+	return m
+}
 
-			// Did we parse enough words for a full token?
-			if count == tokenSize {
-				words := buf[pos:]
-				token := strings.Join(words, " ")
+// letters returns the letters found in the text mapped to the case mode. Grapheme clusters that are not part of
+// the language are ignored.
+func (m *letterMatcher) letters(text string) []string {
+	clusters := make([]string, 0, len(text))
+	state := -1
+	for len(text) > 0 {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		clusters = append(clusters, cluster)
+	}
 
-				copy(buf[pos:], buf[pos+1:])
-				pos = 0
-				count = tokenSize - 1
+	// The alphabet of the language is in lowercase
+	lowered := make([]string, len(clusters))
+	for i, cluster := range clusters {
+		lowered[i] = m.caseMode.lower(m.language, cluster)
+	}
 
-				// Inform the consumer of a new token
-				err := recv(token, nil)
-				if err != nil {
-					return err
+	result := make([]string, 0, len(clusters))
+	for i := 0; i < len(clusters); i++ {
+		start := i
+		letter := lowered[i]
+
+		// Find the longest multi-rune letter starting at this cluster
+		if m.maxMulti > 0 {
+			combined := letter
+			for j := i + 1; j < len(lowered) && len(combined)+len(lowered[j]) <= m.maxMulti; j++ {
+				combined += lowered[j]
+				if m.language.IsMultiLetter(combined) {
+					letter = combined
+					i = j
 				}
 			}
 		}
+
+		// Ignore any letters not part of the language
+		if !m.language.ContainsLetter(letter) {
+			continue
+		}
+
+		result = append(result, m.caseMode.string(m.language, strings.Join(clusters[start:i+1], "")))
 	}
 
-	return nil
+	return result
 }
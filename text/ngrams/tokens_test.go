@@ -21,7 +21,9 @@ package ngrams_test
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -122,6 +124,77 @@ func TestParseLetterTokens(t *testing.T) {
 	}
 }
 
+func TestParseLetterTokensGraphemes(t *testing.T) {
+	lang := alphabet.Language{
+		Name:         "Test",
+		Code:         "test",
+		Letters:      "abcdefghijklmnopqrstuvwxyz\u0301",
+		MultiLetters: []string{"ij", "dzs"},
+	}
+
+	testCases := []struct {
+		desc      string
+		input     string
+		tokenSize int
+		expected  []string
+	}{
+		{desc: "multi-rune letters", input: "IJs dzsem", tokenSize: 1,
+			expected: []string{"ij", "s", "dzs", "e", "m"}},
+		{desc: "multi-rune letters bigrams", input: "ijs dzsem", tokenSize: 2,
+			expected: []string{"ijs", "dzse", "em"}},
+		{desc: "combining marks", input: "cafe\u0301 x", tokenSize: 1,
+			expected: []string{"c", "a", "f", "e\u0301", "x"}},
+		{desc: "ignore clusters not in the language", input: "a👍🏽b ñ", tokenSize: 2,
+			expected: []string{"ab"}},
+		{desc: "trigrams", input: "bijna", tokenSize: 3,
+			expected: []string{"bijn", "ijna"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			err := ngrams.ParseLetterTokens(context.Background(), strings.NewReader(tC.input), lang, tC.tokenSize,
+				func(token string, err error) error {
+					require.NoError(t, err)
+					result = append(result, token)
+					return nil
+				}, ngrams.WithGraphemes())
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
+func TestParseLetterTokensGraphemesMatchRunes(t *testing.T) {
+	// Without multi-rune letters the grapheme parser should produce the same result for the control texts
+	enLang := alphabet.MustBuiltin("en")
+
+	for tokenSize := 1; tokenSize <= 3; tokenSize++ {
+		expected := collection.NewSet[string]()
+		f, err := os.Open("testdata/en-alice-partial.txt")
+		require.NoError(t, err)
+		err = ngrams.ParseLetterTokens(context.Background(), f, enLang, tokenSize,
+			func(token string, err error) error {
+				expected.Insert(token)
+				return nil
+			})
+		f.Close()
+		require.NoError(t, err)
+
+		result := collection.NewSet[string]()
+		f, err = os.Open("testdata/en-alice-partial.txt")
+		require.NoError(t, err)
+		err = ngrams.ParseLetterTokens(context.Background(), f, enLang, tokenSize,
+			func(token string, err error) error {
+				result.Insert(token)
+				return nil
+			}, ngrams.WithGraphemes())
+		f.Close()
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, result)
+	}
+}
+
 func TestParseWordTokens(t *testing.T) {
 	enLang, err := alphabet.Builtin("en")
 	require.NoError(t, err)
@@ -208,6 +281,67 @@ func TestParseWordTokens(t *testing.T) {
 	}
 }
 
+func TestParseWordTokensLongWord(t *testing.T) {
+	long := strings.Repeat("a", bufio.MaxScanTokenSize+1)
+
+	var result []string
+	err := ngrams.ParseWordTokens(context.Background(), strings.NewReader("the "+long+"\tend\n"),
+		alphabet.MustBuiltin("en"), 1,
+		func(token string, err error) error {
+			result = append(result, token)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"the", long, "end"}, result)
+}
+
+func TestParseWordTokensBoundaries(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		language  alphabet.Language
+		input     string
+		tokenSize int
+		opts      []ngrams.ParseOption
+		expected  []string
+	}{
+		{desc: "punctuation", language: alphabet.MustBuiltin("en"), input: "“Hello, world!” (she said)", tokenSize: 1,
+			expected: []string{"hello", "world", "she", "said"}},
+		{desc: "apostrophes", language: alphabet.MustBuiltin("en"), input: "Don’t, don't 'quoted' students' dogs", tokenSize: 1,
+			expected: []string{"don't", "don't", "quoted", "students", "dogs"}},
+		{desc: "hyphens", language: alphabet.MustBuiltin("en"), input: "well-known -- end- well--known", tokenSize: 1,
+			expected: []string{"well-known", "end", "well", "known"}},
+		{desc: "other punctuation splits the word", language: alphabet.MustBuiltin("en"), input: "end.Start and/or 3.14", tokenSize: 1,
+			expected: []string{"end", "start", "and", "or", "3", "14"}},
+		{desc: "language without apostrophes", language: alphabet.MustBuiltin("da"), input: "don't", tokenSize: 1,
+			expected: []string{"don", "t"}},
+		{desc: "language without hyphens", language: alphabet.MustBuiltin("ar"), input: "كتاب-قلم", tokenSize: 1,
+			expected: []string{"كتاب", "قلم"}},
+		{desc: "first hyphen of the language", language: alphabet.MustBuiltin("he"), input: "בית־ספר בית-ספר", tokenSize: 1,
+			expected: []string{"בית־ספר", "בית־ספר"}},
+		{desc: "combining marks", language: alphabet.MustBuiltin("hi"), input: "नमस्ते, दुनिया।", tokenSize: 1,
+			expected: []string{"नमस्ते", "दुनिया"}},
+		{desc: "bigrams", language: alphabet.MustBuiltin("af"), input: "Hy het ’n hond, ’n kat.", tokenSize: 2,
+			expected: []string{"hy het", "het n", "n hond", "hond n", "n kat"}},
+		{desc: "stop words", language: alphabet.MustBuiltin("en"), input: "The dog, and the cat.", tokenSize: 1,
+			opts:     []ngrams.ParseOption{ngrams.WithStopWords(ngrams.NewWordList("the", "and"), ngrams.StopWordsRemove)},
+			expected: []string{"dog", "cat"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result := make([]string, 0)
+			opts := append([]ngrams.ParseOption{ngrams.WithWordBoundaries()}, tC.opts...)
+			err := ngrams.ParseWordTokens(context.Background(), strings.NewReader(tC.input), tC.language, tC.tokenSize,
+				func(token string, err error) error {
+					require.NoError(t, err)
+					result = append(result, token)
+					return nil
+				}, opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tC.expected, result)
+		})
+	}
+}
+
 func tokensFromFrequencyFile(path string) (collection.Set[string], error) {
 	freq, err := ngrams.LoadFrequenciesFromFile(path)
 	if err != nil {
@@ -221,3 +355,37 @@ func tokensFromFrequencyFile(path string) (collection.Set[string], error) {
 
 	return result, nil
 }
+
+func BenchmarkParseLetterTokens(b *testing.B) {
+	data, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(b, err)
+	enLang := alphabet.MustBuiltin("en")
+
+	for tokenSize := 1; tokenSize <= 3; tokenSize++ {
+		b.Run(fmt.Sprintf("size-%d", tokenSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = ngrams.ParseLetterTokens(context.Background(), bytes.NewReader(data), enLang, tokenSize,
+					func(token string, err error) error {
+						return nil
+					})
+			}
+		})
+	}
+}
+
+func BenchmarkParseWordTokens(b *testing.B) {
+	data, err := os.ReadFile("testdata/en-alice-partial.txt")
+	require.NoError(b, err)
+	enLang := alphabet.MustBuiltin("en")
+
+	for tokenSize := 1; tokenSize <= 3; tokenSize++ {
+		b.Run(fmt.Sprintf("size-%d", tokenSize), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = ngrams.ParseWordTokens(context.Background(), bytes.NewReader(data), enLang, tokenSize,
+					func(token string, err error) error {
+						return nil
+					})
+			}
+		})
+	}
+}
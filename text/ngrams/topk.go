@@ -0,0 +1,324 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"container/heap"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// Frequency table metadata keys used to record the error bounds of the approximate top-K mode.
+const (
+	// MetadataTopK records the maximum number of tokens kept.
+	MetadataTopK = "topk"
+	// MetadataTopKTotal records the total count of all the tokens seen (including those not kept).
+	MetadataTopKTotal = "topk_total"
+	// MetadataTopKMaxError records the maximum amount by which any of the kept counts may be overestimated.
+	MetadataTopKMaxError = "topk_max_error"
+	// MetadataSketchEpsilon records the Count-Min Sketch error factor. Estimates exceed the true count
+	// by at most epsilon * total with a probability of 1 - delta.
+	MetadataSketchEpsilon = "cms_epsilon"
+	// MetadataSketchDelta records the probability that a Count-Min Sketch estimate exceeds the error bound.
+	MetadataSketchDelta = "cms_delta"
+)
+
+// CountMinSketch estimates the counts of tokens using a fixed amount of memory.
+// Estimates are never lower than the true count and exceed it by at most epsilon * total
+// with a probability of 1 - delta.
+type CountMinSketch struct {
+	width   uint64
+	counts  [][]int64
+	total   int64
+	epsilon float64
+	delta   float64
+}
+
+// NewCountMinSketch creates a new [CountMinSketch] with the error factor epsilon and the probability delta
+// of exceeding the error bound. E.g. 0.0001 and 0.01.
+func NewCountMinSketch(epsilon float64, delta float64) *CountMinSketch {
+	width := uint64(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	if depth < 1 {
+		depth = 1
+	}
+
+	counts := make([][]int64, depth)
+	for i := range counts {
+		counts[i] = make([]int64, width)
+	}
+
+	return &CountMinSketch{
+		width:   width,
+		counts:  counts,
+		epsilon: epsilon,
+		delta:   delta,
+	}
+}
+
+// Add the count to the token.
+func (s *CountMinSketch) Add(token string, count int64) {
+	h1, h2 := sketchHashes(token)
+	for i, row := range s.counts {
+		row[(h1+uint64(i)*h2)%s.width] += count
+	}
+	s.total += count
+}
+
+// Estimate returns the estimated count of the token.
+func (s *CountMinSketch) Estimate(token string) int64 {
+	h1, h2 := sketchHashes(token)
+	result := int64(math.MaxInt64)
+	for i, row := range s.counts {
+		result = min(result, row[(h1+uint64(i)*h2)%s.width])
+	}
+	return result
+}
+
+// Total returns the sum of all the counts added.
+func (s *CountMinSketch) Total() int64 {
+	return s.total
+}
+
+// Epsilon returns the error factor.
+func (s *CountMinSketch) Epsilon() float64 {
+	return s.epsilon
+}
+
+// Delta returns the probability of exceeding the error bound.
+func (s *CountMinSketch) Delta() float64 {
+	return s.delta
+}
+
+// sketchHashes returns the two hashes used to derive the column of the token in each row (double hashing).
+func sketchHashes(token string) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(token))
+	sum := h.Sum64()
+	return sum, (sum >> 32) | 1
+}
+
+//-----------------------------------------------------------------------------
+
+// TopK keeps track of the k most frequent tokens (heavy hitters) using a bounded amount of memory.
+//
+// The Space-Saving algorithm is used to decide which tokens are kept. When a new token is seen and k tokens
+// are already being tracked, the token with the lowest count is replaced and the new token inherits its count.
+// Counts are therefore overestimated by at most total / k. A [CountMinSketch] is used alongside to tighten
+// the estimated counts. It is safe for concurrent use.
+type TopK struct {
+	mu      sync.Mutex
+	k       int
+	entries map[string]*topKEntry
+	heap    topKHeap
+	sketch  *CountMinSketch
+}
+
+// topKSketchDelta is the probability of the Count-Min Sketch used by [TopK] exceeding its error bound.
+const topKSketchDelta = 0.02
+
+// NewTopK creates a new [TopK] that keeps track of the k most frequent tokens.
+// A k of less than 1 is treated as 1.
+func NewTopK(k int) *TopK {
+	k = max(k, 1)
+	return &TopK{
+		k:       k,
+		entries: make(map[string]*topKEntry, k),
+		heap:    make(topKHeap, 0, k),
+		sketch:  NewCountMinSketch(math.E/float64(4*k), topKSketchDelta),
+	}
+}
+
+// Add the count to the token. The signature matches [FrequencyTable.Add] and negative counts are ignored.
+func (t *TopK) Add(token string, count int) {
+	if count < 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.add(token, int64(count))
+}
+
+// resume seeds the counter with the tokens of a frequency table previously created by a [TopK] (e.g. when
+// updating an existing table). The recorded total, which includes the counts of the tokens that were not kept,
+// and the error bound of the kept counts are carried over. See [MetadataTopKTotal].
+func (t *TopK) resume(ft *FrequencyTable) error {
+	total, err := int64Metadata(ft, MetadataTopKTotal)
+	if err != nil {
+		return err
+	}
+	maxError, err := int64Metadata(ft, MetadataTopKMaxError)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var seeded int64
+	for _, freq := range ft.Entries() {
+		t.add(freq.Token, freq.Count)
+		if entry, exists := t.entries[freq.Token]; exists {
+			entry.err = max(entry.err, maxError)
+		}
+		seeded += freq.Count
+	}
+
+	// The tokens that were not kept still count towards the total
+	if total > seeded {
+		t.sketch.total += total - seeded
+	}
+	return nil
+}
+
+// int64Metadata returns the integer value of the metadata key or 0 if the key does not exist.
+func int64Metadata(ft *FrequencyTable, key string) (int64, error) {
+	value, exists := ft.Metadata(key)
+	if !exists {
+		return 0, nil
+	}
+	result, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frequency table metadata %s=%q. %w", key, value, err)
+	}
+	return result, nil
+}
+
+func (t *TopK) add(token string, count int64) {
+	t.sketch.Add(token, count)
+
+	if entry, exists := t.entries[token]; exists {
+		entry.count += count
+		heap.Fix(&t.heap, entry.index)
+		return
+	}
+
+	if len(t.heap) < t.k {
+		entry := &topKEntry{token: token, count: count}
+		t.entries[token] = entry
+		heap.Push(&t.heap, entry)
+		return
+	}
+
+	// Replace the token with the lowest count
+	entry := t.heap[0]
+	delete(t.entries, entry.token)
+	entry.token = token
+	entry.err = entry.count
+	entry.count += count
+	t.entries[token] = entry
+	heap.Fix(&t.heap, 0)
+}
+
+// K returns the maximum number of tokens kept.
+func (t *TopK) K() int {
+	return t.k
+}
+
+// Len returns the number of tokens being kept.
+func (t *TopK) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.heap)
+}
+
+// Total returns the sum of all the counts added.
+func (t *TopK) Total() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sketch.Total()
+}
+
+// Estimate returns the estimated count of the token and whether the token is being kept.
+func (t *TopK) Estimate(token string) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, exists := t.entries[token]
+	if !exists {
+		return t.sketch.Estimate(token), false
+	}
+	return min(entry.count, t.sketch.Estimate(token)), true
+}
+
+// FrequencyTable returns a new frequency table of the tokens being kept and their estimated counts.
+// The percentages are calculated against the total of all the counts added and the error bounds are
+// recorded in the metadata. See [MetadataTopK].
+func (t *TopK) FrequencyTable() *FrequencyTable {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := NewFrequencyTable()
+	total := t.sketch.Total()
+	var maxError int64
+
+	for _, entry := range t.heap {
+		count := min(entry.count, t.sketch.Estimate(entry.token))
+		maxError = max(maxError, count-max(entry.count-entry.err, 0))
+		result.store.set(entry.token, uint64(count))
+	}
+
+	result.SetMetadata(MetadataTopK, strconv.Itoa(t.k))
+	result.SetMetadata(MetadataTopKTotal, strconv.FormatInt(total, 10))
+	result.SetMetadata(MetadataTopKMaxError, strconv.FormatInt(maxError, 10))
+	result.SetMetadata(MetadataSketchEpsilon, strconv.FormatFloat(t.sketch.Epsilon(), 'g', -1, 64))
+	result.SetMetadata(MetadataSketchDelta, strconv.FormatFloat(t.sketch.Delta(), 'g', -1, 64))
+	result.Update()
+	return result
+}
+
+// topKEntry is a token being kept by [TopK]. err is the count inherited from the token it replaced.
+type topKEntry struct {
+	token string
+	count int64
+	err   int64
+	index int
+}
+
+// topKHeap is a min-heap of the entries ordered by count.
+type topKHeap []*topKEntry
+
+func (h topKHeap) Len() int           { return len(h) }
+func (h topKHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *topKHeap) Push(x any) {
+	entry := x.(*topKEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *topKHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
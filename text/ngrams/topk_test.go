@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	s := ngrams.NewCountMinSketch(0.001, 0.01)
+	assert.Equal(t, 0.001, s.Epsilon())
+	assert.Equal(t, 0.01, s.Delta())
+
+	expected := make(map[string]int64)
+	for i := 0; i < 1000; i++ {
+		token := fmt.Sprintf("token%d", i%100)
+		s.Add(token, int64(i%7+1))
+		expected[token] += int64(i%7 + 1)
+	}
+
+	var total int64
+	for _, count := range expected {
+		total += count
+	}
+	assert.Equal(t, total, s.Total())
+
+	for token, count := range expected {
+		estimate := s.Estimate(token)
+		assert.GreaterOrEqual(t, estimate, count)
+		assert.LessOrEqual(t, estimate, count+int64(0.001*float64(total))+1)
+	}
+	assert.Equal(t, int64(0), s.Estimate("missing"))
+}
+
+func TestTopK(t *testing.T) {
+	topK := ngrams.NewTopK(5)
+	assert.Equal(t, 5, topK.K())
+
+	// Heavy hitters a, b and c with lots of noise
+	for i := 0; i < 100; i++ {
+		topK.Add("a", 10)
+		topK.Add("b", 5)
+		topK.Add("c", 3)
+		topK.Add(fmt.Sprintf("noise%d", i), 1)
+	}
+	assert.Equal(t, 5, topK.Len())
+	assert.Equal(t, int64(1900), topK.Total())
+
+	count, kept := topK.Estimate("a")
+	assert.True(t, kept)
+	assert.GreaterOrEqual(t, count, int64(1000))
+	_, kept = topK.Estimate("noise1")
+	assert.False(t, kept)
+
+	ft := topK.FrequencyTable()
+	assert.Subset(t, ft.Tokens(), []string{"a", "b", "c"})
+
+	a, exists := ft.Get("a")
+	require.True(t, exists)
+	assert.GreaterOrEqual(t, a.Count, int64(1000))
+	assert.InDelta(t, float64(a.Count)/1900, a.Percentage, 0.0001)
+
+	for key, expected := range map[string]string{
+		ngrams.MetadataTopK:      "5",
+		ngrams.MetadataTopKTotal: "1900",
+	} {
+		value, exists := ft.Metadata(key)
+		assert.True(t, exists)
+		assert.Equal(t, expected, value)
+	}
+	for _, key := range []string{ngrams.MetadataTopKMaxError, ngrams.MetadataSketchEpsilon, ngrams.MetadataSketchDelta} {
+		_, exists := ft.Metadata(key)
+		assert.True(t, exists, key)
+	}
+}
+
+func TestTopKExactWhenAllTokensFit(t *testing.T) {
+	topK := ngrams.NewTopK(10)
+	topK.Add("a", 2)
+	topK.Add("b", 1)
+	topK.Add("a", 1)
+
+	ft := topK.FrequencyTable()
+	a, _ := ft.Get("a")
+	assert.Equal(t, int64(3), a.Count)
+	b, _ := ft.Get("b")
+	assert.Equal(t, int64(1), b.Count)
+	value, _ := ft.Metadata(ngrams.MetadataTopKMaxError)
+	assert.Equal(t, "0", value)
+}
+
+func TestTopKInvalidK(t *testing.T) {
+	for _, k := range []int{0, -1} {
+		topK := ngrams.NewTopK(k)
+		assert.Equal(t, 1, topK.K())
+
+		topK.Add("a", 2)
+		topK.Add("b", 1)
+		assert.Equal(t, 1, topK.Len())
+		assert.Equal(t, int64(3), topK.Total())
+	}
+}
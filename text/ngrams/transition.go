@@ -0,0 +1,427 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/exp/maps"
+)
+
+// Smoothing specifies how the probability mass is redistributed to the transitions that were not observed.
+type Smoothing int
+
+const (
+	// SmoothingNone uses the maximum likelihood estimate: count(prefix next) / count(prefix).
+	SmoothingNone Smoothing = iota
+	// SmoothingLaplace adds alpha to the count of every possible transition (additive smoothing).
+	SmoothingLaplace
+	// SmoothingKneserNey uses interpolated Kneser-Ney smoothing, i.e. a discount is subtracted from every
+	// observed count and redistributed according to the number of distinct prefixes a unit follows.
+	SmoothingKneserNey
+)
+
+var smoothingNames = []string{"none", "laplace", "kneser-ney"}
+
+// String returns the name of the smoothing.
+func (s Smoothing) String() string {
+	if s < 0 || int(s) >= len(smoothingNames) {
+		return fmt.Sprintf("Smoothing(%d)", int(s))
+	}
+	return smoothingNames[s]
+}
+
+// ParseSmoothing returns the smoothing for the given name (none, laplace or kneser-ney).
+func ParseSmoothing(name string) (Smoothing, error) {
+	for i, n := range smoothingNames {
+		if strings.EqualFold(n, name) {
+			return Smoothing(i), nil
+		}
+	}
+	return SmoothingNone, fmt.Errorf("invalid smoothing %q", name)
+}
+
+const (
+	// DefaultLaplaceAlpha is the amount added to every count when Laplace smoothing is used.
+	DefaultLaplaceAlpha = 1.0
+	// DefaultKneserNeyDiscount is the amount subtracted from every count when Kneser-Ney smoothing is used.
+	DefaultKneserNeyDiscount = 0.75
+)
+
+// TransitionOption is used to configure how the transition probabilities are derived.
+type TransitionOption func(opt *transitionOptions)
+
+type transitionOptions struct {
+	smoothing Smoothing
+	parameter float64
+	words     bool
+}
+
+// WithLaplace configures additive smoothing where alpha (e.g. 1) is added to the count of every transition.
+func WithLaplace(alpha float64) TransitionOption {
+	return func(opt *transitionOptions) {
+		opt.smoothing = SmoothingLaplace
+		opt.parameter = alpha
+	}
+}
+
+// WithKneserNey configures interpolated Kneser-Ney smoothing where the discount (e.g. 0.75) is subtracted
+// from the count of every observed transition.
+func WithKneserNey(discount float64) TransitionOption {
+	return func(opt *transitionOptions) {
+		opt.smoothing = SmoothingKneserNey
+		opt.parameter = discount
+	}
+}
+
+// WithWordUnits configures the ngrams to be split into words instead of letters. E.g. the prefix of
+// "the quick fox" is "the quick" and the next unit is "fox".
+func WithWordUnits() TransitionOption {
+	return func(opt *transitionOptions) {
+		opt.words = true
+	}
+}
+
+// Transition is the probability of the next letter or word given the previous n-1 letters or words (prefix).
+type Transition struct {
+	Prefix string `json:"prefix"`
+	Next   string `json:"next"`
+	// Count is the number of times the ngram (prefix and next) was observed.
+	Count       int64   `json:"count"`
+	Probability float64 `json:"probability"`
+}
+
+// TransitionTable is the Markov transition table of P(next | prefix) derived from the counts of an ngram
+// [FrequencyTable] where the prefix is the first n-1 letters or words of an ngram.
+type TransitionTable struct {
+	opt      transitionOptions
+	prefixes map[string]*prefixTransitions
+	// continuations is the number of distinct prefixes each unit follows (used by Kneser-Ney)
+	continuations map[string]int
+	// types is the number of distinct ngrams
+	types int
+}
+
+// prefixTransitions are the observed transitions from a prefix.
+type prefixTransitions struct {
+	total int64
+	nexts map[string]int64
+}
+
+// NewTransitionTable derives the transition probabilities from the ngrams (of size 2 or more) of the
+// frequency table. By default the ngrams are split into letters and no smoothing is used.
+// All the ngrams need to be of the same size and skip-grams are not supported because the skipped units
+// are not observed transitions.
+func NewTransitionTable(ft *FrequencyTable, opts ...TransitionOption) (*TransitionTable, error) {
+	var opt transitionOptions
+	for _, apply := range opts {
+		apply(&opt)
+	}
+
+	switch opt.smoothing {
+	case SmoothingLaplace:
+		if opt.parameter <= 0 {
+			return nil, fmt.Errorf("invalid laplace alpha %g", opt.parameter)
+		}
+	case SmoothingKneserNey:
+		if opt.parameter <= 0 || opt.parameter >= 1 {
+			return nil, fmt.Errorf("invalid kneser-ney discount %g", opt.parameter)
+		}
+	}
+
+	if skip, exists := ft.Metadata(MetadataSkip); exists {
+		return nil, fmt.Errorf("skip-grams (skip %s) can not be used to derive transitions", skip)
+	}
+
+	t := &TransitionTable{
+		opt:           opt,
+		prefixes:      make(map[string]*prefixTransitions),
+		continuations: make(map[string]int),
+	}
+
+	size := 0
+	for _, freq := range ft.Entries() {
+		if !opt.words && strings.ContainsRune(freq.Token, ' ') {
+			return nil, fmt.Errorf("the ngram %q contains more than one word. word units need to be used", freq.Token)
+		}
+
+		prefix, next, ok := t.split(freq.Token)
+		if !ok {
+			return nil, fmt.Errorf("failed to split the ngram %q into a prefix and the next unit", freq.Token)
+		}
+
+		// E.g. word monograms split into letters would otherwise produce transitions of varying lengths
+		n := t.units(freq.Token)
+		if size == 0 {
+			size = n
+		} else if n != size {
+			return nil, fmt.Errorf("the ngram %q is of size %d instead of %d", freq.Token, n, size)
+		}
+
+		p, exists := t.prefixes[prefix]
+		if !exists {
+			p = &prefixTransitions{nexts: make(map[string]int64)}
+			t.prefixes[prefix] = p
+		}
+		if _, exists := p.nexts[next]; !exists {
+			t.continuations[next]++
+			t.types++
+		}
+		p.nexts[next] += freq.Count
+		p.total += freq.Count
+	}
+
+	return t, nil
+}
+
+// units returns the number of letters or words in the ngram.
+func (t *TransitionTable) units(token string) int {
+	if t.opt.words {
+		return strings.Count(token, " ") + 1
+	}
+	return utf8.RuneCountInString(token)
+}
+
+// split returns the first n-1 units of the ngram and the last unit.
+func (t *TransitionTable) split(token string) (string, string, bool) {
+	if t.opt.words {
+		i := strings.LastIndexByte(token, ' ')
+		if i < 1 || i == len(token)-1 {
+			return "", "", false
+		}
+		return token[:i], token[i+1:], true
+	}
+
+	_, size := utf8.DecodeLastRuneInString(token)
+	if size == 0 || size == len(token) {
+		return "", "", false
+	}
+	return token[:len(token)-size], token[len(token)-size:], true
+}
+
+// WordUnits returns true if the ngrams were split into words instead of letters. See [WithWordUnits].
+func (t *TransitionTable) WordUnits() bool {
+	return t.opt.words
+}
+
+// Smoothing returns the smoothing used.
+func (t *TransitionTable) Smoothing() Smoothing {
+	return t.opt.smoothing
+}
+
+// Prefixes returns the sorted prefixes that were observed.
+func (t *TransitionTable) Prefixes() []string {
+	result := maps.Keys(t.prefixes)
+	slices.Sort(result)
+	return result
+}
+
+// Vocabulary returns the sorted letters or words that were observed following a prefix.
+func (t *TransitionTable) Vocabulary() []string {
+	result := maps.Keys(t.continuations)
+	slices.Sort(result)
+	return result
+}
+
+// Probability returns P(next | prefix). Unobserved transitions have a probability of 0 unless smoothing is used.
+// When the prefix was never observed then the probability is that of the next unit following any prefix.
+func (t *TransitionTable) Probability(prefix string, next string) float64 {
+	p, exists := t.prefixes[prefix]
+	if !exists {
+		return t.unseenPrefixProbability(next)
+	}
+	return t.probability(p, next)
+}
+
+func (t *TransitionTable) probability(p *prefixTransitions, next string) float64 {
+	count := float64(p.nexts[next])
+	total := float64(p.total)
+
+	switch t.opt.smoothing {
+	case SmoothingLaplace:
+		return (count + t.opt.parameter) / (total + t.opt.parameter*float64(len(t.continuations)))
+	case SmoothingKneserNey:
+		discount := t.opt.parameter
+		discounted := max(count-discount, 0) / total
+		weight := discount * float64(len(p.nexts)) / total
+		return discounted + weight*t.continuation(next)
+	default:
+		return count / total
+	}
+}
+
+// continuation returns the Kneser-Ney continuation probability of the unit, i.e. the number of distinct
+// prefixes it follows relative to the number of distinct ngrams.
+func (t *TransitionTable) continuation(next string) float64 {
+	if t.types == 0 {
+		return 0
+	}
+	return float64(t.continuations[next]) / float64(t.types)
+}
+
+func (t *TransitionTable) unseenPrefixProbability(next string) float64 {
+	switch t.opt.smoothing {
+	case SmoothingLaplace:
+		if len(t.continuations) == 0 {
+			return 0
+		}
+		return 1 / float64(len(t.continuations))
+	case SmoothingKneserNey:
+		return t.continuation(next)
+	default:
+		return 0
+	}
+}
+
+// Transitions returns the observed transitions from the prefix sorted by probability (highest first).
+func (t *TransitionTable) Transitions(prefix string) []Transition {
+	p, exists := t.prefixes[prefix]
+	if !exists {
+		return nil
+	}
+
+	result := make([]Transition, 0, len(p.nexts))
+	for next, count := range p.nexts {
+		result = append(result, Transition{
+			Prefix:      prefix,
+			Next:        next,
+			Count:       count,
+			Probability: t.probability(p, next),
+		})
+	}
+
+	slices.SortFunc(result, func(a, b Transition) int {
+		if c := cmp.Compare(b.Probability, a.Probability); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Next, b.Next)
+	})
+	return result
+}
+
+// Entries returns all of the observed transitions sorted by prefix and then by probability (highest first).
+func (t *TransitionTable) Entries() []Transition {
+	result := make([]Transition, 0, t.types)
+	for _, prefix := range t.Prefixes() {
+		result = append(result, t.Transitions(prefix)...)
+	}
+	return result
+}
+
+// metadata returns the key value pairs that describe how the probabilities were derived.
+func (t *TransitionTable) metadata() [][2]string {
+	result := [][2]string{{"smoothing", t.opt.smoothing.String()}}
+	switch t.opt.smoothing {
+	case SmoothingLaplace:
+		result = append(result, [2]string{"alpha", strconv.FormatFloat(t.opt.parameter, 'g', -1, 64)})
+	case SmoothingKneserNey:
+		result = append(result, [2]string{"discount", strconv.FormatFloat(t.opt.parameter, 'g', -1, 64)})
+	}
+	return result
+}
+
+// Save writes the observed transitions as CSV to the writer.
+//
+// CSV format in UTF-8: prefix,next,probability
+// The smoothing used is recorded in the metadata lines in the format: #meta,key,value.
+func (t *TransitionTable) Save(w io.Writer) error {
+	csvW := csv.NewWriter(w)
+	if err := csvW.Write([]string{"#prefix", "next", "probability"}); err != nil {
+		return fmt.Errorf("failed to write the csv header. %w", err)
+	}
+	for _, meta := range t.metadata() {
+		if err := csvW.Write([]string{metadataPrefix, meta[0], meta[1]}); err != nil {
+			return fmt.Errorf("failed to write the metadata %q. %w", meta[0], err)
+		}
+	}
+
+	for _, tr := range t.Entries() {
+		err := csvW.Write([]string{tr.Prefix, tr.Next, formatPercentage(tr.Probability)})
+		if err != nil {
+			return fmt.Errorf("failed to write the transition %q -> %q. %w", tr.Prefix, tr.Next, err)
+		}
+	}
+
+	csvW.Flush()
+	if err := csvW.Error(); err != nil {
+		return fmt.Errorf("failed to write the csv. %w", err)
+	}
+	return nil
+}
+
+// SaveJSON writes the observed transitions as JSON to the writer. E.g.
+//
+//	{"smoothing":"laplace","alpha":1,"transitions":[{"prefix":"t","next":"h","count":2,"probability":0.5}]}
+func (t *TransitionTable) SaveJSON(w io.Writer) error {
+	doc := struct {
+		Smoothing   string       `json:"smoothing"`
+		Alpha       float64      `json:"alpha,omitempty"`
+		Discount    float64      `json:"discount,omitempty"`
+		Transitions []Transition `json:"transitions"`
+	}{
+		Smoothing:   t.opt.smoothing.String(),
+		Transitions: t.Entries(),
+	}
+	switch t.opt.smoothing {
+	case SmoothingLaplace:
+		doc.Alpha = t.opt.parameter
+	case SmoothingKneserNey:
+		doc.Discount = t.opt.parameter
+	}
+
+	if err := json.NewEncoder(w).Encode(doc); err != nil {
+		return fmt.Errorf("failed to write the json. %w", err)
+	}
+	return nil
+}
+
+// SaveToFile writes the observed transitions to the file. JSON is used when the file has a .json
+// extension and CSV otherwise.
+func (t *TransitionTable) SaveToFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to save the transition table to file %q. %w", path, err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: failed to close %s. %v", path, err)
+		}
+	}()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = t.SaveJSON(f)
+	} else {
+		err = t.Save(f)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save the transition table to file %q. %w", path, err)
+	}
+	return nil
+}
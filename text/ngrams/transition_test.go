@@ -0,0 +1,200 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/go-analyse/text/ngrams"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transitionTestTable() *ngrams.FrequencyTable {
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("th", 6)
+	ft.Add("ta", 2)
+	ft.Add("he", 3)
+	ft.Add("ha", 1)
+	ft.Update()
+	return ft
+}
+
+func TestTransitionTable(t *testing.T) {
+	tt, err := ngrams.NewTransitionTable(transitionTestTable())
+	require.NoError(t, err)
+
+	assert.Equal(t, ngrams.SmoothingNone, tt.Smoothing())
+	assert.Equal(t, []string{"h", "t"}, tt.Prefixes())
+	assert.Equal(t, []string{"a", "e", "h"}, tt.Vocabulary())
+
+	expected := []ngrams.Transition{
+		{Prefix: "t", Next: "h", Count: 6, Probability: 0.75},
+		{Prefix: "t", Next: "a", Count: 2, Probability: 0.25},
+	}
+	assert.Equal(t, expected, tt.Transitions("t"))
+	assert.Nil(t, tt.Transitions("x"))
+
+	assert.Equal(t, 0.75, tt.Probability("h", "e"))
+	assert.Equal(t, 0.0, tt.Probability("h", "h"))
+	assert.Equal(t, 0.0, tt.Probability("x", "a"))
+	assert.Len(t, tt.Entries(), 4)
+	assert.Equal(t, "h", tt.Entries()[0].Prefix)
+}
+
+func TestTransitionTableSmoothing(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		opt      ngrams.TransitionOption
+		expected map[[2]string]float64
+	}{
+		{
+			desc: "laplace",
+			opt:  ngrams.WithLaplace(1),
+			expected: map[[2]string]float64{
+				{"t", "h"}: 7.0 / 11, {"t", "a"}: 3.0 / 11, {"t", "e"}: 1.0 / 11,
+				{"x", "a"}: 1.0 / 3,
+			},
+		},
+		{
+			// 4 distinct bigrams: a follows 2 prefixes, e and h follow 1 prefix
+			desc: "kneser-ney",
+			opt:  ngrams.WithKneserNey(0.5),
+			expected: map[[2]string]float64{
+				{"t", "h"}: 5.5/8 + (0.5*2/8)*0.25,
+				{"t", "a"}: 1.5/8 + (0.5*2/8)*0.5,
+				{"t", "e"}: (0.5 * 2 / 8) * 0.25,
+				{"x", "a"}: 0.5,
+			},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tt, err := ngrams.NewTransitionTable(transitionTestTable(), tC.opt)
+			require.NoError(t, err)
+
+			for key, expected := range tC.expected {
+				assert.InDelta(t, expected, tt.Probability(key[0], key[1]), 1e-12, key)
+			}
+
+			// The probabilities of every prefix sum to 1 over the vocabulary
+			for _, prefix := range tt.Prefixes() {
+				sum := 0.0
+				for _, next := range tt.Vocabulary() {
+					sum += tt.Probability(prefix, next)
+				}
+				assert.InDelta(t, 1.0, sum, 1e-12, prefix)
+			}
+		})
+	}
+}
+
+func TestTransitionTableWords(t *testing.T) {
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("of the cat", 3)
+	ft.Add("of the dog", 1)
+	tt, err := ngrams.NewTransitionTable(ft, ngrams.WithWordUnits())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"of the"}, tt.Prefixes())
+	assert.Equal(t, 0.75, tt.Probability("of the", "cat"))
+}
+
+func TestTransitionTableErrors(t *testing.T) {
+	_, err := ngrams.NewTransitionTable(transitionTestTable(), ngrams.WithLaplace(0))
+	assert.ErrorContains(t, err, "invalid laplace alpha 0")
+	_, err = ngrams.NewTransitionTable(transitionTestTable(), ngrams.WithKneserNey(1))
+	assert.ErrorContains(t, err, "invalid kneser-ney discount 1")
+
+	ft := ngrams.NewFrequencyTable()
+	ft.Add("t", 1)
+	_, err = ngrams.NewTransitionTable(ft)
+	assert.ErrorContains(t, err, "failed to split the ngram \"t\"")
+
+	ft = ngrams.NewFrequencyTable()
+	ft.Add("the", 1)
+	_, err = ngrams.NewTransitionTable(ft, ngrams.WithWordUnits())
+	assert.ErrorContains(t, err, "failed to split the ngram \"the\"")
+
+	ft = ngrams.NewFrequencyTable()
+	ft.Add("the cat", 1)
+	_, err = ngrams.NewTransitionTable(ft)
+	assert.ErrorContains(t, err, "the ngram \"the cat\" contains more than one word")
+
+	ft = ngrams.NewFrequencyTable()
+	ft.Add("the", 2)
+	ft.Add("walking", 1)
+	_, err = ngrams.NewTransitionTable(ft)
+	assert.ErrorContains(t, err, "the ngram \"walking\" is of size 7 instead of 3")
+
+	ft = ngrams.NewFrequencyTable()
+	ft.Add("v_r", 1)
+	ft.SetMetadata(ngrams.MetadataSkip, "1")
+	_, err = ngrams.NewTransitionTable(ft)
+	assert.ErrorContains(t, err, "skip-grams (skip 1) can not be used")
+}
+
+func TestTransitionTableSave(t *testing.T) {
+	tt, err := ngrams.NewTransitionTable(transitionTestTable(), ngrams.WithLaplace(1))
+	require.NoError(t, err)
+
+	var sb strings.Builder
+	require.NoError(t, tt.Save(&sb))
+	assert.Equal(t, `#prefix,next,probability
+#meta,smoothing,laplace
+#meta,alpha,1
+h,e,0.5714285714285714
+h,a,0.2857142857142857
+t,h,0.6363636363636364
+t,a,0.2727272727272727
+`, sb.String())
+
+	path := filepath.Join(t.TempDir(), "transitions.json")
+	require.NoError(t, tt.SaveToFile(path))
+	var sbJSON strings.Builder
+	require.NoError(t, tt.SaveJSON(&sbJSON))
+
+	var doc struct {
+		Smoothing   string              `json:"smoothing"`
+		Alpha       float64             `json:"alpha"`
+		Transitions []ngrams.Transition `json:"transitions"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(sbJSON.String()), &doc))
+	assert.Equal(t, "laplace", doc.Smoothing)
+	assert.Equal(t, 1.0, doc.Alpha)
+	assert.Equal(t, tt.Entries(), doc.Transitions)
+
+	assert.FileExists(t, path)
+	assert.ErrorContains(t, tt.SaveToFile(filepath.Join(t.TempDir(), "missing", "t.csv")),
+		"failed to save the transition table to file")
+}
+
+func TestParseSmoothing(t *testing.T) {
+	for _, s := range []ngrams.Smoothing{ngrams.SmoothingNone, ngrams.SmoothingLaplace, ngrams.SmoothingKneserNey} {
+		parsed, err := ngrams.ParseSmoothing(s.String())
+		require.NoError(t, err)
+		assert.Equal(t, s, parsed)
+	}
+	_, err := ngrams.ParseSmoothing("good-turing")
+	assert.ErrorContains(t, err, "invalid smoothing \"good-turing\"")
+	assert.Equal(t, "Smoothing(7)", ngrams.Smoothing(7).String())
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Andre Jacobs
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package ngrams
+
+import (
+	"slices"
+	"strings"
+)
+
+// SkipMarker is used in skip-gram tokens to mark each letter or word that was skipped.
+// For example the letter skip-gram t_e in the word "the" or the word skip-gram "of _ the" in "of all the".
+// To tell the marker apart from real underscores, the underscores and backslashes in the letters and words
+// of skip-grams are escaped with a backslash (e.g. the word snake_case becomes snake\_case).
+const SkipMarker = "_"
+
+// skipEscaper escapes the letters and words of skip-grams. See [SkipMarker].
+var skipEscaper = strings.NewReplacer(`\`, `\\`, SkipMarker, `\`+SkipMarker)
+
+// ngramWindow keeps track of the last letters or words parsed and produces the ngrams of each size that end
+// on the most recent unit. When skip is more than 0 then k-skip-n-grams are produced, i.e. every
+// combination of size units (in order) that skipped a total of at most skip units.
+type ngramWindow struct {
+	units   []string
+	sizes   []int
+	skip    int
+	sep     string
+	indices []int
+
+	// buf is reused to build the ngrams and interned holds the ngrams already built so that they
+	// do not need to be allocated every time. Only letter ngrams (joined without a separator) are
+	// interned since they are formed from a small alphabet and repeat often, unlike word ngrams.
+	buf      []byte
+	interned map[string]string
+}
+
+// internLimit is the maximum number of ngrams interned by a window.
+const internLimit = 1 << 16
+
+func newNgramWindow(sizes []int, skip int, sep string) *ngramWindow {
+	maxSize := slices.Max(sizes)
+	var interned map[string]string
+	if sep == "" {
+		interned = make(map[string]string)
+	}
+
+	return &ngramWindow{
+		units:    make([]string, 0, maxSize+skip),
+		sizes:    sizes,
+		skip:     skip,
+		sep:      sep,
+		indices:  make([]int, maxSize),
+		interned: interned,
+	}
+}
+
+// push adds the unit to the window and calls emit for each of the ngrams that was completed.
+func (w *ngramWindow) push(unit string, emit EmitFunc) error {
+	if w.skip > 0 && strings.ContainsAny(unit, `\`+SkipMarker) {
+		unit = skipEscaper.Replace(unit)
+	}
+
+	if len(w.units) == cap(w.units) {
+		copy(w.units, w.units[1:])
+		w.units = w.units[:len(w.units)-1]
+	}
+	w.units = append(w.units, unit)
+	last := len(w.units) - 1
+
+	for _, size := range w.sizes {
+		if len(w.units) < size {
+			continue
+		}
+
+		if size == 1 {
+			if err := emit(size, unit); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if w.skip == 0 {
+			if err := emit(size, w.join(w.units[last-size+1:])); err != nil {
+				return err
+			}
+			continue
+		}
+
+		indices := w.indices[:size]
+		indices[size-1] = last
+		first := max(0, last-(size-1)-w.skip)
+		if err := w.combine(indices, size-2, first, last-1, emit); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// combine chooses the index of the unit at position pos (working backwards) from the range [from, to].
+func (w *ngramWindow) combine(indices []int, pos int, from int, to int, emit EmitFunc) error {
+	// Leave enough room for the remaining positions
+	for i := to; i >= from+pos; i-- {
+		indices[pos] = i
+		if pos == 0 {
+			if err := emit(len(indices), w.token(indices)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.combine(indices, pos-1, from, i-1, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join builds the ngram from the units.
+func (w *ngramWindow) join(units []string) string {
+	w.buf = w.buf[:0]
+	for i, unit := range units {
+		if i > 0 {
+			w.buf = append(w.buf, w.sep...)
+		}
+		w.buf = append(w.buf, unit...)
+	}
+	return w.intern()
+}
+
+// token builds the ngram from the chosen indices and marks any skipped units.
+func (w *ngramWindow) token(indices []int) string {
+	w.buf = w.buf[:0]
+	for i, index := range indices {
+		if i > 0 {
+			w.buf = append(w.buf, w.sep...)
+			for skipped := indices[i-1] + 1; skipped < index; skipped++ {
+				w.buf = append(w.buf, SkipMarker...)
+				w.buf = append(w.buf, w.sep...)
+			}
+		}
+		w.buf = append(w.buf, w.units[index]...)
+	}
+	return w.intern()
+}
+
+// intern returns the ngram in buf as a string, reusing the previously built string when possible.
+func (w *ngramWindow) intern() string {
+	// The compiler does not allocate for the string conversion of a map lookup
+	if token, exists := w.interned[string(w.buf)]; exists {
+		return token
+	}
+
+	token := string(w.buf)
+	if w.interned != nil && len(w.interned) < internLimit {
+		w.interned[token] = token
+	}
+	return token
+}
+
+// reset clears the window.
+func (w *ngramWindow) reset() {
+	w.units = w.units[:0]
+}